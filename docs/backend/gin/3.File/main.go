@@ -16,8 +16,25 @@ func directlyRequestAPI() {
 	r.Run(":8080") //文件不存在 会报错 404
 }
 
+// 前端通过 axios / fetch 请求接口下载文件：后端同样返回文件流，前端拿到 Blob 后自己触发下载，例如
+//
+//	const resp = await fetch("http://localhost:8080/api/file")
+//	const name = decodeURIComponent(/filename\*=UTF-8''(.+)/.exec(resp.headers.get("Content-Disposition"))[1])
+//	const a = document.createElement("a")
+//	a.href = URL.createObjectURL(await resp.blob())
+//	a.download = name
+//	a.click()
 func frontendRequestsBackendAPI() {
+	r := gin.Default()
 
+	r.GET("/api/file", func(c *gin.Context) {
+		// 跨域请求时前端默认读不到 Content-Disposition，需要声明暴露出来，前端才能拿到文件名
+		c.Header("Access-Control-Allow-Origin", "*")
+		c.Header("Access-Control-Expose-Headers", "Content-Disposition")
+		// FileAttachment 会设置 Content-Disposition，中文文件名按 RFC 5987 编码成 filename*=UTF-8''...
+		c.FileAttachment("文件下载.go", "文件下载.go")
+	})
+	r.Run(":8080")
 }
 
 func main() {
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"gin-demo/res"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,11 +17,8 @@ func QueryExample() {
 		var form BindForm
 		// 绑定查询参数到结构体
 		if err := c.ShouldBindQuery(&form); err != nil {
-			c.JSON(400, gin.H{
-				"code": 1002,
-				"msg":  "参数错误",
-				"data": gin.H{},
-			})
+			// 返回字段级的错误信息，提示语言根据 Accept-Language 选择
+			res.FailBind(c, err)
 			return
 		}
 		// 返回绑定的结果
@@ -46,11 +45,8 @@ func DynamicQueryExample() {
 		var params QueryParams
 		// 绑定查询参数到结构体
 		if err := c.ShouldBindQuery(&params); err != nil {
-			c.JSON(400, gin.H{
-				"code": 1002,
-				"msg":  "参数错误",
-				"data": gin.H{},
-			})
+			// 返回字段级的错误信息，提示语言根据 Accept-Language 选择
+			res.FailBind(c, err)
 			return
 		}
 		query := c.Param("query")
@@ -74,11 +70,8 @@ func FormExample() {
 		var form BindForm
 		// 绑定表单数据到结构体
 		if err := c.ShouldBind(&form); err != nil {
-			c.JSON(400, gin.H{
-				"code": 1002,
-				"msg":  "参数错误",
-				"data": gin.H{},
-			})
+			// 返回字段级的错误信息，提示语言根据 Accept-Language 选择
+			res.FailBind(c, err)
 			return
 		}
 		// 返回绑定的结果
@@ -105,11 +98,8 @@ func JSONExample() {
 		var jsonData JSONData
 		// 绑定 JSON 数据到结构体
 		if err := c.ShouldBindJSON(&jsonData); err != nil {
-			c.JSON(400, gin.H{
-				"code": 1002,
-				"msg":  "参数错误",
-				"data": gin.H{},
-			})
+			// 返回字段级的错误信息，提示语言根据 Accept-Language 选择
+			res.FailBind(c, err)
 			return
 		}
 		// 返回绑定的结果
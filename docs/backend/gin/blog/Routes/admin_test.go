@@ -0,0 +1,143 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// setRole 由管理员修改用户角色，修改后该用户需要重新登录
+func (s *testServer) setRole(adminToken string, userID uint, role string) {
+	s.t.Helper()
+	s.expect(http.StatusOK, http.MethodPatch, fmt.Sprintf("/admin/users/%d/role", userID), adminToken, map[string]any{"role": role})
+}
+
+func TestRoles(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, admin := s.newUser("admin")
+			_, alice := s.newUser("alice")
+			bobID, bob := s.newUser("bob")
+			carolID, _ := s.newUser("carol")
+
+			postID := s.createPost(alice, "标题", "内容")
+			commentID := s.createComment(alice, postID, "评论")
+
+			// 普通作者不能删除别人的评论和文章
+			s.expect(http.StatusForbidden, http.MethodDelete, fmt.Sprintf("/comments/%d", commentID), bob, nil)
+			s.expect(http.StatusForbidden, http.MethodDelete, fmt.Sprintf("/posts/%d", postID), bob, nil)
+
+			// 角色变更后旧 token 立即失效，重新登录后拿到新角色
+			s.setRole(admin, bobID, "moderator")
+			s.expect(http.StatusUnauthorized, http.MethodGet, "/posts", bob, nil)
+			bob = s.login("bob@example.com", "password123")
+
+			// 版主可以删除任何评论，但不能删除别人的文章
+			s.expect(http.StatusForbidden, http.MethodDelete, fmt.Sprintf("/posts/%d", postID), bob, nil)
+			s.expect(http.StatusOK, http.MethodDelete, fmt.Sprintf("/comments/%d", commentID), bob, nil)
+
+			// 读者不能发表文章，但可以评论
+			s.setRole(admin, carolID, "reader")
+			carol := s.login("carol@example.com", "password123")
+			s.expect(http.StatusForbidden, http.MethodPost, "/posts", carol, map[string]any{"Title": "t", "Content": "c"})
+			s.createComment(carol, postID, "读者的评论")
+
+			// 管理员可以删除任何文章
+			s.expect(http.StatusOK, http.MethodDelete, fmt.Sprintf("/posts/%d", postID), admin, nil)
+		})
+	}
+}
+
+func TestAdminUsers(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			adminID, admin := s.newUser("admin")
+			aliceID, alice := s.newUser("alice")
+
+			// 非管理员不能访问管理接口
+			s.expect(http.StatusForbidden, http.MethodGet, "/admin/users", alice, nil)
+			s.expect(http.StatusForbidden, http.MethodPatch, fmt.Sprintf("/admin/users/%d/role", aliceID), alice, map[string]any{"role": "admin"})
+			s.expect(http.StatusForbidden, http.MethodDelete, fmt.Sprintf("/admin/users/%d", adminID), alice, nil)
+
+			rec := s.do(http.MethodGet, "/admin/users", admin, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("期望 200，实际 %d，响应: %s", rec.Code, rec.Body.String())
+			}
+			// 密码哈希不能出现在响应中
+			if strings.Contains(rec.Body.String(), "Password") || strings.Contains(rec.Body.String(), "$2a$") {
+				t.Fatalf("用户列表泄露了密码: %s", rec.Body.String())
+			}
+			users := decode(t, rec)["users"].([]any)
+			if len(users) != 2 {
+				t.Fatalf("期望 2 个用户，实际 %d", len(users))
+			}
+			// 默认按注册时间倒序
+			if first := users[0].(map[string]any); first["Email"] != "alice@example.com" || first["role"] != "author" {
+				t.Fatalf("第一个用户不符合预期: %v", first)
+			}
+			// 用户列表不按作者和标签过滤，这些参数被忽略
+			filtered := s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/admin/users?author_id=%d&tag=go&size=1", aliceID), admin, nil)
+			if users := filtered["users"].([]any); len(users) != 1 || users[0].(map[string]any)["Email"] != "alice@example.com" {
+				t.Fatalf("带过滤参数的用户列表不符合预期: %v", filtered)
+			}
+
+			s.expect(http.StatusBadRequest, http.MethodPatch, fmt.Sprintf("/admin/users/%d/role", aliceID), admin, map[string]any{"role": "superuser"})
+			s.expect(http.StatusBadRequest, http.MethodPatch, fmt.Sprintf("/admin/users/%d/role", adminID), admin, map[string]any{"role": "reader"})
+			s.expect(http.StatusNotFound, http.MethodPatch, "/admin/users/9999/role", admin, map[string]any{"role": "reader"})
+			s.expect(http.StatusBadRequest, http.MethodDelete, fmt.Sprintf("/admin/users/%d", adminID), admin, nil)
+			s.expect(http.StatusNotFound, http.MethodDelete, "/admin/users/9999", admin, nil)
+
+			// 删除用户后，该用户立即下线且无法再登录
+			s.expect(http.StatusOK, http.MethodDelete, fmt.Sprintf("/admin/users/%d", aliceID), admin, nil)
+			s.expect(http.StatusUnauthorized, http.MethodGet, "/posts", alice, nil)
+			s.expect(http.StatusUnauthorized, http.MethodPost, "/login", "", map[string]any{
+				"email": "alice@example.com", "password": "password123",
+			})
+		})
+	}
+}
+
+func TestPromoteAdmins(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.newUser("alice")
+	s.expect(http.StatusForbidden, http.MethodGet, "/admin/users", alice, nil)
+
+	// 已注册的用户在启动时被提升为管理员，旧会话被吊销
+	s.deps.Config.Admin.Emails = append(s.deps.Config.Admin.Emails, "alice@example.com")
+	if err := s.deps.PromoteAdmins(context.Background()); err != nil {
+		t.Fatalf("提升管理员失败: %v", err)
+	}
+	s.expect(http.StatusUnauthorized, http.MethodGet, "/admin/users", alice, nil)
+	alice = s.login("alice@example.com", "password123")
+	s.expect(http.StatusOK, http.MethodGet, "/admin/users", alice, nil)
+}
+
+func TestCreatePostCannotCreateUsers(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			// 嵌套的作者不会被当作关联写入 users 表，角色只能由管理员或配置的邮箱设置
+			s.expect(http.StatusOK, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "标题", "Content": "内容",
+				"User": map[string]any{"Name": "mallory", "Email": "mallory@example.com", "Password": "x", "role": "admin"},
+			})
+			if user, err := s.deps.Users.FindByEmail(context.Background(), "mallory@example.com"); err == nil {
+				t.Fatalf("不应该创建用户: %+v", user)
+			}
+		})
+	}
+}
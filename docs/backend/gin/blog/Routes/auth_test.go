@@ -0,0 +1,189 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRegister(t *testing.T) {
+	s := newTestServer(t)
+
+	id := s.register("alice", "alice@example.com", "password123")
+	if id == 0 {
+		t.Fatal("注册成功后应返回用户 ID")
+	}
+
+	// 重复邮箱
+	s.expect(http.StatusConflict, http.MethodPost, "/register", "", map[string]any{
+		"name": "alice2", "email": "alice@example.com", "password": "password123",
+	})
+	// 参数校验：邮箱格式、密码长度、缺少字段
+	for _, body := range []map[string]any{
+		{"name": "bob", "email": "not-an-email", "password": "password123"},
+		{"name": "bob", "email": "bob@example.com", "password": "short"},
+		{"name": "bob", "email": "bob@example.com", "password": "this-password-is-way-too-long"},
+		{"email": "bob@example.com", "password": "password123"},
+	} {
+		s.expect(http.StatusBadRequest, http.MethodPost, "/register", "", body)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	s := newTestServer(t)
+	id := s.register("alice", "alice@example.com", "password123")
+
+	if token := s.login("alice@example.com", "password123"); token == "" {
+		t.Fatal("登录成功后应返回 token")
+	}
+	// 也可以用用户 ID 登录
+	s.expect(http.StatusOK, http.MethodPost, "/login", "", map[string]any{"id": id, "password": "password123"})
+
+	s.expect(http.StatusUnauthorized, http.MethodPost, "/login", "", map[string]any{
+		"email": "alice@example.com", "password": "wrong-password",
+	})
+	s.expect(http.StatusUnauthorized, http.MethodPost, "/login", "", map[string]any{
+		"email": "nobody@example.com", "password": "password123",
+	})
+	// 既没有 ID 也没有邮箱
+	s.expect(http.StatusBadRequest, http.MethodPost, "/login", "", map[string]any{"password": "password123"})
+}
+
+// signToken 用指定的密钥和声明直接签发 token，用来构造各种异常 token
+func signToken(t *testing.T, method jwt.SigningMethod, key any, claims jwt.Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("签发 token 失败: %v", err)
+	}
+	return token
+}
+
+func TestJWTFailureModes(t *testing.T) {
+	s := newTestServer(t)
+	id, _ := s.newUser("alice")
+	now := time.Now()
+
+	cases := []struct {
+		name   string
+		header string
+	}{
+		{"缺少 Authorization", ""},
+		{"格式错误", "Bearer not.a.jwt"},
+		{"签名密钥错误", "Bearer " + signToken(t, jwt.SigningMethodHS256, []byte("another-secret-0123456789"), jwt.MapClaims{
+			"ID": id, "exp": now.Add(time.Hour).Unix(),
+		})},
+		{"已过期", "Bearer " + signToken(t, jwt.SigningMethodHS256, []byte(testSecret), jwt.MapClaims{
+			"ID": id, "exp": now.Add(-time.Hour).Unix(),
+		})},
+		{"缺少 ID", "Bearer " + signToken(t, jwt.SigningMethodHS256, []byte(testSecret), jwt.MapClaims{
+			"exp": now.Add(time.Hour).Unix(),
+		})},
+		{"会话不存在", "Bearer " + signToken(t, jwt.SigningMethodHS256, []byte(testSecret), jwt.MapClaims{
+			"ID": id, "sid": "unknown-session", "typ": "access", "exp": now.Add(time.Hour).Unix(),
+		})},
+		{"alg=none", "Bearer " + signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, jwt.MapClaims{
+			"ID": id, "exp": now.Add(time.Hour).Unix(),
+		})},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := newRequest(http.MethodGet, "/feed", tc.header)
+			rec := s.serve(req)
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("期望 401，实际 %d，响应: %s", rec.Code, rec.Body.String())
+			}
+			// 匿名用户可以访问的路由只允许不带 token，带了无效的 token 同样返回 401
+			want := http.StatusUnauthorized
+			if tc.header == "" {
+				want = http.StatusOK
+			}
+			if rec = s.serve(newRequest(http.MethodGet, "/posts", tc.header)); rec.Code != want {
+				t.Fatalf("GET /posts: 期望 %d，实际 %d，响应: %s", want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+// loginPair 登录并返回 access token 和 refresh token
+func (s *testServer) loginPair(email, password string) (string, string) {
+	s.t.Helper()
+	resp := s.expect(http.StatusOK, http.MethodPost, "/login", "", map[string]any{
+		"email": email, "password": password,
+	})
+	return resp["token"].(string), resp["refresh_token"].(string)
+}
+
+func (s *testServer) refresh(status int, refreshToken string) map[string]any {
+	s.t.Helper()
+	return s.expect(status, http.MethodPost, "/token/refresh", "", map[string]any{"refresh_token": refreshToken})
+}
+
+func TestRefreshToken(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			s.register("alice", "alice@example.com", "password123")
+			access, refresh := s.loginPair("alice@example.com", "password123")
+
+			// refresh token 不能当作 access token 使用，反之亦然
+			s.expect(http.StatusUnauthorized, http.MethodGet, "/posts", refresh, nil)
+			s.refresh(http.StatusUnauthorized, access)
+			s.refresh(http.StatusBadRequest, "")
+
+			resp := s.refresh(http.StatusOK, refresh)
+			newAccess, newRefresh := resp["token"].(string), resp["refresh_token"].(string)
+			if newRefresh == refresh {
+				t.Fatal("刷新后应轮换 refresh token")
+			}
+			// 旧的 access token 在过期前仍然有效，新的也可以使用
+			s.expect(http.StatusOK, http.MethodGet, "/posts", access, nil)
+			s.expect(http.StatusOK, http.MethodGet, "/posts", newAccess, nil)
+
+			// 重复使用旧的 refresh token：视为泄露，吊销整个会话
+			s.refresh(http.StatusUnauthorized, refresh)
+			s.expect(http.StatusUnauthorized, http.MethodGet, "/posts", newAccess, nil)
+			s.refresh(http.StatusUnauthorized, newRefresh)
+
+			// 其他会话不受影响
+			other, _ := s.loginPair("alice@example.com", "password123")
+			s.expect(http.StatusOK, http.MethodGet, "/posts", other, nil)
+		})
+	}
+}
+
+func TestLogout(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			s.register("alice", "alice@example.com", "password123")
+			a1, r1 := s.loginPair("alice@example.com", "password123")
+			a2, _ := s.loginPair("alice@example.com", "password123")
+			a3, r3 := s.loginPair("alice@example.com", "password123")
+			_, bob := s.newUser("bob")
+
+			s.expect(http.StatusUnauthorized, http.MethodPost, "/logout", "", nil)
+
+			// 只注销当前会话
+			s.expect(http.StatusOK, http.MethodPost, "/logout", a1, nil)
+			s.expect(http.StatusUnauthorized, http.MethodGet, "/posts", a1, nil)
+			s.refresh(http.StatusUnauthorized, r1)
+			s.expect(http.StatusOK, http.MethodGet, "/posts", a2, nil)
+
+			// 注销所有会话
+			s.expect(http.StatusOK, http.MethodPost, "/logout", a2, map[string]any{"all": true})
+			s.expect(http.StatusUnauthorized, http.MethodGet, "/posts", a3, nil)
+			s.refresh(http.StatusUnauthorized, r3)
+			// 其他用户不受影响
+			s.expect(http.StatusOK, http.MethodGet, "/posts", bob, nil)
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCommentCRUD(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			bobID, bob := s.newUser("bob")
+			postID := s.createPost(alice, "Hello", "first post")
+			commentsPath := fmt.Sprintf("/posts/%d/comments", postID)
+
+			commentID := s.createComment(bob, postID, "nice post")
+
+			resp := s.expect(http.StatusOK, http.MethodGet, commentsPath, alice, nil)
+			comments := resp["comments"].([]any)
+			if len(comments) != 1 {
+				t.Fatalf("期望 1 条评论，实际 %d", len(comments))
+			}
+			comment := comments[0].(map[string]any)
+			if comment["Content"] != "nice post" || uint(comment["user_id"].(float64)) != bobID {
+				t.Fatalf("评论内容不符: %v", comment)
+			}
+
+			s.expect(http.StatusOK, http.MethodDelete, fmt.Sprintf("/comments/%d", commentID), bob, nil)
+			resp = s.expect(http.StatusOK, http.MethodGet, commentsPath, alice, nil)
+			if n := len(resp["comments"].([]any)); n != 0 {
+				t.Fatalf("删除后期望 0 条评论，实际 %d", n)
+			}
+		})
+	}
+}
+
+func TestCommentValidation(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.newUser("alice")
+
+	s.expect(http.StatusNotFound, http.MethodDelete, "/comments/999", alice, nil)
+	s.expect(http.StatusBadRequest, http.MethodDelete, "/comments/abc", alice, nil)
+	s.expect(http.StatusBadRequest, http.MethodGet, "/posts/abc/comments", alice, nil)
+	s.expect(http.StatusBadRequest, http.MethodPost, "/comments", alice, "not an object")
+}
+
+func TestCommentOwnership(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.newUser("alice")
+	_, bob := s.newUser("bob")
+	postID := s.createPost(alice, "Hello", "first post")
+	_, carol := s.newUser("carol")
+	commentID := s.createComment(bob, postID, "nice post")
+	path := fmt.Sprintf("/comments/%d", commentID)
+
+	// 其他用户不能删除评论
+	s.expect(http.StatusForbidden, http.MethodDelete, path, carol, nil)
+	// 文章作者可以删除自己文章下的评论
+	s.expect(http.StatusOK, http.MethodDelete, path, alice, nil)
+}
+
+func TestCommentModeration(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			_, bob := s.newUser("bob")
+			_, carol := s.newUser("carol")
+
+			resp := s.expect(http.StatusOK, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "需要审核", "Content": "内容", "comment_mode": "review",
+			})
+			post := resp["post"].(map[string]any)
+			if post["comment_mode"] != "review" {
+				t.Fatalf("评论模式应为 review: %v", post)
+			}
+			postID := uint(post["ID"].(float64))
+			s.expect(http.StatusBadRequest, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "t", "Content": "c", "comment_mode": "never",
+			})
+
+			// 读者的评论进入待审核状态，客户端不能自行指定状态
+			resp = s.expect(http.StatusOK, http.MethodPost, "/comments", bob, map[string]any{
+				"post_id": postID, "Content": "待审核", "status": "approved",
+			})
+			comment := resp["comment"].(map[string]any)
+			if comment["status"] != "pending" {
+				t.Fatalf("评论应等待审核: %v", comment)
+			}
+			pendingID := uint(comment["ID"].(float64))
+			// 文章作者自己的评论直接发布
+			s.createComment(alice, postID, "作者的评论")
+
+			comments := fmt.Sprintf("/posts/%d/comments", postID)
+			visible := func(token string) int {
+				t.Helper()
+				list := s.expect(http.StatusOK, http.MethodGet, comments, token, nil)["comments"].([]any)
+				detail := s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/posts/%d", postID), token, nil)
+				if n := len(detail["post"].(map[string]any)["Comments"].([]any)); n != len(list) {
+					t.Fatalf("文章详情中的评论数 %d 和评论列表 %d 不一致", n, len(list))
+				}
+				return len(list)
+			}
+			// 待审核的评论只有评论者和文章作者可见
+			if n := visible(carol); n != 1 {
+				t.Fatalf("其他用户应只看到 1 条评论，实际 %d", n)
+			}
+			if n := visible(bob); n != 2 {
+				t.Fatalf("评论者应看到自己的待审核评论，实际 %d", n)
+			}
+			if n := visible(alice); n != 2 {
+				t.Fatalf("文章作者应看到全部评论，实际 %d", n)
+			}
+
+			approve := fmt.Sprintf("/comments/%d/approve", pendingID)
+			s.expect(http.StatusForbidden, http.MethodPatch, approve, bob, nil)
+			s.expect(http.StatusForbidden, http.MethodPatch, approve, carol, nil)
+			s.expect(http.StatusNotFound, http.MethodPatch, "/comments/9999/approve", alice, nil)
+			resp = s.expect(http.StatusOK, http.MethodPatch, approve, alice, nil)
+			if status := resp["comment"].(map[string]any)["status"]; status != "approved" {
+				t.Fatalf("审核后状态应为 approved，实际 %v", status)
+			}
+			if n := visible(carol); n != 2 {
+				t.Fatalf("审核通过后所有人可见，实际 %d", n)
+			}
+
+			// 切换回自动发布后，新评论直接公开
+			s.edit(http.StatusOK, http.MethodPut, fmt.Sprintf("/posts/%d", postID), alice, postID, map[string]any{
+				"title": "自动发布", "content": "内容", "comment_mode": "auto",
+			})
+			s.createComment(carol, postID, "直接公开")
+			if n := visible(bob); n != 3 {
+				t.Fatalf("自动发布模式下评论应直接公开，实际 %d", n)
+			}
+
+			// 评论不存在的文章
+			s.expect(http.StatusNotFound, http.MethodPost, "/comments", bob, map[string]any{"post_id": 9999, "Content": "x"})
+		})
+	}
+}
+
+func TestListCommentsPagination(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			bobID, bob := s.newUser("bob")
+			postID := s.createPost(alice, "Hello", "first post")
+			var all []uint
+			for i := 0; i < 3; i++ {
+				all = append(all, s.createComment(alice, postID, fmt.Sprintf("comment %d", i)))
+			}
+			all = append(all, s.createComment(bob, postID, "bob's comment"))
+			path := fmt.Sprintf("/posts/%d/comments", postID)
+
+			// 默认按时间正序
+			ids, meta := s.listIDs(path+"?size=3", alice, "comments")
+			if fmt.Sprint(ids) != fmt.Sprint(all[:3]) || meta["total"].(float64) != 4 {
+				t.Fatalf("第一页不符: %v %v", ids, meta)
+			}
+			ids, meta = s.listIDs(path+"?size=3&cursor="+meta["next_cursor"].(string), alice, "comments")
+			if fmt.Sprint(ids) != fmt.Sprint(all[3:]) || meta["next_cursor"] != "" {
+				t.Fatalf("第二页不符: %v %v", ids, meta)
+			}
+			ids, _ = s.listIDs(path+"?sort=-created_at&size=1", alice, "comments")
+			if fmt.Sprint(ids) != fmt.Sprint(all[3:]) {
+				t.Fatalf("倒序第一条不符: %v", ids)
+			}
+			ids, _ = s.listIDs(fmt.Sprintf("%s?author_id=%d", path, bobID), alice, "comments")
+			if fmt.Sprint(ids) != fmt.Sprint(all[3:]) {
+				t.Fatalf("按作者过滤不符: %v", ids)
+			}
+		})
+	}
+}
+
+// reply 回复评论并返回新评论的 ID
+func (s *testServer) reply(token string, postID, parentID uint, content string) uint {
+	s.t.Helper()
+	resp := s.expect(http.StatusOK, http.MethodPost, "/comments", token, map[string]any{
+		"post_id": postID, "parent_id": parentID, "Content": content,
+	})
+	return uint(resp["comment"].(map[string]any)["ID"].(float64))
+}
+
+// commentTree 把评论树转换成 "ID(回复...)" 形式的字符串，方便比较结构
+func commentTree(nodes []any) string {
+	var sb strings.Builder
+	for i, n := range nodes {
+		node := n.(map[string]any)
+		if i > 0 {
+			sb.WriteString(" ")
+		}
+		fmt.Fprintf(&sb, "%v", node["ID"])
+		if replies := node["replies"].([]any); len(replies) > 0 {
+			sb.WriteString("(" + commentTree(replies) + ")")
+		}
+	}
+	return sb.String()
+}
+
+func TestCommentThreads(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			_, bob := s.newUser("bob")
+			_, carol := s.newUser("carol")
+			postID := s.createPost(alice, "讨论", "内容")
+			otherPost := s.createPost(alice, "另一篇", "内容")
+			path := fmt.Sprintf("/posts/%d/comments", postID)
+
+			c1 := s.createComment(bob, postID, "顶层评论")
+			c2 := s.reply(carol, postID, c1, "回复")
+			c3 := s.reply(bob, postID, c2, "回复的回复")
+			c4 := s.createComment(carol, postID, "另一条顶层评论")
+			tree := func(query string) string {
+				s.t.Helper()
+				return commentTree(s.expect(http.StatusOK, http.MethodGet, path+"?tree=1"+query, bob, nil)["comments"].([]any))
+			}
+			if got, want := tree(""), fmt.Sprintf("%d(%d(%d)) %d", c1, c2, c3, c4); got != want {
+				t.Fatalf("评论树不符合预期: %s，期望 %s", got, want)
+			}
+			// 分页和排序只作用于顶层评论
+			if got := tree("&size=1&page=2"); got != fmt.Sprint(c4) {
+				t.Fatalf("第二页应只有第二个顶层评论: %s", got)
+			}
+			if got := tree("&sort=-created_at&size=1"); got != fmt.Sprint(c4) {
+				t.Fatalf("倒序时第一个顶层评论应是最新的: %s", got)
+			}
+			if ids, _ := s.listIDs(path, bob, "comments"); len(ids) != 4 {
+				t.Fatalf("不带 tree 时仍返回平铺的评论: %v", ids)
+			}
+			s.expect(http.StatusBadRequest, http.MethodGet, path+"?tree=1&cursor=x", bob, nil)
+
+			// 超过层数限制、回复其他文章或不存在的评论都会被拒绝
+			s.deps.Config.Comments.MaxDepth = 3
+			s.expect(http.StatusBadRequest, http.MethodPost, "/comments", carol, map[string]any{"post_id": postID, "parent_id": c3, "Content": "太深了"})
+			s.expect(http.StatusNotFound, http.MethodPost, "/comments", carol, map[string]any{"post_id": otherPost, "parent_id": c1, "Content": "串楼"})
+			s.expect(http.StatusNotFound, http.MethodPost, "/comments", carol, map[string]any{"post_id": postID, "parent_id": 999, "Content": "不存在"})
+
+			// 删除有回复的评论时保留占位符，回复仍然挂在原来的位置
+			s.expect(http.StatusOK, http.MethodDelete, fmt.Sprintf("/comments/%d", c2), carol, nil)
+			if got, want := tree(""), fmt.Sprintf("%d(%d(%d)) %d", c1, c2, c3, c4); got != want {
+				t.Fatalf("删除后评论树不符合预期: %s，期望 %s", got, want)
+			}
+			root := s.expect(http.StatusOK, http.MethodGet, path+"?tree=1", bob, nil)["comments"].([]any)[0].(map[string]any)
+			placeholder := root["replies"].([]any)[0].(map[string]any)
+			if placeholder["Content"] != "[deleted]" || placeholder["status"] != "deleted" || placeholder["user_id"].(float64) != 0 {
+				t.Fatalf("占位符不应保留原内容和作者: %v", placeholder)
+			}
+			s.expect(http.StatusBadRequest, http.MethodPost, "/comments", bob, map[string]any{"post_id": postID, "parent_id": c2, "Content": "回复占位符"})
+
+			// 删除最后一条回复后，占位符也被清理
+			s.expect(http.StatusOK, http.MethodDelete, fmt.Sprintf("/comments/%d", c3), bob, nil)
+			if got, want := tree(""), fmt.Sprintf("%d %d", c1, c4); got != want {
+				t.Fatalf("清理占位符后评论树不符合预期: %s，期望 %s", got, want)
+			}
+			if ids, _ := s.listIDs(path, bob, "comments"); len(ids) != 2 {
+				t.Fatalf("占位符应被删除: %v", ids)
+			}
+		})
+	}
+}
+
+func TestCommentCreateInput(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			aliceID, alice := s.newUser("alice")
+			bobID, bob := s.newUser("bob")
+			postID := uint(s.expect(http.StatusOK, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "需要审核", "Content": "内容", "comment_mode": "review",
+			})["post"].(map[string]any)["ID"].(float64))
+
+			// 请求体中的作者、状态和嵌套的关联都会被忽略
+			comment := s.expect(http.StatusOK, http.MethodPost, "/comments", bob, map[string]any{
+				"post_id": postID, "Content": "评论",
+				"user_id": aliceID,
+				"status":  "approved",
+				"User":    map[string]any{"Name": "mallory", "Email": "mallory@example.com", "Password": "x", "role": "admin"},
+				"Post":    map[string]any{"Title": "伪造的文章", "Content": "内容", "user_id": bobID},
+			})["comment"].(map[string]any)
+			if uint(comment["user_id"].(float64)) != bobID || comment["status"] != "pending" {
+				t.Fatalf("评论的作者应为当前用户并等待审核: %v", comment)
+			}
+			if user, err := s.deps.Users.FindByEmail(context.Background(), "mallory@example.com"); err == nil {
+				t.Fatalf("不应该创建用户: %+v", user)
+			}
+			if ids, _ := s.listIDs(fmt.Sprintf("/posts?author_id=%d", bobID), bob, "posts"); len(ids) != 0 {
+				t.Fatalf("不应该创建文章: %v", ids)
+			}
+
+			// 文章和内容必须提供
+			s.expect(http.StatusBadRequest, http.MethodPost, "/comments", bob, map[string]any{"Content": "没有文章"})
+			s.expect(http.StatusBadRequest, http.MethodPost, "/comments", bob, map[string]any{"post_id": postID})
+		})
+	}
+}
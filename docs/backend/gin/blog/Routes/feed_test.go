@@ -0,0 +1,205 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// feed 不带 token 获取订阅源，断言状态码和 Content-Type 并返回内容
+func (s *testServer) feed(path, contentType string) []byte {
+	s.t.Helper()
+	rec := s.do(http.MethodGet, path, "", nil)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), contentType) {
+		s.t.Fatalf("GET %s: 状态码 %d，Content-Type %s，响应: %s", path, rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	return rec.Body.Bytes()
+}
+
+// jsonFeedItems 返回 JSON Feed 中每一项的标题
+func (s *testServer) jsonFeedItems(path string) []string {
+	s.t.Helper()
+	var doc struct {
+		Items []struct {
+			Title string `json:"title"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(s.feed(path, "application/feed+json"), &doc); err != nil {
+		s.t.Fatalf("解析 JSON Feed 失败: %v", err)
+	}
+	titles := []string{}
+	for _, item := range doc.Items {
+		titles = append(titles, item.Title)
+	}
+	return titles
+}
+
+func TestPostFeeds(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			aliceID, alice := s.newUser("alice")
+			bobID, bob := s.newUser("bob")
+			resp := s.expect(http.StatusOK, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "Markdown 文章", "Content": "**粗体**\n\n<script>alert(1)</script>", "format": "markdown",
+			})
+			aliceFirst := uint(resp["post"].(map[string]any)["ID"].(float64))
+			bobPost := s.createPost(bob, "bob 的文章", "内容")
+			s.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/posts/%d/tags", bobPost), bob, map[string]any{"names": []string{"Go"}})
+			s.expect(http.StatusOK, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "草稿", "Content": "内容", "status": "draft",
+			})
+
+			// RSS：最新的在前，草稿不出现，链接是指向公开页面的绝对地址，内容经过过滤
+			var rss struct {
+				Channel struct {
+					Items []struct {
+						Title       string   `xml:"title"`
+						Link        string   `xml:"link"`
+						Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+						Categories  []string `xml:"category"`
+						Description string   `xml:"description"`
+					} `xml:"item"`
+				} `xml:"channel"`
+			}
+			if err := xml.Unmarshal(s.feed("/feed.xml", "application/rss+xml"), &rss); err != nil {
+				t.Fatalf("解析 RSS 失败: %v", err)
+			}
+			items := rss.Channel.Items
+			if len(items) != 2 || items[0].Title != "bob 的文章" || items[0].Link != fmt.Sprintf("http://example.com/p/%d", bobPost) ||
+				items[0].Creator != "bob" || fmt.Sprint(items[0].Categories) != "[go]" {
+				t.Fatalf("RSS 的内容不符合预期: %+v", items)
+			}
+			if !strings.Contains(items[1].Description, "<strong>粗体</strong>") || strings.Contains(items[1].Description, "<script") {
+				t.Fatalf("RSS 中的文章内容应该渲染并过滤: %q", items[1].Description)
+			}
+
+			// Atom
+			var atom struct {
+				Entries []struct {
+					ID     string `xml:"id"`
+					Author struct {
+						URI string `xml:"uri"`
+					} `xml:"author"`
+				} `xml:"entry"`
+			}
+			if err := xml.Unmarshal(s.feed("/atom.xml", "application/atom+xml"), &atom); err != nil {
+				t.Fatalf("解析 Atom 失败: %v", err)
+			}
+			if len(atom.Entries) != 2 || atom.Entries[1].ID != fmt.Sprintf("http://example.com/p/%d", aliceFirst) ||
+				atom.Entries[1].Author.URI != fmt.Sprintf("http://example.com/u/%d", aliceID) {
+				t.Fatalf("Atom 的内容不符合预期: %+v", atom.Entries)
+			}
+
+			// JSON Feed，以及作者和标签的订阅源
+			if titles := s.jsonFeedItems("/feed.json"); fmt.Sprint(titles) != "[bob 的文章 Markdown 文章]" {
+				t.Fatalf("JSON Feed 的内容不符合预期: %v", titles)
+			}
+			if titles := s.jsonFeedItems(fmt.Sprintf("/users/%d/feed.json", aliceID)); fmt.Sprint(titles) != "[Markdown 文章]" {
+				t.Fatalf("作者订阅源的内容不符合预期: %v", titles)
+			}
+			s.feed(fmt.Sprintf("/users/%d/feed.xml", bobID), "application/rss+xml")
+			s.expect(http.StatusNotFound, http.MethodGet, "/users/9999/atom.xml", "", nil)
+			if titles := s.jsonFeedItems("/tags/GO/feed.json"); fmt.Sprint(titles) != "[bob 的文章]" {
+				t.Fatalf("标签订阅源的内容不符合预期: %v", titles)
+			}
+			if titles := s.jsonFeedItems("/tags/rust/feed.json"); len(titles) != 0 {
+				t.Fatalf("没有文章的标签应该返回空的订阅源: %v", titles)
+			}
+
+			// 配置了 site.url 时使用配置的地址
+			s.deps.Config.Site.URL = "https://blog.example.com/"
+			if data := string(s.feed("/feed.json", "application/feed+json")); !strings.Contains(data, `"feed_url": "https://blog.example.com/feed.json"`) ||
+				!strings.Contains(data, fmt.Sprintf(`"url": "https://blog.example.com/p/%d"`, bobPost)) {
+				t.Fatalf("订阅源应该使用 site.url 生成链接: %s", data)
+			}
+		})
+	}
+}
+
+func TestPostFeedScheduled(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			s.deps.Config.Site.FeedSize = 2
+			aliceID, alice := s.newUser("alice")
+			// 定时文章创建得最早，之后又发布了超过 feed_size 篇文章
+			publishAt := time.Now().Add(time.Hour)
+			s.expect(http.StatusOK, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "定时文章", "Content": "内容", "publish_at": publishAt,
+			})
+			for i := 1; i <= 3; i++ {
+				s.createPost(alice, fmt.Sprintf("文章 %d", i), "内容")
+			}
+			if titles := s.jsonFeedItems("/feed.json"); fmt.Sprint(titles) != "[文章 3 文章 2]" {
+				t.Fatalf("发布前的订阅源不符合预期: %v", titles)
+			}
+
+			// 发布后按发布时间排在最前面，而不是因为创建时间早被挤出订阅源
+			if n, err := s.deps.PublishDuePosts(context.Background(), publishAt.Add(time.Minute)); err != nil || n != 1 {
+				t.Fatalf("定时文章应被发布: %d, %v", n, err)
+			}
+			for _, path := range []string{"/feed.json", fmt.Sprintf("/users/%d/feed.json", aliceID)} {
+				if titles := s.jsonFeedItems(path); fmt.Sprint(titles) != "[定时文章 文章 3]" {
+					t.Fatalf("%s: 发布后的订阅源不符合预期: %v", path, titles)
+				}
+			}
+		})
+	}
+}
+
+func TestPostFeedCaching(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			postID := s.createPost(alice, "标题", "内容")
+
+			rec := s.do(http.MethodGet, "/feed.xml", "", nil)
+			etag, modified := rec.Header().Get("ETag"), rec.Header().Get("Last-Modified")
+			if etag == "" || modified == "" || rec.Header().Get("Cache-Control") != "public, max-age=60" {
+				t.Fatalf("订阅源的缓存响应头不符合预期: %v", rec.Header())
+			}
+			for _, header := range []map[string]string{
+				{"If-None-Match": etag},
+				{"If-Modified-Since": modified},
+				{"If-Modified-Since": time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)},
+			} {
+				if rec = s.doWith(http.MethodGet, "/feed.xml", "", nil, header); rec.Code != http.StatusNotModified {
+					t.Fatalf("%v: 期望 304，实际 %d", header, rec.Code)
+				}
+			}
+			earlier := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+			if rec = s.doWith(http.MethodGet, "/feed.xml", "", nil, map[string]string{"If-Modified-Since": earlier}); rec.Code != http.StatusOK {
+				t.Fatalf("订阅源在 If-Modified-Since 之后有变化，期望 200，实际 %d", rec.Code)
+			}
+			// 不同格式的 ETag 不同
+			if s.do(http.MethodGet, "/feed.json", "", nil).Header().Get("ETag") == etag {
+				t.Fatal("不同格式的订阅源应该有不同的 ETag")
+			}
+
+			// 修改文章后旧的 ETag 不再命中；同时带上两个条件时以 If-None-Match 为准
+			s.edit(http.StatusOK, http.MethodPatch, fmt.Sprintf("/posts/%d", postID), alice, postID, map[string]any{"title": "新标题"})
+			rec = s.doWith(http.MethodGet, "/feed.xml", "", nil, map[string]string{
+				"If-None-Match": etag, "If-Modified-Since": time.Now().Add(time.Hour).UTC().Format(http.TimeFormat),
+			})
+			if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "新标题") {
+				t.Fatalf("文章修改后应该返回新的订阅源，实际 %d", rec.Code)
+			}
+		})
+	}
+}
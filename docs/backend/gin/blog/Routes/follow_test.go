@@ -0,0 +1,177 @@
+package routes
+
+import (
+	"blog/config"
+	"blog/models"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// followIDs 返回关注列表中用户的 ID
+func (s *testServer) followIDs(path, token, key string) []uint {
+	s.t.Helper()
+	resp := s.expect(http.StatusOK, http.MethodGet, path, token, nil)
+	var ids []uint
+	for _, item := range resp[key].([]any) {
+		ids = append(ids, uint(item.(map[string]any)["id"].(float64)))
+	}
+	return ids
+}
+
+func TestFollows(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, admin := s.newUser("admin")
+			aliceID, alice := s.newUser("alice")
+			bobID, bob := s.newUser("bob")
+			carolID, carol := s.newUser("carol")
+
+			s.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", bobID), alice, nil)
+			// 重复关注不报错，也不会产生重复记录
+			s.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", bobID), alice, nil)
+			s.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", bobID), carol, nil)
+			s.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", carolID), alice, nil)
+			s.expect(http.StatusBadRequest, http.MethodPost, fmt.Sprintf("/users/%d/follow", aliceID), alice, nil)
+			s.expect(http.StatusNotFound, http.MethodPost, "/users/9999/follow", alice, nil)
+
+			// 默认最近关注的在前
+			if ids := s.followIDs(fmt.Sprintf("/users/%d/followers", bobID), alice, "followers"); fmt.Sprint(ids) != fmt.Sprint([]uint{carolID, aliceID}) {
+				t.Fatalf("粉丝列表不符合预期: %v", ids)
+			}
+			resp := s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/users/%d/following?size=1", aliceID), bob, nil)
+			pagination := resp["pagination"].(map[string]any)
+			if pagination["total"].(float64) != 2 || pagination["next_cursor"] == "" {
+				t.Fatalf("关注列表的分页不符合预期: %v", pagination)
+			}
+			entry := resp["following"].([]any)[0].(map[string]any)
+			if entry["id"].(float64) != float64(carolID) || entry["name"] != "carol" || entry["email"] != nil {
+				t.Fatalf("关注列表的内容不符合预期: %v", entry)
+			}
+			s.expect(http.StatusNotFound, http.MethodGet, "/users/9999/followers", alice, nil)
+			// 关注关系没有作者，author_id 等过滤参数被忽略
+			if ids := s.followIDs(fmt.Sprintf("/users/%d/followers?author_id=%d&tag=go", bobID, aliceID), alice, "followers"); len(ids) != 2 {
+				t.Fatalf("带过滤参数的粉丝列表不符合预期: %v", ids)
+			}
+
+			// 取消关注
+			s.expect(http.StatusOK, http.MethodDelete, fmt.Sprintf("/users/%d/follow", carolID), alice, nil)
+			s.expect(http.StatusNotFound, http.MethodDelete, fmt.Sprintf("/users/%d/follow", carolID), alice, nil)
+			if ids := s.followIDs(fmt.Sprintf("/users/%d/following", aliceID), alice, "following"); fmt.Sprint(ids) != fmt.Sprint([]uint{bobID}) {
+				t.Fatalf("取消关注后的关注列表不符合预期: %v", ids)
+			}
+
+			// 被删除的用户不再出现在列表中
+			s.expect(http.StatusOK, http.MethodDelete, fmt.Sprintf("/admin/users/%d", carolID), admin, nil)
+			if ids := s.followIDs(fmt.Sprintf("/users/%d/followers", bobID), bob, "followers"); fmt.Sprint(ids) != fmt.Sprint([]uint{aliceID}) {
+				t.Fatalf("删除用户后的粉丝列表不符合预期: %v", ids)
+			}
+		})
+	}
+}
+
+func TestFeed(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			bobID, bob := s.newUser("bob")
+			carolID, carol := s.newUser("carol")
+			_, dave := s.newUser("dave")
+
+			// 还没有关注任何人时时间线为空
+			if ids, _ := s.listIDs("/feed", alice, "posts"); len(ids) != 0 {
+				t.Fatalf("时间线应为空: %v", ids)
+			}
+
+			// 定时发布的文章创建得最早，但发布得最晚，应该排在时间线的最前面
+			scheduled := s.expect(http.StatusOK, http.MethodPost, "/posts", bob, map[string]any{
+				"Title": "定时", "Content": "内容", "publish_at": time.Now().Add(time.Hour),
+			})
+			scheduledID := uint(scheduled["post"].(map[string]any)["ID"].(float64))
+			var want []uint
+			for i := 0; i < 3; i++ {
+				want = append(want, s.createPost(bob, fmt.Sprintf("bob 的文章 %d", i), "内容"))
+				want = append(want, s.createPost(carol, fmt.Sprintf("carol 的文章 %d", i), "内容"))
+				s.createPost(dave, fmt.Sprintf("dave 的文章 %d", i), "内容")
+			}
+			// 草稿不出现在时间线中
+			s.expect(http.StatusOK, http.MethodPost, "/posts", bob, map[string]any{
+				"Title": "草稿", "Content": "内容", "status": "draft",
+			})
+			s.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", bobID), alice, nil)
+			s.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", carolID), alice, nil)
+			// 时间线按发布时间倒序
+			for i, j := 0, len(want)-1; i < j; i, j = i+1, j-1 {
+				want[i], want[j] = want[j], want[i]
+			}
+			if n, err := s.deps.PublishDuePosts(context.Background(), time.Now().Add(2*time.Hour)); err != nil || n != 1 {
+				t.Fatalf("定时文章应被发布: %d, %v", n, err)
+			}
+			want = append([]uint{scheduledID}, want...)
+
+			ids, meta := s.listIDs("/feed?size=4", alice, "posts")
+			if fmt.Sprint(ids) != fmt.Sprint(want[:4]) || meta["next_cursor"] == "" || meta["total"] != nil {
+				t.Fatalf("时间线第一页不符合预期: %v %v", ids, meta)
+			}
+			ids, meta = s.listIDs("/feed?size=4&cursor="+meta["next_cursor"].(string), alice, "posts")
+			if fmt.Sprint(ids) != fmt.Sprint(want[4:]) || meta["next_cursor"] != "" {
+				t.Fatalf("时间线第二页不符合预期: %v %v", ids, meta)
+			}
+			s.expect(http.StatusBadRequest, http.MethodGet, "/feed?page=2", alice, nil)
+			// 时间线固定按发布时间倒序，不支持排序和过滤参数
+			for _, query := range []string{"sort=created_at", "author_id=1", "tag=go", "from=2024-01-01"} {
+				s.expect(http.StatusBadRequest, http.MethodGet, "/feed?"+query, alice, nil)
+			}
+
+			// 取消关注后不再包含该作者的文章
+			s.expect(http.StatusOK, http.MethodDelete, fmt.Sprintf("/users/%d/follow", carolID), alice, nil)
+			ids, _ = s.listIDs("/feed", alice, "posts")
+			if len(ids) != 4 {
+				t.Fatalf("取消关注后的时间线不符合预期: %v", ids)
+			}
+		})
+	}
+}
+
+// 发布状态上线前发布的文章没有 published_at，迁移时用创建时间补上，时间线按索引中的 published_at 排序
+func TestFeedLegacyPosts(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.newUser("alice")
+	bobID, bob := s.newUser("bob")
+	legacy := s.createPost(bob, "旧文章", "内容")
+	newer := s.createPost(bob, "新文章", "内容")
+	if err := s.db.Model(&models.Post{}).Where("id = ?", legacy).Update("published_at", nil).Error; err != nil {
+		t.Fatal(err)
+	}
+	config.MigrateDB(s.db)
+
+	var post models.Post
+	if err := s.db.First(&post, legacy).Error; err != nil || post.PublishedAt == nil || !post.PublishedAt.Equal(post.CreatedAt) {
+		t.Fatalf("迁移应使用创建时间补上发布时间: %v %v", post.PublishedAt, err)
+	}
+	for _, index := range []string{"idx_posts_user_published", "idx_posts_published"} {
+		if !s.db.Migrator().HasIndex(&models.Post{}, index) {
+			t.Fatalf("缺少索引 %s", index)
+		}
+	}
+
+	s.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", bobID), alice, nil)
+	ids, meta := s.listIDs("/feed?size=1", alice, "posts")
+	if fmt.Sprint(ids) != fmt.Sprint([]uint{newer}) || meta["next_cursor"] == "" {
+		t.Fatalf("时间线第一页不符合预期: %v %v", ids, meta)
+	}
+	ids, _ = s.listIDs("/feed?size=1&cursor="+meta["next_cursor"].(string), alice, "posts")
+	if fmt.Sprint(ids) != fmt.Sprint([]uint{legacy}) {
+		t.Fatalf("时间线第二页不符合预期: %v", ids)
+	}
+}
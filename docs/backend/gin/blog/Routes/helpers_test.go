@@ -0,0 +1,221 @@
+package routes
+
+import (
+	"blog/app"
+	"blog/config"
+	"blog/middle"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gin-demo/res"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const testSecret = "test-secret-0123456789abcdef"
+
+func TestMain(m *testing.M) {
+	// 测试时不需要控制台和文件日志
+	config.Log.SetOutput(io.Discard)
+	log.SetOutput(io.Discard)
+	gin.DefaultWriter = io.Discard
+	os.Exit(m.Run())
+}
+
+// testServer 是端到端测试的服务：真实的路由、中间件和控制器，数据库换成内存 SQLite
+type testServer struct {
+	t      *testing.T
+	deps   *app.Container
+	router *gin.Engine
+	db     *gorm.DB // 只有 newTestServer 启动的服务才有，用来构造接口无法产生的数据
+}
+
+func testConfig() *config.Config {
+	cfg := config.Default()
+	cfg.Server.Mode = gin.TestMode
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = config.MemoryPath
+	cfg.JWT.Secret = testSecret
+	// newUser("admin") 注册的用户自动成为管理员
+	cfg.Admin.Emails = []string{"admin@example.com"}
+	return cfg
+}
+
+// newTestServer 启动一个使用内存 SQLite 的服务，每个测试都有独立的数据库
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Media.Dir = t.TempDir()
+	middle.InitJWT(cfg.JWT)
+	db := config.InitDB(cfg.Database)
+	config.MigrateDB(db)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	deps := app.NewContainer(cfg, db)
+	return &testServer{t: t, deps: deps, router: SetupRouter(deps), db: db}
+}
+
+// newMemoryTestServer 启动一个使用内存仓储的服务，用来保证内存实现和 GORM 实现行为一致
+func newMemoryTestServer(t *testing.T) *testServer {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Media.Dir = t.TempDir()
+	middle.InitJWT(cfg.JWT)
+	deps := app.NewMemoryContainer(cfg)
+	return &testServer{t: t, deps: deps, router: SetupRouter(deps)}
+}
+
+// do 发送请求，body 为 nil 时不带请求体，token 为空时不带 Authorization 头
+func (s *testServer) do(method, path, token string, body any) *httptest.ResponseRecorder {
+	s.t.Helper()
+	return s.doWith(method, path, token, body, nil)
+}
+
+// doWith 和 do 相同，额外设置 header 中的请求头
+func (s *testServer) doWith(method, path, token string, body any, header map[string]string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("编码请求体失败: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	return s.serve(req)
+}
+
+// expect 发送请求并断言状态码，返回响应中的 data
+func (s *testServer) expect(status int, method, path, token string, body any) map[string]any {
+	s.t.Helper()
+	rec := s.do(method, path, token, body)
+	if rec.Code != status {
+		s.t.Fatalf("%s %s: 期望状态码 %d，实际 %d，响应: %s", method, path, status, rec.Code, rec.Body.String())
+	}
+	return decode(s.t, rec)
+}
+
+// etag 以 token 对应的用户获取文章，返回响应中的 ETag
+func (s *testServer) etag(token string, postID uint) string {
+	s.t.Helper()
+	rec := s.do(http.MethodGet, fmt.Sprintf("/posts/%d", postID), token, nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == "" {
+		s.t.Fatalf("获取文章 %d 的 ETag 失败，状态码 %d，响应: %s", postID, rec.Code, rec.Body.String())
+	}
+	return rec.Header().Get("ETag")
+}
+
+// edit 先获取文章当前的 ETag，再带上 If-Match 发送修改请求并断言状态码，返回响应中的 data
+func (s *testServer) edit(status int, method, path, token string, postID uint, body any) map[string]any {
+	s.t.Helper()
+	rec := s.doWith(method, path, token, body, map[string]string{"If-Match": s.etag(token, postID)})
+	if rec.Code != status {
+		s.t.Fatalf("%s %s: 期望状态码 %d，实际 %d，响应: %s", method, path, status, rec.Code, rec.Body.String())
+	}
+	return decode(s.t, rec)
+}
+
+// envelope 解析统一响应格式，并检查业务码和 HTTP 状态码是否一致
+func envelope(t *testing.T, rec *httptest.ResponseRecorder) res.Response {
+	t.Helper()
+	var resp res.Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v，响应: %s", err, rec.Body.String())
+	}
+	if resp.Code.Status() != rec.Code {
+		t.Fatalf("业务码 %d 与 HTTP 状态码 %d 不一致，响应: %s", resp.Code, rec.Code, rec.Body.String())
+	}
+	if resp.Msg == "" || resp.RequestID == "" || resp.RequestID != rec.Header().Get(res.RequestIDHeader) {
+		t.Fatalf("响应缺少 msg 或 request_id: %s", rec.Body.String())
+	}
+	return resp
+}
+
+// decode 解析统一响应格式，返回其中的 data（失败响应的 data 为 null，返回空 map）
+func decode(t *testing.T, rec *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	data, _ := envelope(t, rec).Data.(map[string]any)
+	if data == nil {
+		data = map[string]any{}
+	}
+	return data
+}
+
+// register 注册用户并返回用户 ID
+func (s *testServer) register(name, email, password string) uint {
+	s.t.Helper()
+	resp := s.expect(http.StatusOK, http.MethodPost, "/register", "", map[string]any{
+		"name": name, "email": email, "password": password,
+	})
+	return uint(resp["user_id"].(float64))
+}
+
+// login 使用邮箱登录并返回 token
+func (s *testServer) login(email, password string) string {
+	s.t.Helper()
+	resp := s.expect(http.StatusOK, http.MethodPost, "/login", "", map[string]any{
+		"email": email, "password": password,
+	})
+	return resp["token"].(string)
+}
+
+// newUser 注册并登录一个用户，返回用户 ID 和 token
+func (s *testServer) newUser(name string) (uint, string) {
+	s.t.Helper()
+	email := fmt.Sprintf("%s@example.com", name)
+	id := s.register(name, email, "password123")
+	return id, s.login(email, "password123")
+}
+
+// createPost 创建文章并返回文章 ID
+func (s *testServer) createPost(token, title, content string) uint {
+	s.t.Helper()
+	resp := s.expect(http.StatusOK, http.MethodPost, "/posts", token, map[string]any{
+		"Title": title, "Content": content,
+	})
+	return uint(resp["post"].(map[string]any)["ID"].(float64))
+}
+
+// createComment 创建评论并返回评论 ID
+func (s *testServer) createComment(token string, postID uint, content string) uint {
+	s.t.Helper()
+	resp := s.expect(http.StatusOK, http.MethodPost, "/comments", token, map[string]any{
+		"post_id": postID, "Content": content,
+	})
+	return uint(resp["comment"].(map[string]any)["ID"].(float64))
+}
+
+// newRequest 构造一个带原始 Authorization 头的请求，用于测试异常 token
+func newRequest(method, path, authorization string) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return req
+}
+
+func (s *testServer) serve(req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
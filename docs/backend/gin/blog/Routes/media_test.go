@@ -0,0 +1,235 @@
+package routes
+
+import (
+	"blog/storage"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// pngData 是以 PNG 文件头开头的内容，足以被识别为 image/png
+var pngData = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 100)...)
+
+// uploadRequest 构造上传文件的请求，声明的 Content-Type 固定为 image/png，服务端应该忽略它
+func uploadRequest(token string, postID uint, filename string, content []byte) *http.Request {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, filename))
+	header.Set("Content-Type", "image/png")
+	part, _ := w.CreatePart(header)
+	part.Write(content)
+	w.Close()
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/posts/%d/media", postID), &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+// upload 上传文件并断言状态码，返回响应中的 data
+func (s *testServer) upload(status int, token string, postID uint, filename string, content []byte) map[string]any {
+	s.t.Helper()
+	rec := s.serve(uploadRequest(token, postID, filename, content))
+	if rec.Code != status {
+		s.t.Fatalf("上传 %s: 期望状态码 %d，实际 %d，响应: %s", filename, status, rec.Code, rec.Body.String())
+	}
+	return decode(s.t, rec)
+}
+
+func TestMediaUpload(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			_, bob := s.newUser("bob")
+			postID := s.createPost(alice, "标题", "内容")
+
+			data := s.upload(http.StatusOK, alice, postID, "照片[1].png", pngData)
+			media := data["media"].(map[string]any)
+			url := media["url"].(string)
+			if media["content_type"] != "image/png" || media["size"] != float64(len(pngData)) || media["filename"] != "照片[1].png" ||
+				!strings.HasPrefix(url, "/media/") || !strings.HasSuffix(url, ".png") {
+				t.Fatalf("上传结果不符合预期: %v", media)
+			}
+			if data["markdown"] != fmt.Sprintf(`![照片\[1\].png](%s)`, url) {
+				t.Fatalf("图片的 Markdown 不符合预期: %v", data["markdown"])
+			}
+
+			// 不需要登录就可以访问，图片直接显示，浏览器不会按内容重新猜测类型
+			rec := s.do(http.MethodGet, url, "", nil)
+			if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), pngData) ||
+				rec.Header().Get("Content-Type") != "image/png" || rec.Header().Get("X-Content-Type-Options") != "nosniff" ||
+				!strings.HasPrefix(rec.Header().Get("Content-Disposition"), "inline") {
+				t.Fatalf("访问图片的响应不符合预期: %d %v", rec.Code, rec.Header())
+			}
+			etag := rec.Header().Get("ETag")
+			if rec = s.doWith(http.MethodGet, url, "", nil, map[string]string{"If-None-Match": etag}); rec.Code != http.StatusNotModified {
+				t.Fatalf("带上 ETag 时期望 304，实际 %d", rec.Code)
+			}
+
+			// 其他类型的文件以附件的形式下载，扩展名取决于检测出的类型
+			data = s.upload(http.StatusOK, alice, postID, "report.png", []byte("%PDF-1.4\n内容"))
+			pdf := data["media"].(map[string]any)
+			if pdf["content_type"] != "application/pdf" || !strings.HasSuffix(pdf["url"].(string), ".pdf") ||
+				data["markdown"] != fmt.Sprintf("[report.png](%s)", pdf["url"]) {
+				t.Fatalf("PDF 的上传结果不符合预期: %v", data)
+			}
+			rec = s.do(http.MethodGet, pdf["url"].(string), "", nil)
+			if disposition := rec.Header().Get("Content-Disposition"); disposition != `attachment; filename=report.png` {
+				t.Fatalf("附件应该以下载的形式返回: %q", disposition)
+			}
+
+			// 文章的文件列表
+			list := s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/posts/%d/media", postID), "", nil)["media"].([]any)
+			if len(list) != 2 || list[0].(map[string]any)["url"] != url {
+				t.Fatalf("文件列表不符合预期: %v", list)
+			}
+
+			// 上传的图片可以插入 Markdown 文章
+			resp := s.expect(http.StatusOK, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "带图片的文章", "Content": data["markdown"].(string) + "\n\n" + s.upload(http.StatusOK, alice, postID, "a.png", pngData)["markdown"].(string),
+				"format": "markdown",
+			})
+			withImage := uint(resp["post"].(map[string]any)["ID"].(float64))
+			post := s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/posts/%d?render=html", withImage), "", nil)["post"].(map[string]any)
+			if !strings.Contains(post["content_html"].(string), `<img src="/media/`) {
+				t.Fatalf("渲染后的文章应该包含图片: %s", post["content_html"])
+			}
+
+			// 只有文章作者可以上传
+			s.upload(http.StatusForbidden, bob, postID, "a.png", pngData)
+			s.upload(http.StatusUnauthorized, "", postID, "a.png", pngData)
+			s.upload(http.StatusNotFound, alice, 9999, "a.png", pngData)
+		})
+	}
+}
+
+func TestMediaValidation(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			postID := s.createPost(alice, "标题", "内容")
+
+			// 类型根据内容检测：改了扩展名和 Content-Type 的 HTML 仍然被拒绝
+			resp := envelope(t, s.serve(uploadRequest(alice, postID, "evil.png", []byte("<html><script>alert(1)</script></html>"))))
+			if resp.Code != 1010 || !strings.Contains(resp.Msg, "text/html") {
+				t.Fatalf("伪装成图片的 HTML 应该被拒绝: %+v", resp)
+			}
+			s.upload(http.StatusUnsupportedMediaType, alice, postID, "a.txt", []byte("纯文本"))
+			s.upload(http.StatusBadRequest, alice, postID, "empty.png", nil)
+
+			// 超过大小限制：文件本身超出，以及整个请求体超出
+			s.deps.Config.Media.MaxSize = 1
+			big := append(bytes.Clone(pngData), make([]byte, 1<<20)...)
+			s.upload(http.StatusRequestEntityTooLarge, alice, postID, "big.png", big)
+			s.upload(http.StatusRequestEntityTooLarge, alice, postID, "huge.png", append(big, make([]byte, 2<<20)...))
+			s.upload(http.StatusOK, alice, postID, "small.png", pngData)
+
+			// 不是 multipart 请求，或者没有 file 字段
+			s.expect(http.StatusBadRequest, http.MethodPost, fmt.Sprintf("/posts/%d/media", postID), alice, map[string]any{"file": "a.png"})
+
+			// 草稿的文件列表只有作者可以查看
+			resp2 := s.expect(http.StatusOK, http.MethodPost, "/posts", alice, map[string]any{"Title": "草稿", "Content": "内容", "status": "draft"})
+			draftID := uint(resp2["post"].(map[string]any)["ID"].(float64))
+			s.upload(http.StatusOK, alice, draftID, "a.png", pngData)
+			s.expect(http.StatusNotFound, http.MethodGet, fmt.Sprintf("/posts/%d/media", draftID), "", nil)
+			s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/posts/%d/media", draftID), alice, nil)
+
+			// 不存在或不合法的 key
+			s.expect(http.StatusNotFound, http.MethodGet, "/media/missing.png", "", nil)
+			s.expect(http.StatusNotFound, http.MethodGet, "/media/..png", "", nil)
+		})
+	}
+}
+
+func TestMediaDelete(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			_, bob := s.newUser("bob")
+			_, admin := s.newUser("admin")
+			postID := s.createPost(alice, "标题", "内容")
+			first := s.upload(http.StatusOK, alice, postID, "a.png", pngData)["media"].(map[string]any)
+			second := s.upload(http.StatusOK, alice, postID, "b.png", pngData)["media"].(map[string]any)
+			firstPath := fmt.Sprintf("/media/%d", uint(first["id"].(float64)))
+
+			s.expect(http.StatusUnauthorized, http.MethodDelete, firstPath, "", nil)
+			s.expect(http.StatusForbidden, http.MethodDelete, firstPath, bob, nil)
+			s.expect(http.StatusOK, http.MethodDelete, firstPath, alice, nil)
+			s.expect(http.StatusNotFound, http.MethodDelete, firstPath, alice, nil)
+			s.expect(http.StatusNotFound, http.MethodGet, first["url"].(string), "", nil)
+			// 存储中的文件也被删除
+			key := strings.TrimPrefix(first["url"].(string), "/media/")
+			if _, err := s.deps.Storage.Open(context.Background(), key); !errors.Is(err, storage.ErrNotExist) {
+				t.Fatalf("删除后存储中不应该还有文件: %v", err)
+			}
+
+			// 管理员可以删除任何人的文件
+			s.expect(http.StatusOK, http.MethodDelete, fmt.Sprintf("/media/%d", uint(second["id"].(float64))), admin, nil)
+			if list := s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/posts/%d/media", postID), "", nil)["media"].([]any); len(list) != 0 {
+				t.Fatalf("删除后文件列表应该为空: %v", list)
+			}
+		})
+	}
+}
+
+func TestMediaPostLifecycle(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			postID := s.createPost(alice, "标题", "内容")
+			media := s.upload(http.StatusOK, alice, postID, "a.png", pngData)["media"].(map[string]any)
+			url := media["url"].(string)
+
+			// 已发布文章的文件允许共享缓存长期保存
+			rec := s.do(http.MethodGet, url, "", nil)
+			if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "public, max-age=31536000, immutable" {
+				t.Fatalf("已发布文章的文件: 状态码 %d，Cache-Control %q", rec.Code, rec.Header().Get("Cache-Control"))
+			}
+			// 撤回为草稿后仍然可以访问，但不允许共享缓存保存
+			s.edit(http.StatusOK, http.MethodPatch, fmt.Sprintf("/posts/%d/status", postID), alice, postID, map[string]any{"status": "draft"})
+			rec = s.do(http.MethodGet, url, "", nil)
+			if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "private, no-cache" {
+				t.Fatalf("草稿的文件: 状态码 %d，Cache-Control %q", rec.Code, rec.Header().Get("Cache-Control"))
+			}
+			rec = s.doWith(http.MethodGet, url, "", nil, map[string]string{"If-None-Match": rec.Header().Get("ETag")})
+			if rec.Code != http.StatusNotModified || rec.Header().Get("Cache-Control") != "private, no-cache" {
+				t.Fatalf("草稿的文件回源验证: 状态码 %d，Cache-Control %q", rec.Code, rec.Header().Get("Cache-Control"))
+			}
+
+			// 删除文章时文件一起删除
+			s.expect(http.StatusOK, http.MethodDelete, fmt.Sprintf("/posts/%d", postID), alice, nil)
+			s.expect(http.StatusNotFound, http.MethodGet, url, "", nil)
+			if _, err := s.deps.Storage.Open(context.Background(), strings.TrimPrefix(url, "/media/")); !errors.Is(err, storage.ErrNotExist) {
+				t.Fatalf("删除文章后存储中不应该还有文件: %v", err)
+			}
+			if _, err := s.deps.Media.FindByID(context.Background(), uint(media["id"].(float64))); err == nil {
+				t.Fatal("删除文章后不应该还有文件记录")
+			}
+		})
+	}
+}
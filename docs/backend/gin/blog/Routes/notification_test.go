@@ -0,0 +1,199 @@
+package routes
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// notifications 返回当前用户的通知类型（最新的在前）和未读数
+func (s *testServer) notifications(token, query string) ([]string, float64) {
+	s.t.Helper()
+	resp := s.expect(http.StatusOK, http.MethodGet, "/notifications"+query, token, nil)
+	var types []string
+	for _, item := range resp["notifications"].([]any) {
+		n := item.(map[string]any)
+		types = append(types, fmt.Sprintf("%s:%s", n["type"], n["actor_name"]))
+	}
+	return types, resp["unread_count"].(float64)
+}
+
+func TestNotifications(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			aliceID, alice := s.newUser("alice")
+			_, bob := s.newUser("bob")
+			_, carol := s.newUser("carol")
+			postID := s.createPost(alice, "标题", "内容")
+
+			// 评论通知文章作者，自己评论自己的文章不通知
+			bobComment := s.createComment(bob, postID, "评论")
+			s.createComment(alice, postID, "作者的评论")
+			// 回复通知被回复的人，同时通知文章作者
+			s.reply(carol, postID, bobComment, "回复")
+			// 文章作者回复别人时只通知被回复的人
+			s.reply(alice, postID, bobComment, "作者的回复")
+			// 关注通知被关注的人，重复关注不重复通知
+			s.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", aliceID), bob, nil)
+			s.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", aliceID), bob, nil)
+
+			types, unread := s.notifications(alice, "")
+			if fmt.Sprint(types) != "[follow:bob comment:carol comment:bob]" || unread != 3 {
+				t.Fatalf("alice 的通知不符合预期: %v %v", types, unread)
+			}
+			types, unread = s.notifications(bob, "")
+			if fmt.Sprint(types) != "[reply:alice reply:carol]" || unread != 2 {
+				t.Fatalf("bob 的通知不符合预期: %v %v", types, unread)
+			}
+
+			// 标记已读
+			resp := s.expect(http.StatusOK, http.MethodGet, "/notifications?size=1", alice, nil)
+			latest := resp["notifications"].([]any)[0].(map[string]any)
+			path := fmt.Sprintf("/notifications/%v/read", latest["id"])
+			s.expect(http.StatusNotFound, http.MethodPatch, path, bob, nil)
+			s.expect(http.StatusOK, http.MethodPatch, path, alice, nil)
+			s.expect(http.StatusOK, http.MethodPatch, path, alice, nil)
+			s.expect(http.StatusNotFound, http.MethodPatch, "/notifications/9999/read", alice, nil)
+			types, unread = s.notifications(alice, "?unread=true")
+			if fmt.Sprint(types) != "[comment:carol comment:bob]" || unread != 2 {
+				t.Fatalf("标记已读后的未读通知不符合预期: %v %v", types, unread)
+			}
+			marked := s.expect(http.StatusOK, http.MethodPost, "/notifications/read", alice, nil)["marked"]
+			if types, unread = s.notifications(alice, "?unread=true"); len(types) != 0 || unread != 0 || marked != float64(2) {
+				t.Fatalf("全部标记已读后仍有未读通知: %v %v %v", types, unread, marked)
+			}
+			if types, _ = s.notifications(alice, ""); len(types) != 3 {
+				t.Fatalf("已读的通知仍然应该出现在列表中: %v", types)
+			}
+		})
+	}
+}
+
+func TestNotificationsForPendingReplies(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			_, bob := s.newUser("bob")
+			_, carol := s.newUser("carol")
+			resp := s.expect(http.StatusOK, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "需要审核", "Content": "内容", "comment_mode": "review",
+			})
+			postID := uint(resp["post"].(map[string]any)["ID"].(float64))
+			aliceComment := s.createComment(alice, postID, "作者的评论")
+
+			// 待审核的回复只通知文章作者去审核，审核通过后才通知被回复的人
+			replyID := s.reply(bob, postID, aliceComment, "回复")
+			if types, _ := s.notifications(alice, ""); fmt.Sprint(types) != "[comment:bob]" {
+				t.Fatalf("待审核的回复应该通知文章作者: %v", types)
+			}
+			carolComment := s.createComment(carol, postID, "评论")
+			s.expect(http.StatusOK, http.MethodPatch, fmt.Sprintf("/comments/%d/approve", carolComment), alice, nil)
+			carolReply := s.reply(bob, postID, carolComment, "回复 carol")
+			if types, _ := s.notifications(carol, ""); len(types) != 0 {
+				t.Fatalf("回复审核通过前不应通知被回复的人: %v", types)
+			}
+			s.expect(http.StatusOK, http.MethodPatch, fmt.Sprintf("/comments/%d/approve", carolReply), alice, nil)
+			s.expect(http.StatusOK, http.MethodPatch, fmt.Sprintf("/comments/%d/approve", carolReply), alice, nil)
+			if types, _ := s.notifications(carol, ""); fmt.Sprint(types) != "[reply:bob]" {
+				t.Fatalf("回复审核通过后应该通知被回复的人一次: %v", types)
+			}
+			// 回复文章作者的评论时，作者已经收到过待审核通知，审核通过后不再重复通知
+			s.expect(http.StatusOK, http.MethodPatch, fmt.Sprintf("/comments/%d/approve", replyID), alice, nil)
+			if types, _ := s.notifications(alice, ""); len(types) != 3 {
+				t.Fatalf("文章作者的通知不符合预期: %v", types)
+			}
+		})
+	}
+}
+
+// sseEvent 是从事件流中读到的一个事件
+type sseEvent struct {
+	name string
+	data map[string]any
+}
+
+// readEvent 读取下一个事件，跳过心跳等注释行
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var ev sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("读取事件流失败: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			ev.name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), &ev.data); err != nil {
+				t.Fatalf("解析事件数据失败: %v %q", err, line)
+			}
+		case line == "" && ev.name != "":
+			return ev
+		}
+	}
+}
+
+func TestNotificationStream(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			bobID, bob := s.newUser("bob")
+			postID := s.createPost(alice, "标题", "内容")
+			s.createComment(bob, postID, "离线时的评论")
+
+			// 事件流需要真实的连接才能边写边读
+			srv := httptest.NewServer(s.router)
+			defer srv.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/notifications/stream", nil)
+			req.Header.Set("Authorization", "Bearer "+alice)
+			resp, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatalf("连接事件流失败: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+				t.Fatalf("事件流的响应不符合预期: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+			}
+			r := bufio.NewReader(resp.Body)
+
+			// 连接后先收到当前的未读数
+			if ev := readEvent(t, r); ev.name != "unread" || ev.data["count"] != float64(1) {
+				t.Fatalf("第一个事件应该是未读数: %+v", ev)
+			}
+			commentID := s.createComment(bob, postID, "在线时的评论")
+			ev := readEvent(t, r)
+			if ev.name != "notification" || ev.data["type"] != "comment" || ev.data["actor_name"] != "bob" ||
+				ev.data["comment_id"] != float64(commentID) {
+				t.Fatalf("推送的通知不符合预期: %+v", ev)
+			}
+			// 别人的通知不会推送给 alice
+			s.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", bobID), alice, nil)
+			s.createComment(bob, postID, "第三条评论")
+			if ev := readEvent(t, r); ev.data["type"] != "comment" {
+				t.Fatalf("收到了不属于 alice 的通知: %+v", ev)
+			}
+		})
+	}
+}
@@ -0,0 +1,535 @@
+package routes
+
+import (
+	"blog/repository"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+func TestPostCRUD(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			aliceID, alice := s.newUser("alice")
+
+			postID := s.createPost(alice, "Hello", "first post")
+
+			resp := s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/posts/%d", postID), alice, nil)
+			post := resp["post"].(map[string]any)
+			if post["Title"] != "Hello" || post["Content"] != "first post" {
+				t.Fatalf("文章内容不符: %v", post)
+			}
+			if uint(post["user_id"].(float64)) != aliceID {
+				t.Fatalf("文章作者应为 %d，实际 %v", aliceID, post["user_id"])
+			}
+
+			resp = s.expect(http.StatusOK, http.MethodGet, "/posts", alice, nil)
+			if n := len(resp["posts"].([]any)); n != 1 {
+				t.Fatalf("期望 1 篇文章，实际 %d", n)
+			}
+			resp = s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/users/%d/posts", aliceID), alice, nil)
+			if n := len(resp["posts"].([]any)); n != 1 {
+				t.Fatalf("期望用户有 1 篇文章，实际 %d", n)
+			}
+
+			resp = s.edit(http.StatusOK, http.MethodPut, fmt.Sprintf("/posts/%d", postID), alice, postID, map[string]any{
+				"title": "Hello again", "content": "edited",
+			})
+			if post := resp["post"].(map[string]any); post["Title"] != "Hello again" || post["Content"] != "edited" {
+				t.Fatalf("更新后的文章内容不符: %v", post)
+			}
+
+			s.expect(http.StatusOK, http.MethodDelete, fmt.Sprintf("/posts/%d", postID), alice, nil)
+			s.expect(http.StatusNotFound, http.MethodGet, fmt.Sprintf("/posts/%d", postID), alice, nil)
+		})
+	}
+}
+
+func TestPostValidation(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.newUser("alice")
+
+	s.expect(http.StatusNotFound, http.MethodGet, "/posts/999", alice, nil)
+	s.expect(http.StatusBadRequest, http.MethodGet, "/posts/abc", alice, nil)
+	s.expect(http.StatusNotFound, http.MethodPut, "/posts/999", alice, map[string]any{"title": "t", "content": "c"})
+	s.expect(http.StatusNotFound, http.MethodDelete, "/posts/999", alice, nil)
+	s.expect(http.StatusBadRequest, http.MethodGet, "/users/abc/posts", alice, nil)
+}
+
+func TestPostOwnership(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.newUser("alice")
+	_, bob := s.newUser("bob")
+	postID := s.createPost(alice, "Hello", "first post")
+	path := fmt.Sprintf("/posts/%d", postID)
+
+	// 非作者不能修改或删除文章
+	s.expect(http.StatusForbidden, http.MethodPut, path, bob, map[string]any{"title": "hacked", "content": "hacked"})
+	s.expect(http.StatusForbidden, http.MethodDelete, path, bob, nil)
+
+	resp := s.expect(http.StatusOK, http.MethodGet, path, alice, nil)
+	if post := resp["post"].(map[string]any); post["Title"] != "Hello" {
+		t.Fatalf("非作者的修改不应生效: %v", post)
+	}
+}
+
+// listIDs 请求文章列表，返回文章 ID 和分页信息
+func (s *testServer) listIDs(path, token, key string) ([]uint, map[string]any) {
+	s.t.Helper()
+	resp := s.expect(http.StatusOK, http.MethodGet, path, token, nil)
+	var ids []uint
+	for _, item := range resp[key].([]any) {
+		ids = append(ids, uint(item.(map[string]any)["ID"].(float64)))
+	}
+	return ids, resp["pagination"].(map[string]any)
+}
+
+func TestListPostsPagination(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			aliceID, alice := s.newUser("alice")
+			_, bob := s.newUser("bob")
+			var all []uint
+			for i := 0; i < 5; i++ {
+				all = append(all, s.createPost(alice, fmt.Sprintf("post %d", i), "content"))
+			}
+			all = append(all, s.createPost(bob, "bob's post", "content"))
+
+			// 默认最新的在前
+			ids, meta := s.listIDs("/posts?size=4", alice, "posts")
+			if fmt.Sprint(ids) != fmt.Sprint([]uint{all[5], all[4], all[3], all[2]}) {
+				t.Fatalf("第一页顺序不符: %v", ids)
+			}
+			if meta["total"].(float64) != 6 || meta["page"].(float64) != 1 || meta["next_cursor"] == "" {
+				t.Fatalf("分页信息不符: %v", meta)
+			}
+
+			// 页码分页
+			ids, meta = s.listIDs("/posts?size=4&page=2", alice, "posts")
+			if fmt.Sprint(ids) != fmt.Sprint([]uint{all[1], all[0]}) || meta["next_cursor"] != "" {
+				t.Fatalf("第二页不符: %v %v", ids, meta)
+			}
+
+			// 游标分页：依次翻完所有文章
+			var walked []uint
+			cursor := ""
+			for {
+				ids, meta = s.listIDs("/posts?size=4&sort=created_at&cursor="+cursor, alice, "posts")
+				walked = append(walked, ids...)
+				if cursor = meta["next_cursor"].(string); cursor == "" {
+					break
+				}
+				if _, ok := meta["page"]; ok && len(walked) > 4 {
+					t.Fatalf("游标分页不应返回页码: %v", meta)
+				}
+			}
+			if fmt.Sprint(walked) != fmt.Sprint(all) {
+				t.Fatalf("游标翻页结果不符: %v", walked)
+			}
+
+			// 按作者过滤
+			ids, meta = s.listIDs(fmt.Sprintf("/posts?author_id=%d", aliceID), alice, "posts")
+			if len(ids) != 5 || meta["total"].(float64) != 5 {
+				t.Fatalf("按作者过滤结果不符: %v", ids)
+			}
+			ids, _ = s.listIDs(fmt.Sprintf("/users/%d/posts?size=2", aliceID), alice, "posts")
+			if fmt.Sprint(ids) != fmt.Sprint([]uint{all[4], all[3]}) {
+				t.Fatalf("用户文章分页结果不符: %v", ids)
+			}
+
+			// 按日期过滤
+			today := time.Now().Format(time.DateOnly)
+			if ids, _ = s.listIDs("/posts?from="+today+"&to="+today, alice, "posts"); len(ids) != 6 {
+				t.Fatalf("今天应有 6 篇文章，实际 %d", len(ids))
+			}
+			tomorrow := time.Now().AddDate(0, 0, 1).Format(time.DateOnly)
+			if ids, _ = s.listIDs("/posts?from="+tomorrow, alice, "posts"); len(ids) != 0 {
+				t.Fatalf("明天之后不应有文章，实际 %d", len(ids))
+			}
+		})
+	}
+}
+
+func TestListPostsInvalidQuery(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.newUser("alice")
+
+	for _, query := range []string{
+		"size=101", "page=-1", "sort=title", "cursor=%21%21", "from=yesterday",
+		"from=2024-02-01&to=2024-01-01",
+	} {
+		s.expect(http.StatusBadRequest, http.MethodGet, "/posts?"+query, alice, nil)
+	}
+}
+
+func TestPostLifecycle(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			_, bob := s.newUser("bob")
+			published := s.createPost(alice, "已发布", "内容")
+
+			// 草稿只有作者可见，也不能被搜到
+			draft := s.expect(http.StatusOK, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "草稿", "Content": "独一无二的草稿", "status": "draft",
+			})["post"].(map[string]any)
+			draftID := uint(draft["ID"].(float64))
+			if draft["status"] != "draft" || draft["published_at"] != nil {
+				t.Fatalf("草稿状态不符合预期: %v", draft)
+			}
+			s.expect(http.StatusNotFound, http.MethodGet, fmt.Sprintf("/posts/%d", draftID), bob, nil)
+			s.expect(http.StatusNotFound, http.MethodPost, "/comments", bob, map[string]any{"post_id": draftID, "Content": "抢沙发"})
+			s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/posts/%d", draftID), alice, nil)
+			if ids, _ := s.listIDs("/posts", bob, "posts"); len(ids) != 1 || ids[0] != published {
+				t.Fatalf("其他用户不应看到草稿: %v", ids)
+			}
+			if ids, _ := s.listIDs("/posts?status=draft", alice, "posts"); len(ids) != 1 || ids[0] != draftID {
+				t.Fatalf("作者应能按状态筛选自己的草稿: %v", ids)
+			}
+			if hits := s.searchHits(bob, "q="+url.QueryEscape("独一无二")); len(hits) != 0 {
+				t.Fatalf("草稿不应被搜到: %v", hits)
+			}
+
+			// 发布后所有人可见，并记录发布时间
+			s.expect(http.StatusForbidden, http.MethodPatch, fmt.Sprintf("/posts/%d/status", published), bob, map[string]any{"status": "draft"})
+			post := s.edit(http.StatusOK, http.MethodPatch, fmt.Sprintf("/posts/%d/status", draftID), alice, draftID,
+				map[string]any{"status": "published"})["post"].(map[string]any)
+			if post["status"] != "published" || post["published_at"] == nil {
+				t.Fatalf("发布后的状态不符合预期: %v", post)
+			}
+			s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/posts/%d", draftID), bob, nil)
+			if hits := s.searchHits(bob, "q="+url.QueryEscape("独一无二")); len(hits) != 1 {
+				t.Fatalf("发布后应能被搜到: %v", hits)
+			}
+
+			// 定时发布必须指定未来的时间，到期后由调度器发布
+			s.expect(http.StatusBadRequest, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "定时", "Content": "内容", "status": "scheduled",
+			})
+			s.expect(http.StatusBadRequest, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "定时", "Content": "内容", "publish_at": time.Now().Add(-time.Hour),
+			})
+			scheduled := s.expect(http.StatusOK, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "定时", "Content": "定时发布的内容", "publish_at": time.Now().Add(time.Hour),
+			})["post"].(map[string]any)
+			scheduledID := uint(scheduled["ID"].(float64))
+			if scheduled["status"] != "scheduled" {
+				t.Fatalf("带 publish_at 的文章应为定时发布: %v", scheduled)
+			}
+			s.expect(http.StatusNotFound, http.MethodGet, fmt.Sprintf("/posts/%d", scheduledID), bob, nil)
+			if n, err := s.deps.PublishDuePosts(context.Background(), time.Now()); err != nil || n != 0 {
+				t.Fatalf("未到期的文章不应被发布: %d, %v", n, err)
+			}
+			if n, err := s.deps.PublishDuePosts(context.Background(), time.Now().Add(2*time.Hour)); err != nil || n != 1 {
+				t.Fatalf("到期的文章应被发布: %d, %v", n, err)
+			}
+			post = s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/posts/%d", scheduledID), bob, nil)["post"].(map[string]any)
+			// 发布后不再是定时文章，publish_at 被清空
+			if post["status"] != "published" || post["published_at"] == nil || post["publish_at"] != nil {
+				t.Fatalf("调度器发布后的状态不符合预期: %v", post)
+			}
+			if hits := s.searchHits(bob, "q="+url.QueryEscape("定时发布")); len(hits) != 1 {
+				t.Fatalf("调度器发布的文章应能被搜到: %v", hits)
+			}
+
+			// 归档的文章仍可通过 ID 访问，但不出现在列表和搜索中，也不能评论
+			s.edit(http.StatusOK, http.MethodPatch, fmt.Sprintf("/posts/%d/status", draftID), alice, draftID, map[string]any{"status": "archived"})
+			s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/posts/%d", draftID), bob, nil)
+			s.expect(http.StatusForbidden, http.MethodPost, "/comments", bob, map[string]any{"post_id": draftID, "Content": "评论"})
+			if ids, _ := s.listIDs("/posts", bob, "posts"); len(ids) != 2 {
+				t.Fatalf("归档的文章不应出现在列表中: %v", ids)
+			}
+			if hits := s.searchHits(bob, "q="+url.QueryEscape("独一无二")); len(hits) != 0 {
+				t.Fatalf("归档的文章不应被搜到: %v", hits)
+			}
+			s.edit(http.StatusBadRequest, http.MethodPatch, fmt.Sprintf("/posts/%d/status", draftID), alice, draftID, map[string]any{"status": "deleted"})
+		})
+	}
+}
+
+func TestPostConcurrency(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			_, bob := s.newUser("bob")
+			postID := s.createPost(alice, "标题", "内容")
+			path := fmt.Sprintf("/posts/%d", postID)
+			body := map[string]any{"title": "新标题", "content": "新内容"}
+
+			// ETag 没有变化时返回 304，兼容弱校验的 W/ 前缀
+			first := s.etag(alice, postID)
+			for _, tag := range []string{first, "W/" + first, `"other", ` + first} {
+				if rec := s.doWith(http.MethodGet, path, alice, nil, map[string]string{"If-None-Match": tag}); rec.Code != http.StatusNotModified {
+					t.Fatalf("If-None-Match %s: 期望 304，实际 %d", tag, rec.Code)
+				}
+			}
+
+			// 修改必须带 If-Match，版本不一致时返回 412
+			s.expect(http.StatusPreconditionRequired, http.MethodPut, path, alice, body)
+			// If-Match 使用强比较，弱校验的 ETag 即使版本号相同也不匹配
+			for _, tag := range []string{"W/" + first, `"other", W/` + first} {
+				if rec := s.doWith(http.MethodPut, path, alice, body, map[string]string{"If-Match": tag}); rec.Code != http.StatusPreconditionFailed {
+					t.Fatalf("If-Match %s: 期望 412，实际 %d", tag, rec.Code)
+				}
+			}
+			rec := s.doWith(http.MethodPut, path, alice, body, map[string]string{"If-Match": first})
+			if rec.Code != http.StatusOK {
+				t.Fatalf("带当前 ETag 修改应成功，实际 %d: %s", rec.Code, rec.Body.String())
+			}
+			second := rec.Header().Get("ETag")
+			if post := decode(t, rec)["post"].(map[string]any); post["version"].(float64) != 2 || second == "" || second == first {
+				t.Fatalf("修改后版本号应为 2 并返回新的 ETag: %v, %s", post, second)
+			}
+			for _, method := range []string{http.MethodPut, http.MethodPatch} {
+				target := path
+				if method == http.MethodPatch {
+					target += "/status"
+				}
+				rec = s.doWith(method, target, alice, map[string]any{"title": "覆盖", "content": "覆盖", "status": "draft"}, map[string]string{"If-Match": first})
+				if rec.Code != http.StatusPreconditionFailed {
+					t.Fatalf("%s 使用旧 ETag 应返回 412，实际 %d", method, rec.Code)
+				}
+			}
+			if rec = s.doWith(http.MethodGet, path, alice, nil, map[string]string{"If-None-Match": first}); rec.Code != http.StatusOK {
+				t.Fatalf("文章修改后旧 ETag 不应命中缓存，实际 %d", rec.Code)
+			}
+			// 权限检查先于版本检查
+			s.expect(http.StatusForbidden, http.MethodPut, path, bob, body)
+
+			// 新增评论不改变版本号，之前的 If-Match 仍然有效；If-Match: * 不检查版本
+			s.createComment(bob, postID, "评论")
+			if rec = s.doWith(http.MethodPatch, path+"/status", alice, map[string]any{"status": "archived"}, map[string]string{"If-Match": second}); rec.Code != http.StatusOK {
+				t.Fatalf("评论不应改变版本号，实际 %d: %s", rec.Code, rec.Body.String())
+			}
+			if rec = s.doWith(http.MethodPut, path, alice, body, map[string]string{"If-Match": "*"}); rec.Code != http.StatusOK {
+				t.Fatalf("If-Match: * 应跳过版本检查，实际 %d", rec.Code)
+			}
+
+			// 两个请求读到同一个版本后先后保存，后保存的返回 ErrStale
+			a, err := s.deps.Posts.FindByID(context.Background(), postID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := s.deps.Posts.FindByID(context.Background(), postID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a.Content, b.Content = "A", "B"
+			if err := s.deps.Posts.Update(context.Background(), a); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.deps.Posts.Update(context.Background(), b); !errors.Is(err, repository.ErrStale) {
+				t.Fatalf("后保存的修改应返回 ErrStale，实际 %v", err)
+			}
+			if b.Version != a.Version-1 {
+				t.Fatalf("保存失败时不应修改版本号: %d, %d", a.Version, b.Version)
+			}
+			post := s.expect(http.StatusOK, http.MethodGet, path, alice, nil)["post"].(map[string]any)
+			if post["Content"] != "A" {
+				t.Fatalf("后保存的修改不应覆盖先保存的: %v", post)
+			}
+		})
+	}
+}
+
+func TestPostPatch(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			_, bob := s.newUser("bob")
+			postID := s.createPost(alice, "标题", "正文")
+			path := fmt.Sprintf("/posts/%d", postID)
+
+			// PUT 是整篇替换，缺少内容时拒绝，而不是把内容清空
+			s.edit(http.StatusBadRequest, http.MethodPut, path, alice, postID, map[string]any{"title": "只有标题"})
+
+			// PATCH 只修改出现的字段
+			post := s.edit(http.StatusOK, http.MethodPatch, path, alice, postID, map[string]any{"title": "新标题"})["post"].(map[string]any)
+			if post["Title"] != "新标题" || post["Content"] != "正文" || post["comment_mode"] != "auto" {
+				t.Fatalf("只应修改标题: %v", post)
+			}
+			post = s.edit(http.StatusOK, http.MethodPatch, path, alice, postID, map[string]any{"Content": "新正文", "comment_mode": "review"})["post"].(map[string]any)
+			if post["Title"] != "新标题" || post["Content"] != "新正文" || post["comment_mode"] != "review" {
+				t.Fatalf("应修改内容和评论模式: %v", post)
+			}
+			if got := s.revisionNumbers(alice, postID); fmt.Sprint(got) != "[3 2 1]" {
+				t.Fatalf("部分更新也应记录修订: %v", got)
+			}
+
+			for _, body := range []any{
+				map[string]any{},
+				map[string]any{"content": nil},
+				map[string]any{"title": ""},
+				map[string]any{"title": strings.Repeat("长", 101)},
+				map[string]any{"title": 5},
+				map[string]any{"comment_mode": "none"},
+				map[string]any{"status": "draft"},
+				[]any{"title"},
+			} {
+				s.edit(http.StatusBadRequest, http.MethodPatch, path, alice, postID, body)
+			}
+			rec := s.doWith(http.MethodPatch, path, alice, map[string]any{"title": nil}, map[string]string{"If-Match": "*"})
+			if details := envelope(t, rec).Details; rec.Code != http.StatusBadRequest || len(details) != 1 || details[0].Field != "title" {
+				t.Fatalf("null 应返回 title 的字段错误: %d %s", rec.Code, rec.Body.String())
+			}
+			s.expect(http.StatusPreconditionRequired, http.MethodPatch, path, alice, map[string]any{"title": "x"})
+			s.expect(http.StatusForbidden, http.MethodPatch, path, bob, map[string]any{"title": "x"})
+
+			post = s.expect(http.StatusOK, http.MethodGet, path, alice, nil)["post"].(map[string]any)
+			if post["Title"] != "新标题" || post["Content"] != "新正文" || post["version"].(float64) != 3 {
+				t.Fatalf("失败的修改不应生效: %v", post)
+			}
+		})
+	}
+}
+
+// otherValidator 模拟替换了 gin 的校验器：Engine 不再是 *validator.Validate
+type otherValidator struct{ binding.StructValidator }
+
+func (otherValidator) Engine() any { return nil }
+
+func TestPostPatchOtherValidator(t *testing.T) {
+	defer func(v binding.StructValidator) { binding.Validator = v }(binding.Validator)
+	binding.Validator = otherValidator{binding.Validator}
+
+	s := newMemoryTestServer(t)
+	_, alice := s.newUser("alice")
+	postID := s.createPost(alice, "标题", "正文")
+	path := fmt.Sprintf("/posts/%d", postID)
+	// 没有修改的字段用文章当前的值补齐后校验
+	post := s.edit(http.StatusOK, http.MethodPatch, path, alice, postID, map[string]any{"title": "新标题"})["post"].(map[string]any)
+	if post["Title"] != "新标题" || post["Content"] != "正文" {
+		t.Fatalf("只应修改标题: %v", post)
+	}
+	s.edit(http.StatusBadRequest, http.MethodPatch, path, alice, postID, map[string]any{"title": ""})
+	s.edit(http.StatusBadRequest, http.MethodPatch, path, alice, postID, map[string]any{"format": "html"})
+}
+
+func TestPostRendering(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			_, bob := s.newUser("bob")
+			content := "# 标题\n\n<script>alert(1)</script>\n\n[链接](javascript:alert(1)) **粗体**"
+			postID := s.createPost(alice, "标题", content)
+			path := fmt.Sprintf("/posts/%d", postID)
+
+			// 默认是纯文本，不带 render 参数时不返回 HTML
+			post := s.expect(http.StatusOK, http.MethodGet, path, bob, nil)["post"].(map[string]any)
+			if post["format"] != "plain" || post["content_html"] != nil {
+				t.Fatalf("默认格式不符合预期: %v", post)
+			}
+			post = s.expect(http.StatusOK, http.MethodGet, path+"?render=html", bob, nil)["post"].(map[string]any)
+			html := post["content_html"].(string)
+			if !strings.HasPrefix(html, "<p># 标题</p>") || !strings.Contains(html, "&lt;script&gt;") || post["Content"] != content {
+				t.Fatalf("纯文本应该转义后原样显示: %q", html)
+			}
+			s.expect(http.StatusBadRequest, http.MethodGet, path+"?render=pdf", bob, nil)
+			s.edit(http.StatusBadRequest, http.MethodPatch, path, alice, postID, map[string]any{"format": "html"})
+
+			// 改成 Markdown 后渲染结果随版本号更新，脚本和 javascript: 链接被过滤
+			s.edit(http.StatusOK, http.MethodPatch, path, alice, postID, map[string]any{"format": "markdown"})
+			rec := s.do(http.MethodGet, path+"?render=html", bob, nil)
+			html = decode(t, rec)["post"].(map[string]any)["content_html"].(string)
+			if !strings.Contains(html, "<h1>标题</h1>") || !strings.Contains(html, "<strong>粗体</strong>") ||
+				strings.Contains(html, "<script") || strings.Contains(html, "javascript:") {
+				t.Fatalf("Markdown 渲染结果不符合预期: %q", html)
+			}
+			// 两种表示的 ETag 不同，各自可以用 If-None-Match 校验
+			if rec.Header().Get("ETag") == s.etag(bob, postID) {
+				t.Fatal("带 HTML 的响应应该有不同的 ETag")
+			}
+			rec = s.doWith(http.MethodGet, path+"?render=html", bob, nil, map[string]string{"If-None-Match": rec.Header().Get("ETag")})
+			if rec.Code != http.StatusNotModified {
+				t.Fatalf("期望 304，实际 %d", rec.Code)
+			}
+
+			// 格式也记录在修订中，恢复修订时一起恢复
+			resp := s.expect(http.StatusOK, http.MethodGet, path+"/revisions/2", alice, nil)
+			if resp["revision"].(map[string]any)["format"] != "markdown" {
+				t.Fatalf("修订中应该记录格式: %v", resp)
+			}
+			post = s.edit(http.StatusOK, http.MethodPost, path+"/revisions/1/restore", alice, postID, nil)["post"].(map[string]any)
+			if post["format"] != "plain" {
+				t.Fatalf("恢复修订后格式应该是 plain: %v", post)
+			}
+		})
+	}
+}
+
+func TestPostCreateInput(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			aliceID, alice := s.newUser("alice")
+			bobID, _ := s.newUser("bob")
+
+			// 请求体中的作者和嵌套的关联都会被忽略，文章只属于当前用户
+			post := s.expect(http.StatusOK, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "标题", "Content": "内容",
+				"user_id":    bobID,
+				"User":       map[string]any{"ID": bobID},
+				"Comments":   []any{map[string]any{"Content": "伪造的评论", "user_id": bobID, "status": "approved"}},
+				"tags":       []string{"Go", " gin ", "go"},
+				"categories": []string{"Backend"},
+			})["post"].(map[string]any)
+			postID := uint(post["ID"].(float64))
+			detail := s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/posts/%d", postID), alice, nil)["post"].(map[string]any)
+			if uint(detail["user_id"].(float64)) != aliceID || uint(detail["User"].(map[string]any)["ID"].(float64)) != aliceID {
+				t.Fatalf("文章的作者应该是当前用户: %v", detail)
+			}
+			if comments := detail["Comments"].([]any); len(comments) != 0 {
+				t.Fatalf("创建文章时不应写入评论: %v", comments)
+			}
+			if got := termNames(detail, "tags"); fmt.Sprint(got) != "[go gin]" {
+				t.Fatalf("标签应该被整理后添加: %v", got)
+			}
+			if got := termNames(post, "categories"); fmt.Sprint(got) != "[Backend]" {
+				t.Fatalf("创建的响应中应该包含分类: %v", got)
+			}
+			if ids, _ := s.listIDs(fmt.Sprintf("/posts?author_id=%d", bobID), alice, "posts"); len(ids) != 0 {
+				t.Fatalf("不应该有属于 bob 的文章: %v", ids)
+			}
+
+			// 标题和内容必须提供
+			s.expect(http.StatusBadRequest, http.MethodPost, "/posts", alice, map[string]any{"Title": "只有标题"})
+			s.expect(http.StatusBadRequest, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "标题", "Content": "内容", "tags": []string{""},
+			})
+		})
+	}
+}
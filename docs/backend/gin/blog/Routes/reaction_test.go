@@ -0,0 +1,176 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// react 设置反应并返回统计结果
+func (s *testServer) react(token, path, kind string) map[string]any {
+	s.t.Helper()
+	return s.expect(http.StatusOK, http.MethodPut, path+"/reactions", token, map[string]any{"kind": kind})["reactions"].(map[string]any)
+}
+
+// counters 返回文章上由钩子维护的点赞数和反应数
+func (s *testServer) counters(token string, postID uint) (likes, reactions float64) {
+	s.t.Helper()
+	post := s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/posts/%d", postID), token, nil)["post"].(map[string]any)
+	return post["like_count"].(float64), post["reaction_count"].(float64)
+}
+
+func TestReactions(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			_, bob := s.newUser("bob")
+			_, carol := s.newUser("carol")
+			postID := s.createPost(alice, "标题", "内容")
+			path := fmt.Sprintf("/posts/%d", postID)
+
+			// 每个用户对每个目标只有一个反应，重复点赞不会重复计数
+			s.react(bob, path, "like")
+			summary := s.react(bob, path, "like")
+			if fmt.Sprint(summary["counts"]) != "map[like:1]" || summary["mine"] != "like" {
+				t.Fatalf("重复点赞后的统计不符合预期: %v", summary)
+			}
+			// 换成表情反应时替换原来的反应
+			summary = s.react(bob, path, "🎉")
+			if fmt.Sprint(summary["counts"]) != "map[🎉:1]" || summary["mine"] != "🎉" {
+				t.Fatalf("替换反应后的统计不符合预期: %v", summary)
+			}
+			if likes, reactions := s.counters(alice, postID); likes != 0 || reactions != 1 {
+				t.Fatalf("替换反应后的计数不符合预期: %v %v", likes, reactions)
+			}
+			s.react(bob, path, "like")
+			s.react(carol, path, "like")
+			if likes, reactions := s.counters(alice, postID); likes != 2 || reactions != 2 {
+				t.Fatalf("点赞后的计数不符合预期: %v %v", likes, reactions)
+			}
+			summary = s.expect(http.StatusOK, http.MethodGet, path+"/reactions", alice, nil)["reactions"].(map[string]any)
+			if fmt.Sprint(summary["counts"]) != "map[like:2]" || summary["mine"] != "" {
+				t.Fatalf("文章作者看到的统计不符合预期: %v", summary)
+			}
+
+			// 修改文章不会覆盖计数
+			s.edit(http.StatusOK, http.MethodPatch, path, alice, postID, map[string]any{"title": "新标题"})
+			if likes, _ := s.counters(alice, postID); likes != 2 {
+				t.Fatalf("修改文章后点赞数不应变化: %v", likes)
+			}
+
+			// 取消反应
+			s.expect(http.StatusOK, http.MethodDelete, path+"/reactions", carol, nil)
+			s.expect(http.StatusNotFound, http.MethodDelete, path+"/reactions", carol, nil)
+			if likes, reactions := s.counters(alice, postID); likes != 1 || reactions != 1 {
+				t.Fatalf("取消点赞后的计数不符合预期: %v %v", likes, reactions)
+			}
+
+			// 只能使用配置中的表情
+			s.expect(http.StatusBadRequest, http.MethodPut, path+"/reactions", bob, map[string]any{"kind": "👎"})
+			s.expect(http.StatusBadRequest, http.MethodPut, path+"/reactions", bob, map[string]any{})
+			s.deps.Config.Reactions.Emojis = nil
+			s.expect(http.StatusBadRequest, http.MethodPut, path+"/reactions", bob, map[string]any{"kind": "🎉"})
+
+			// 评论的反应
+			commentID := s.createComment(bob, postID, "评论")
+			commentPath := fmt.Sprintf("/comments/%d", commentID)
+			s.react(carol, commentPath, "like")
+			comments := s.expect(http.StatusOK, http.MethodGet, path+"/comments", bob, nil)["comments"].([]any)
+			if comment := comments[0].(map[string]any); comment["like_count"].(float64) != 1 {
+				t.Fatalf("评论的点赞数不符合预期: %v", comment)
+			}
+			s.expect(http.StatusNotFound, http.MethodPut, "/comments/999/reactions", carol, map[string]any{"kind": "like"})
+
+			// 看不到的草稿不能做出反应
+			draft := s.expect(http.StatusOK, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "草稿", "Content": "内容", "status": "draft",
+			})["post"].(map[string]any)
+			s.expect(http.StatusNotFound, http.MethodPut, fmt.Sprintf("/posts/%v/reactions", draft["ID"]), bob, map[string]any{"kind": "like"})
+		})
+	}
+}
+
+func TestMostLikedPosts(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			bobID, bob := s.newUser("bob")
+			_, carol := s.newUser("carol")
+			first := s.createPost(alice, "一个赞", "内容")
+			second := s.createPost(alice, "两个赞", "内容")
+			third := s.createPost(bob, "没有赞", "内容")
+			s.react(bob, fmt.Sprintf("/posts/%d", first), "like")
+			s.react(bob, fmt.Sprintf("/posts/%d", second), "like")
+			s.react(carol, fmt.Sprintf("/posts/%d", second), "like")
+			// 表情反应不计入点赞数
+			s.react(carol, fmt.Sprintf("/posts/%d", third), "🎉")
+			// 草稿即使有赞也不出现在排行榜中
+			draft := s.expect(http.StatusOK, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "草稿", "Content": "内容", "status": "draft",
+			})["post"].(map[string]any)
+			s.react(alice, fmt.Sprintf("/posts/%v", draft["ID"]), "like")
+
+			ids, pagination := s.listIDs("/posts/most-liked", bob, "posts")
+			if fmt.Sprint(ids) != fmt.Sprint([]uint{second, first, third}) {
+				t.Fatalf("排行榜顺序不符合预期: %v", ids)
+			}
+			if total := pagination["total"].(float64); total != 3 {
+				t.Fatalf("排行榜总数不符合预期: %v", total)
+			}
+			if ids, _ := s.listIDs("/posts/most-liked?size=1&page=2", bob, "posts"); fmt.Sprint(ids) != fmt.Sprint([]uint{first}) {
+				t.Fatalf("排行榜分页不符合预期: %v", ids)
+			}
+			if ids, _ := s.listIDs(fmt.Sprintf("/posts/most-liked?author_id=%d", bobID), bob, "posts"); fmt.Sprint(ids) != fmt.Sprint([]uint{third}) {
+				t.Fatalf("排行榜按作者过滤不符合预期: %v", ids)
+			}
+			s.expect(http.StatusBadRequest, http.MethodGet, "/posts/most-liked?cursor=x", bob, nil)
+		})
+	}
+}
+
+// 计数只由反应的钩子维护，请求体中伪造的计数会被忽略
+func TestForgedCounters(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			_, bob := s.newUser("bob")
+			liked := s.createPost(alice, "一个赞", "内容")
+			s.react(bob, fmt.Sprintf("/posts/%d", liked), "like")
+			forged := uint(s.expect(http.StatusOK, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "伪造", "Content": "内容", "like_count": 999, "reaction_count": 999,
+			})["post"].(map[string]any)["ID"].(float64))
+			if likes, reactions := s.counters(alice, forged); likes != 0 || reactions != 0 {
+				t.Fatalf("创建文章时不应接受计数: %v %v", likes, reactions)
+			}
+			s.edit(http.StatusOK, http.MethodPut, fmt.Sprintf("/posts/%d", forged), alice, forged, map[string]any{
+				"title": "伪造", "content": "内容", "like_count": 999,
+			})
+			if likes, _ := s.counters(alice, forged); likes != 0 {
+				t.Fatalf("修改文章时不应接受计数: %v", likes)
+			}
+			if ids, _ := s.listIDs("/posts/most-liked", bob, "posts"); fmt.Sprint(ids) != fmt.Sprint([]uint{liked, forged}) {
+				t.Fatalf("伪造的计数不应影响排行榜: %v", ids)
+			}
+
+			comment := s.expect(http.StatusOK, http.MethodPost, "/comments", bob, map[string]any{
+				"post_id": liked, "Content": "评论", "like_count": 50, "reaction_count": 50,
+			})["comment"].(map[string]any)
+			comments := s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/posts/%d/comments", liked), bob, nil)["comments"].([]any)
+			if comment["like_count"].(float64) != 0 || comments[0].(map[string]any)["like_count"].(float64) != 0 {
+				t.Fatalf("创建评论时不应接受计数: %v", comments)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package routes
+
+import (
+	"gin-demo/res"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResponseEnvelope(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.newUser("alice")
+
+	cases := []struct {
+		name   string
+		rec    *httptest.ResponseRecorder
+		status int
+		code   res.Code
+	}{
+		{"成功", s.do(http.MethodGet, "/posts", alice, nil), http.StatusOK, res.CodeOK},
+		{"参数错误", s.do(http.MethodGet, "/posts/abc", alice, nil), http.StatusBadRequest, res.CodeInvalidParams},
+		{"未认证", s.do(http.MethodGet, "/feed", "", nil), http.StatusUnauthorized, res.CodeUnauthorized},
+		{"无权限", s.do(http.MethodGet, "/admin/users", alice, nil), http.StatusForbidden, res.CodeForbidden},
+		{"不存在", s.do(http.MethodGet, "/posts/999", alice, nil), http.StatusNotFound, res.CodeNotFound},
+		{"接口不存在", s.do(http.MethodGet, "/no-such-route", "", nil), http.StatusNotFound, res.CodeNotFound},
+		{"冲突", s.do(http.MethodPost, "/register", "", map[string]any{
+			"name": "alice", "email": "alice@example.com", "password": "password123",
+		}), http.StatusConflict, res.CodeConflict},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.rec.Code != tc.status {
+				t.Fatalf("期望状态码 %d，实际 %d，响应: %s", tc.status, tc.rec.Code, tc.rec.Body.String())
+			}
+			if resp := envelope(t, tc.rec); resp.Code != tc.code {
+				t.Fatalf("期望业务码 %d，实际 %d", tc.code, resp.Code)
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	s := newTestServer(t)
+
+	req := newRequest(http.MethodGet, "/posts", "")
+	req.Header.Set(res.RequestIDHeader, "client-req-42")
+	rec := s.serve(req)
+	if resp := envelope(t, rec); resp.RequestID != "client-req-42" {
+		t.Fatalf("应沿用客户端的请求 ID，实际 %q", resp.RequestID)
+	}
+
+	// 不合法的请求 ID 会被替换成新生成的
+	req = newRequest(http.MethodGet, "/posts", "")
+	req.Header.Set(res.RequestIDHeader, strings.Repeat("x", 100))
+	rec = s.serve(req)
+	if id := envelope(t, rec).RequestID; len(id) != 32 {
+		t.Fatalf("应生成新的请求 ID，实际 %q", id)
+	}
+}
+
+func TestValidationDetails(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := s.do(http.MethodPost, "/register", "", map[string]any{"name": "bob", "email": "not-an-email", "password": "short"})
+	resp := envelope(t, rec)
+	if resp.Code != res.CodeInvalidParams {
+		t.Fatalf("期望业务码 %d，实际 %d", res.CodeInvalidParams, resp.Code)
+	}
+	rules := map[string]string{}
+	for _, d := range resp.Details {
+		rules[d.Field] = d.Rule
+	}
+	if rules["email"] != "email" || rules["password"] != "min" || len(rules) != 2 {
+		t.Fatalf("字段错误不符合预期: %+v", resp.Details)
+	}
+	if resp.Msg != resp.Details[0].Message || !strings.Contains(resp.Msg, "email") {
+		t.Fatalf("默认应返回中文提示: %q", resp.Msg)
+	}
+
+	// 根据 Accept-Language 返回英文提示
+	req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(`{"name": "bob", "email": "bob@example.com", "password": "short"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	resp = envelope(t, s.serve(req))
+	if len(resp.Details) != 1 || resp.Details[0].Message != "password must be at least 8 characters in length" {
+		t.Fatalf("英文字段错误不符合预期: %+v", resp.Details)
+	}
+
+	// 查询参数使用 form 标签作为字段名
+	_, alice := s.newUser("alice")
+	resp = envelope(t, s.do(http.MethodGet, "/posts?size=1000", alice, nil))
+	if len(resp.Details) != 1 || resp.Details[0].Field != "size" || resp.Details[0].Rule != "max" {
+		t.Fatalf("查询参数错误不符合预期: %+v", resp.Details)
+	}
+
+	// JSON 格式错误没有字段级错误
+	rec = s.do(http.MethodPost, "/register", "", "not an object")
+	if resp := envelope(t, rec); resp.Code != res.CodeInvalidParams || len(resp.Details) != 0 {
+		t.Fatalf("格式错误的响应不符合预期: %s", rec.Body.String())
+	}
+}
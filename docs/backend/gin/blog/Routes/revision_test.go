@@ -0,0 +1,99 @@
+package routes
+
+import (
+	"blog/models"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// revisionNumbers 返回修订列表中的修订号
+func (s *testServer) revisionNumbers(token string, postID uint) []int {
+	s.t.Helper()
+	data := s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/posts/%d/revisions", postID), token, nil)
+	var numbers []int
+	for _, r := range data["revisions"].([]any) {
+		numbers = append(numbers, int(r.(map[string]any)["number"].(float64)))
+	}
+	return numbers
+}
+
+func TestRevisions(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			_, bob := s.newUser("bob")
+			postID := s.createPost(alice, "标题", "第一行\n第二行\n第三行")
+			path := fmt.Sprintf("/posts/%d", postID)
+
+			// 每次修改标题或内容都会追加修订，只修改状态不会
+			s.edit(http.StatusOK, http.MethodPut, path, alice, postID, map[string]any{"title": "标题", "content": "第一行\n第二行（已修改）\n第三行"})
+			s.edit(http.StatusOK, http.MethodPatch, path+"/status", alice, postID, map[string]any{"status": "archived"})
+			if got := s.revisionNumbers(alice, postID); fmt.Sprint(got) != "[2 1]" {
+				t.Fatalf("修订历史不符合预期: %v", got)
+			}
+
+			data := s.expect(http.StatusOK, http.MethodGet, path+"/revisions/diff?from=1", alice, nil)
+			if data["to"].(float64) != 2 {
+				t.Fatalf("不传 to 时应和最新的修订比较: %v", data)
+			}
+			if stats := data["stats"].(map[string]any); stats["added"].(float64) != 1 || stats["removed"].(float64) != 1 {
+				t.Fatalf("差异统计不符合预期: %v", stats)
+			}
+			if unified := data["unified"].(string); !strings.Contains(unified, "-第二行\n+第二行（已修改）\n") {
+				t.Fatalf("unified diff 不符合预期: %q", unified)
+			}
+			rev := s.expect(http.StatusOK, http.MethodGet, path+"/revisions/1", alice, nil)["revision"].(map[string]any)
+			if rev["content"] != "第一行\n第二行\n第三行" {
+				t.Fatalf("修订内容不符合预期: %v", rev)
+			}
+
+			// 恢复旧版本会追加一个内容相同的新修订
+			post := s.edit(http.StatusOK, http.MethodPost, path+"/revisions/1/restore", alice, postID, nil)["post"].(map[string]any)
+			if post["Content"] != "第一行\n第二行\n第三行" {
+				t.Fatalf("恢复后的内容不符合预期: %v", post)
+			}
+			if got := s.revisionNumbers(alice, postID); fmt.Sprint(got) != "[3 2 1]" {
+				t.Fatalf("恢复后的修订历史不符合预期: %v", got)
+			}
+			data = s.expect(http.StatusOK, http.MethodGet, path+"/revisions/diff?from=1&to=3", alice, nil)
+			if data["unified"] != "" {
+				t.Fatalf("恢复后的版本应和修订 1 相同: %v", data)
+			}
+
+			s.expect(http.StatusForbidden, http.MethodGet, path+"/revisions", bob, nil)
+			s.expect(http.StatusForbidden, http.MethodPost, path+"/revisions/1/restore", bob, nil)
+			s.expect(http.StatusNotFound, http.MethodGet, path+"/revisions/9", alice, nil)
+			s.expect(http.StatusBadRequest, http.MethodGet, path+"/revisions/x", alice, nil)
+			s.expect(http.StatusBadRequest, http.MethodGet, path+"/revisions/diff", alice, nil)
+
+			// 两个修订的差异太大时拒绝比较，而不是占满 CPU 和内存
+			s.edit(http.StatusOK, http.MethodPut, path, alice, postID, map[string]any{"title": "标题", "content": strings.Repeat("a\n", 30000)})
+			s.edit(http.StatusOK, http.MethodPut, path, alice, postID, map[string]any{"title": "标题", "content": strings.Repeat("b\n", 30000)})
+			s.expect(http.StatusUnprocessableEntity, http.MethodGet, path+"/revisions/diff?from=4&to=5", alice, nil)
+		})
+	}
+}
+
+// 修订功能上线前创建的文章没有修订，第一次修改时先补记修改前的版本
+func TestRevisionBaseline(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.newUser("alice")
+	postID := s.createPost(alice, "旧文章", "旧内容")
+	if err := s.db.Where("post_id = ?", postID).Delete(&models.PostRevision{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	s.edit(http.StatusOK, http.MethodPut, fmt.Sprintf("/posts/%d", postID), alice, postID, map[string]any{"title": "旧文章", "content": "新内容"})
+	if got := s.revisionNumbers(alice, postID); fmt.Sprint(got) != "[2 1]" {
+		t.Fatalf("修订历史不符合预期: %v", got)
+	}
+	rev := s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/posts/%d/revisions/1", postID), alice, nil)["revision"].(map[string]any)
+	if rev["content"] != "旧内容" {
+		t.Fatalf("应补记修改前的版本: %v", rev)
+	}
+}
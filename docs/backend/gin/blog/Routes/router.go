@@ -1,33 +1,163 @@
 package routes
 
 import (
+	"blog/app"
+	"blog/config"
 	"blog/controllers"
+	"blog/feed"
 	"blog/middle"
+	"blog/models"
+	"blog/site"
+	"gin-demo/res"
+	"net/http"
+	"slices"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
-func SetupRouter() *gin.Engine {
-	r := gin.Default()
+// policy 是路由的认证要求
+type policy int
 
-	r.POST("/register", controllers.Register)
-	r.POST("/login", controllers.Login)
+const (
+	public   policy = iota // 不需要认证，也不读取 token，例如注册、登录和公开的 HTML 页面
+	optional               // 匿名用户可以访问，带上有效的 token 时按当前用户过滤可见范围，例如阅读文章
+	required               // 必须带上有效的 token，例如发表文章、时间线、通知
+)
+
+// route 声明一条路由：路径、认证要求和处理函数，handlers 中可以在处理函数前加上权限检查等中间件
+type route struct {
+	method   string
+	path     string
+	policy   policy
+	handlers []gin.HandlerFunc
+}
+
+func chain(handlers ...gin.HandlerFunc) []gin.HandlerFunc {
+	return handlers
+}
+
+// routeTable 返回全部 API 路由。每条路由在这里声明自己的认证要求，
+// 新增路由时不需要判断应该放进哪个路由组，读接口默认应该是 optional
+func routeTable(h *controllers.Handler) []route {
+	createPost := middle.RequirePermission(models.PermCreatePost)
+	manageUsers := middle.RequirePermission(models.PermManageUsers)
+	return []route{
+		{http.MethodPost, "/register", public, chain(h.Register)},
+		{http.MethodPost, "/login", public, chain(h.Login)},
+		{http.MethodPost, "/token/refresh", public, chain(h.RefreshToken)},
+		{http.MethodPost, "/logout", required, chain(h.Logout)},
+
+		{http.MethodPost, "/posts", required, chain(createPost, h.CreatePost)},
+		{http.MethodGet, "/posts", optional, chain(h.GetAllPosts)},
+		{http.MethodGet, "/posts/most-liked", optional, chain(h.GetMostLikedPosts)},
+		{http.MethodGet, "/posts/:post_id", optional, chain(h.GetPostByID)},
+		{http.MethodGet, "/users/:user_id/posts", optional, chain(h.GetPostsByUser)},
+		{http.MethodPut, "/posts/:post_id", required, chain(h.UpdatePost)},
+		{http.MethodPatch, "/posts/:post_id", required, chain(h.PatchPost)},
+		{http.MethodDelete, "/posts/:post_id", required, chain(h.DeletePost)},
+		{http.MethodPatch, "/posts/:post_id/status", required, chain(h.SetPostStatus)},
+		// 修订历史只有文章作者可以查看
+		{http.MethodGet, "/posts/:post_id/revisions", required, chain(h.ListRevisions)},
+		{http.MethodGet, "/posts/:post_id/revisions/diff", required, chain(h.DiffRevisions)},
+		{http.MethodGet, "/posts/:post_id/revisions/:rev", required, chain(h.GetRevision)},
+		{http.MethodPost, "/posts/:post_id/revisions/:rev/restore", required, chain(h.RestoreRevision)},
+
+		{http.MethodPost, "/posts/:post_id/tags", required, chain(h.AttachTags)},
+		{http.MethodDelete, "/posts/:post_id/tags/:name", required, chain(h.DetachTag)},
+		{http.MethodGet, "/tags", optional, chain(h.ListTags)},
+		{http.MethodGet, "/tags/:name/posts", optional, chain(h.GetPostsByTag)},
+		{http.MethodPost, "/posts/:post_id/categories", required, chain(h.AttachCategories)},
+		{http.MethodDelete, "/posts/:post_id/categories/:name", required, chain(h.DetachCategory)},
+		{http.MethodGet, "/categories", optional, chain(h.ListCategories)},
+		{http.MethodGet, "/categories/:name/posts", optional, chain(h.GetPostsByCategory)},
+
+		{http.MethodPost, "/comments", required, chain(h.CreateComment)},
+		{http.MethodGet, "/posts/:post_id/comments", optional, chain(h.GetCommentsByPost)},
+		{http.MethodDelete, "/comments/:comment_id", required, chain(h.DeleteComment)},
+		{http.MethodPatch, "/comments/:comment_id/approve", required, chain(h.ApproveComment)},
+
+		{http.MethodGet, "/posts/:post_id/reactions", optional, chain(h.GetPostReactions)},
+		{http.MethodPut, "/posts/:post_id/reactions", required, chain(h.ReactToPost)},
+		{http.MethodDelete, "/posts/:post_id/reactions", required, chain(h.UnreactPost)},
+		{http.MethodGet, "/comments/:comment_id/reactions", optional, chain(h.GetCommentReactions)},
+		{http.MethodPut, "/comments/:comment_id/reactions", required, chain(h.ReactToComment)},
+		{http.MethodDelete, "/comments/:comment_id/reactions", required, chain(h.UnreactComment)},
+
+		{http.MethodPost, "/users/:user_id/follow", required, chain(h.FollowUser)},
+		{http.MethodDelete, "/users/:user_id/follow", required, chain(h.UnfollowUser)},
+		{http.MethodGet, "/users/:user_id/followers", optional, chain(h.ListFollowers)},
+		{http.MethodGet, "/users/:user_id/following", optional, chain(h.ListFollowing)},
+		{http.MethodGet, "/feed", required, chain(h.GetFeed)},
 
-	auth := r.Group("/")
-	// 需要认证的路由
-	auth.Use(middle.JWTAuthMiddleware())
-	{
-		auth.POST("/posts", controllers.CreatePost)
-		auth.GET("/posts", controllers.GetAllPosts)
-		auth.GET("/posts/:post_id", controllers.GetPostByID)
-		auth.GET("/users/:user_id/posts", controllers.GetPostsByUser)
-		auth.PUT("/posts/:post_id", controllers.UpdatePost)
-		auth.DELETE("/posts/:post_id", controllers.DeletePost)
+		{http.MethodGet, "/notifications", required, chain(h.ListNotifications)},
+		{http.MethodGet, "/notifications/stream", required, chain(h.StreamNotifications)},
+		{http.MethodPost, "/notifications/read", required, chain(h.MarkAllNotificationsRead)},
+		{http.MethodPatch, "/notifications/:notification_id/read", required, chain(h.MarkNotificationRead)},
 
-		auth.POST("/comments", controllers.CreateComment)
-		auth.GET("/posts/:post_id/comments", controllers.GetCommentsByPost)
-		auth.DELETE("/comments/:comment_id", controllers.DeleteComment)
+		{http.MethodGet, "/search", optional, chain(h.Search)},
 
+		{http.MethodPost, "/posts/:post_id/media", required, chain(h.UploadMedia)},
+		{http.MethodGet, "/posts/:post_id/media", optional, chain(h.ListPostMedia)},
+		{http.MethodDelete, "/media/:media_id", required, chain(h.DeleteMedia)},
+		// 文件地址中的 key 是随机生成的，知道地址就可以访问，方便在文章和订阅源中直接引用
+		{http.MethodGet, "/media/:key", public, chain(h.ServeMedia)},
+
+		// 订阅源对所有人相同，和公开页面一样不读取 token
+		{http.MethodGet, "/feed.xml", public, chain(h.PostFeed(feed.RSS))},
+		{http.MethodGet, "/atom.xml", public, chain(h.PostFeed(feed.Atom))},
+		{http.MethodGet, "/feed.json", public, chain(h.PostFeed(feed.JSON))},
+		{http.MethodGet, "/users/:user_id/feed.xml", public, chain(h.PostFeed(feed.RSS))},
+		{http.MethodGet, "/users/:user_id/atom.xml", public, chain(h.PostFeed(feed.Atom))},
+		{http.MethodGet, "/users/:user_id/feed.json", public, chain(h.PostFeed(feed.JSON))},
+		{http.MethodGet, "/tags/:name/feed.xml", public, chain(h.PostFeed(feed.RSS))},
+		{http.MethodGet, "/tags/:name/atom.xml", public, chain(h.PostFeed(feed.Atom))},
+		{http.MethodGet, "/tags/:name/feed.json", public, chain(h.PostFeed(feed.JSON))},
+
+		// 管理员接口
+		{http.MethodGet, "/admin/users", required, chain(manageUsers, h.ListUsers)},
+		{http.MethodPatch, "/admin/users/:user_id/role", required, chain(manageUsers, h.SetUserRole)},
+		{http.MethodDelete, "/admin/users/:user_id", required, chain(manageUsers, h.DeleteUser)},
+	}
+}
+
+// SetupRouter 根据依赖容器注册所有路由
+func SetupRouter(deps *app.Container) *gin.Engine {
+	gin.SetMode(deps.Config.Server.Mode)
+	r := gin.New()
+	// 请求 ID 放在最前面，后面的中间件和处理函数返回的错误响应都能带上它；
+	// panic 时也返回统一格式的 500 响应
+	r.Use(res.RequestID(), gin.Logger(), gin.CustomRecovery(func(c *gin.Context, err any) {
+		config.Log.WithFields(logrus.Fields{
+			"request_id": c.GetString(res.RequestIDKey),
+			"error":      err,
+		}).Error("请求处理时发生 panic")
+		res.FailCode(c, res.CodeServerError, "")
+	}))
+	r.NoRoute(func(c *gin.Context) { res.FailCode(c, res.CodeNotFound, "接口不存在") })
+	// 只信任配置中声明的反向代理，避免 ClientIP 被伪造
+	if err := r.SetTrustedProxies(deps.Config.Server.TrustedProxies); err != nil {
+		config.Log.WithError(err).Warn("受信任代理配置无效，已忽略")
+	}
+
+	h := controllers.NewHandler(deps)
+
+	// 公开的 HTML 页面，不需要认证。静态文件内容只随版本发布变化，可以长时间缓存
+	r.HTMLRender = site.Pages
+	static := r.Group("/static", func(c *gin.Context) { c.Header("Cache-Control", "public, max-age=86400") })
+	static.StaticFS("/", site.Static())
+	r.GET("/", h.SiteHome)
+	r.GET("/p/:post_id", h.SitePost)
+	r.GET("/u/:user_id", h.SiteAuthor)
+
+	// 按路由声明的认证要求，在处理函数前加上对应的认证中间件
+	auth := map[policy][]gin.HandlerFunc{
+		public:   nil,
+		optional: {middle.OptionalAuth(deps.Sessions)},
+		required: {middle.JWTAuthMiddleware(deps.Sessions)},
+	}
+	for _, rt := range routeTable(h) {
+		r.Handle(rt.method, rt.path, slices.Concat(auth[rt.policy], rt.handlers)...)
 	}
 	return r
 
@@ -0,0 +1,78 @@
+package routes
+
+import (
+	"blog/controllers"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestRoutePolicies 不带 token 访问路由表中的每一条路由，检查认证要求和声明一致
+func TestRoutePolicies(t *testing.T) {
+	s := newTestServer(t)
+	// 路径参数换成任意合法的值，只关心是否被认证中间件拦下
+	params := strings.NewReplacer(":post_id", "1", ":user_id", "1", ":comment_id", "1",
+		":notification_id", "1", ":rev", "1", ":name", "go", ":media_id", "1", ":key", "missing.png")
+	for _, rt := range routeTable(controllers.NewHandler(s.deps)) {
+		rec := s.do(rt.method, params.Replace(rt.path), "", nil)
+		if unauthorized := rec.Code == http.StatusUnauthorized; unauthorized != (rt.policy == required) {
+			t.Errorf("%s %s: 认证要求为 %d，匿名访问返回 %d", rt.method, rt.path, rt.policy, rec.Code)
+		}
+	}
+}
+
+func TestAnonymousRead(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			aliceID, alice := s.newUser("alice")
+			_, bob := s.newUser("bob")
+			resp := s.expect(http.StatusOK, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "需要审核", "Content": "内容", "comment_mode": "review",
+			})
+			postID := uint(resp["post"].(map[string]any)["ID"].(float64))
+			resp = s.expect(http.StatusOK, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "草稿", "Content": "内容", "status": "draft",
+			})
+			draftID := uint(resp["post"].(map[string]any)["ID"].(float64))
+			approved := s.createComment(alice, postID, "作者的评论")
+			s.createComment(bob, postID, "待审核的评论")
+			s.react(bob, fmt.Sprintf("/posts/%d", postID), "like")
+
+			// 匿名用户只能看到已发布的文章和公开的评论
+			if ids, _ := s.listIDs("/posts", "", "posts"); fmt.Sprint(ids) != fmt.Sprint([]uint{postID}) {
+				t.Fatalf("匿名用户的文章列表不符合预期: %v", ids)
+			}
+			post := s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/posts/%d", postID), "", nil)["post"].(map[string]any)
+			if comments := post["Comments"].([]any); len(comments) != 1 {
+				t.Fatalf("匿名用户不应该看到待审核的评论: %v", comments)
+			}
+			if ids, _ := s.listIDs(fmt.Sprintf("/posts/%d/comments", postID), "", "comments"); fmt.Sprint(ids) != fmt.Sprint([]uint{approved}) {
+				t.Fatalf("匿名用户的评论列表不符合预期: %v", ids)
+			}
+			s.expect(http.StatusNotFound, http.MethodGet, fmt.Sprintf("/posts/%d", draftID), "", nil)
+			summary := s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/posts/%d/reactions", postID), "", nil)["reactions"].(map[string]any)
+			if summary["mine"] != "" {
+				t.Fatalf("匿名用户没有自己的反应: %v", summary)
+			}
+			s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/users/%d/followers", aliceID), "", nil)
+			s.expect(http.StatusOK, http.MethodGet, "/tags", "", nil)
+
+			// 同一个接口带上 token 时按当前用户过滤
+			if ids, _ := s.listIDs(fmt.Sprintf("/users/%d/posts", aliceID), alice, "posts"); len(ids) != 2 {
+				t.Fatalf("作者应该看到自己的草稿: %v", ids)
+			}
+			if ids, _ := s.listIDs(fmt.Sprintf("/users/%d/posts", aliceID), "", "posts"); len(ids) != 1 {
+				t.Fatalf("匿名用户不应该看到草稿: %v", ids)
+			}
+
+			// 写操作仍然需要登录
+			s.expect(http.StatusUnauthorized, http.MethodPost, "/comments", "", map[string]any{"post_id": postID, "Content": "匿名评论"})
+			s.expect(http.StatusUnauthorized, http.MethodPut, fmt.Sprintf("/posts/%d/reactions", postID), "", map[string]any{"kind": "like"})
+		})
+	}
+}
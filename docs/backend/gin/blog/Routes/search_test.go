@@ -0,0 +1,126 @@
+package routes
+
+import (
+	"blog/search"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// searchHits 搜索并返回结果列表
+func (s *testServer) searchHits(token, query string) []map[string]any {
+	s.t.Helper()
+	data := s.expect(http.StatusOK, http.MethodGet, "/search?"+query, token, nil)
+	var hits []map[string]any
+	for _, h := range data["results"].([]any) {
+		hits = append(hits, h.(map[string]any))
+	}
+	return hits
+}
+
+func TestSearch(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			_, bob := s.newUser("bob")
+
+			gormPost := s.createPost(alice, "GORM 入门教程", "介绍如何用 GORM 连接 MySQL 数据库")
+			ginPost := s.createPost(alice, "Gin 中间件", "编写 JWT 中间件，顺便提一下 GORM")
+			commentID := s.createComment(bob, ginPost, "请问 GORM 的事务怎么写？")
+
+			q := func(v string) string { return "q=" + url.QueryEscape(v) }
+			hits := s.searchHits(bob, q("gorm"))
+			if len(hits) != 3 {
+				t.Fatalf("期望 3 条结果，实际 %d: %v", len(hits), hits)
+			}
+			// 标题命中的文章相关度最高
+			if hits[0]["type"] != "post" || uint(hits[0]["id"].(float64)) != gormPost {
+				t.Fatalf("第一条结果应为标题命中的文章: %v", hits[0])
+			}
+			if snippet := hits[0]["snippet"].(string); !strings.Contains(snippet, "<mark>GORM</mark>") {
+				t.Fatalf("摘要应高亮关键词: %q", snippet)
+			}
+
+			// 中文关键词、多个关键词同时命中、按类型过滤
+			if hits := s.searchHits(bob, q("数据库")); len(hits) != 1 || uint(hits[0]["id"].(float64)) != gormPost {
+				t.Fatalf("中文搜索结果不符合预期: %v", hits)
+			}
+			if hits := s.searchHits(bob, q("gorm 事务")); len(hits) != 1 || hits[0]["type"] != "comment" ||
+				uint(hits[0]["id"].(float64)) != commentID || uint(hits[0]["post_id"].(float64)) != ginPost {
+				t.Fatalf("评论搜索结果不符合预期: %v", hits)
+			}
+			if hits := s.searchHits(bob, q("gorm")+"&type=comment"); len(hits) != 1 {
+				t.Fatalf("按类型过滤的结果不符合预期: %v", hits)
+			}
+			data := s.expect(http.StatusOK, http.MethodGet, "/search?"+q("gorm")+"&size=2&page=2", bob, nil)
+			if len(data["results"].([]any)) != 1 || data["pagination"].(map[string]any)["total"].(float64) != 3 {
+				t.Fatalf("分页结果不符合预期: %v", data)
+			}
+
+			// 修改和删除后索引同步更新
+			s.edit(http.StatusOK, http.MethodPut, fmt.Sprintf("/posts/%d", ginPost), alice, ginPost, map[string]any{
+				"title": "Gin 中间件", "content": "编写 JWT 中间件",
+			})
+			s.expect(http.StatusOK, http.MethodDelete, fmt.Sprintf("/comments/%d", commentID), bob, nil)
+			if hits := s.searchHits(bob, q("gorm")); len(hits) != 1 {
+				t.Fatalf("修改和删除后应只剩 1 条结果: %v", hits)
+			}
+			s.expect(http.StatusOK, http.MethodDelete, fmt.Sprintf("/posts/%d", gormPost), alice, nil)
+			if hits := s.searchHits(bob, q("gorm")); len(hits) != 0 {
+				t.Fatalf("删除文章后不应再有结果: %v", hits)
+			}
+
+			// 待审核的评论审核通过后才能被搜到
+			reviewed := s.expect(http.StatusOK, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "需要审核", "Content": "内容", "comment_mode": "review",
+			})["post"].(map[string]any)
+			pending := s.expect(http.StatusOK, http.MethodPost, "/comments", bob, map[string]any{
+				"post_id": reviewed["ID"], "Content": "独一无二的评论",
+			})["comment"].(map[string]any)
+			if hits := s.searchHits(bob, q("独一无二")); len(hits) != 0 {
+				t.Fatalf("待审核的评论不应被搜到: %v", hits)
+			}
+			s.expect(http.StatusOK, http.MethodPatch, fmt.Sprintf("/comments/%d/approve", uint(pending["ID"].(float64))), alice, nil)
+			if hits := s.searchHits(bob, q("独一无二")); len(hits) != 1 {
+				t.Fatalf("审核通过的评论应能被搜到: %v", hits)
+			}
+
+			s.expect(http.StatusBadRequest, http.MethodGet, "/search", bob, nil)
+			s.expect(http.StatusBadRequest, http.MethodGet, "/search?q=x&type=user", bob, nil)
+		})
+	}
+}
+
+func TestReindex(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.newUser("alice")
+	_, bob := s.newUser("bob")
+	for i := 0; i < 120; i++ {
+		s.createPost(alice, fmt.Sprintf("文章 %d", i), "重建索引")
+	}
+	postID := s.createPost(alice, "需要审核", "内容")
+	s.edit(http.StatusOK, http.MethodPut, fmt.Sprintf("/posts/%d", postID), alice, postID, map[string]any{
+		"title": "需要审核", "content": "内容", "comment_mode": "review",
+	})
+	s.createComment(bob, postID, "待审核的评论")
+
+	// 模拟服务重启：索引为空，从数据库重建
+	s.deps.Search = search.NewIndex()
+	if err := s.deps.Reindex(context.Background()); err != nil {
+		t.Fatalf("重建索引失败: %v", err)
+	}
+	data := s.expect(http.StatusOK, http.MethodGet, "/search?q="+url.QueryEscape("重建索引"), bob, nil)
+	if total := data["pagination"].(map[string]any)["total"].(float64); total != 120 {
+		t.Fatalf("重建后应有 120 篇文章，实际 %v", total)
+	}
+	if hits := s.searchHits(bob, "q="+url.QueryEscape("待审核")); len(hits) != 0 {
+		t.Fatalf("重建索引不应包含待审核的评论: %v", hits)
+	}
+}
@@ -0,0 +1,159 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// page 不带 token 获取公开页面，断言状态码并返回页面内容
+func (s *testServer) page(status int, path string) string {
+	s.t.Helper()
+	rec := s.do(http.MethodGet, path, "", nil)
+	if rec.Code != status {
+		s.t.Fatalf("GET %s: 期望状态码 %d，实际 %d，响应: %s", path, status, rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		s.t.Fatalf("GET %s: 期望返回 HTML，实际 %s", path, ct)
+	}
+	return rec.Body.String()
+}
+
+// assertContains 检查页面中包含 want 中的全部内容，并且不包含 unwanted 中的任何内容
+func assertContains(t *testing.T, html string, want, unwanted []string) {
+	t.Helper()
+	for _, w := range want {
+		if !strings.Contains(html, w) {
+			t.Errorf("页面中缺少 %q", w)
+		}
+	}
+	for _, u := range unwanted {
+		if strings.Contains(html, u) {
+			t.Errorf("页面中不应该出现 %q", u)
+		}
+	}
+	if t.Failed() {
+		t.Fatalf("页面内容:\n%s", html)
+	}
+}
+
+func TestSitePages(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			s.deps.Config.Site.PageSize = 2
+			aliceID, alice := s.newUser("alice")
+			bobID, bob := s.newUser("bob")
+			_, carol := s.newUser("carol")
+
+			// 最早创建的定时文章，发布前不出现在任何页面中
+			publishAt := time.Now().Add(48 * time.Hour)
+			s.expect(http.StatusOK, http.MethodPost, "/posts", bob, map[string]any{
+				"Title": "定时文章", "Content": "内容", "publish_at": publishAt,
+			})
+			s.createPost(alice, "第一篇", "内容")
+			s.createPost(bob, "bob 的文章", "内容")
+			resp := s.expect(http.StatusOK, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "Markdown 文章", "Content": "**粗体**\n\n<script>alert(1)</script>",
+				"format": "markdown", "comment_mode": "review",
+			})
+			postID := uint(resp["post"].(map[string]any)["ID"].(float64))
+			resp = s.expect(http.StatusOK, http.MethodPost, "/posts", alice, map[string]any{
+				"Title": "草稿", "Content": "内容", "status": "draft",
+			})
+			draftID := uint(resp["post"].(map[string]any)["ID"].(float64))
+
+			// 首页按发布时间倒序，草稿不出现，不需要登录
+			home := s.page(http.StatusOK, "/")
+			assertContains(t, home, []string{"Markdown 文章", "bob 的文章", `href="/?page=2"`, "第 1 / 2 页"},
+				[]string{"第一篇", "草稿", `rel="prev"`})
+			assertContains(t, s.page(http.StatusOK, "/?page=2"), []string{"第一篇", `href="/"`}, []string{"bob 的文章", `rel="next"`})
+			s.page(http.StatusNotFound, "/?page=3")
+			s.page(http.StatusBadRequest, "/?page=abc")
+
+			// 文章页：内容经过渲染和过滤，只展示公开的评论
+			commentID := s.createComment(alice, postID, "作者的评论 <b>不是标签</b>")
+			replyID := s.reply(bob, postID, commentID, "bob 的回复")
+			s.createComment(carol, postID, "待审核的评论")
+			post := s.page(http.StatusOK, fmt.Sprintf("/p/%d", postID))
+			assertContains(t, post,
+				[]string{"<strong>粗体</strong>", "作者的评论 &lt;b&gt;不是标签&lt;/b&gt;", fmt.Sprintf(`href="/u/%d"`, aliceID)},
+				[]string{"<script>alert", "bob 的回复", "待审核的评论"})
+			// 审核通过后回复嵌套在上级评论下
+			s.expect(http.StatusOK, http.MethodPatch, fmt.Sprintf("/comments/%d/approve", replyID), alice, nil)
+			assertContains(t, s.page(http.StatusOK, fmt.Sprintf("/p/%d", postID)), []string{`<ul class="replies">`, "bob 的回复"}, nil)
+
+			// 草稿即使作者本人访问也不展示
+			rec := s.do(http.MethodGet, fmt.Sprintf("/p/%d", draftID), alice, nil)
+			if rec.Code != http.StatusNotFound {
+				t.Fatalf("草稿的页面应该返回 404，实际 %d", rec.Code)
+			}
+			s.page(http.StatusNotFound, "/p/9999")
+			s.page(http.StatusNotFound, "/p/abc")
+
+			// 作者页只有该作者的文章
+			assertContains(t, s.page(http.StatusOK, fmt.Sprintf("/u/%d", bobID)), []string{"bob 的文章"}, []string{"第一篇", "Markdown 文章"})
+			assertContains(t, s.page(http.StatusOK, fmt.Sprintf("/u/%d", aliceID)), []string{"第一篇", "Markdown 文章"}, []string{"草稿", `rel="next"`})
+			s.page(http.StatusNotFound, fmt.Sprintf("/u/%d?page=2", aliceID))
+			s.page(http.StatusNotFound, "/u/9999")
+
+			// 定时文章发布后按发布时间排在首页最前面，显示的是发布日期而不是创建日期
+			if n, err := s.deps.PublishDuePosts(context.Background(), publishAt.Add(time.Minute)); err != nil || n != 1 {
+				t.Fatalf("定时文章应被发布: %d, %v", n, err)
+			}
+			assertContains(t, s.page(http.StatusOK, "/"), []string{"定时文章", "Markdown 文章", publishAt.Format(time.DateOnly)},
+				[]string{"bob 的文章"})
+		})
+	}
+}
+
+func TestSiteCaching(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			postID := s.createPost(alice, "标题", "内容")
+			path := fmt.Sprintf("/p/%d", postID)
+
+			rec := s.do(http.MethodGet, path, "", nil)
+			etag := rec.Header().Get("ETag")
+			if etag == "" || rec.Header().Get("Cache-Control") != "public, max-age=60" {
+				t.Fatalf("页面的缓存响应头不符合预期: %v", rec.Header())
+			}
+			// 登录与否看到的页面相同
+			if rec = s.do(http.MethodGet, path, alice, nil); rec.Header().Get("ETag") != etag {
+				t.Fatal("登录用户看到的页面应该和匿名访问者相同")
+			}
+			rec = s.doWith(http.MethodGet, path, "", nil, map[string]string{"If-None-Match": etag})
+			if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+				t.Fatalf("期望 304，实际 %d", rec.Code)
+			}
+			// 新的评论改变页面内容，旧的 ETag 不再命中
+			s.createComment(alice, postID, "新评论")
+			rec = s.doWith(http.MethodGet, path, "", nil, map[string]string{"If-None-Match": etag})
+			if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+				t.Fatalf("页面变化后应该返回新的内容，实际 %d", rec.Code)
+			}
+
+			// 错误页面不缓存
+			if rec = s.do(http.MethodGet, "/p/9999", "", nil); rec.Header().Get("Cache-Control") != "no-store" {
+				t.Fatalf("错误页面不应该被缓存: %v", rec.Header())
+			}
+			// 静态文件
+			rec = s.do(http.MethodGet, "/static/style.css", "", nil)
+			if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/css") ||
+				rec.Header().Get("Cache-Control") != "public, max-age=86400" {
+				t.Fatalf("静态文件的响应不符合预期: %d %v", rec.Code, rec.Header())
+			}
+		})
+	}
+}
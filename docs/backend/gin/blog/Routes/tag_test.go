@@ -0,0 +1,135 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// termNames 返回文章中的标签或分类名称
+func termNames(post map[string]any, key string) []string {
+	names := []string{}
+	for _, t := range post[key].([]any) {
+		names = append(names, t.(map[string]any)["name"].(string))
+	}
+	return names
+}
+
+// postIDs 返回文章列表中的文章 ID
+func postIDs(data map[string]any) []uint {
+	ids := []uint{}
+	for _, p := range data["posts"].([]any) {
+		ids = append(ids, uint(p.(map[string]any)["ID"].(float64)))
+	}
+	return ids
+}
+
+func TestTags(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			_, bob := s.newUser("bob")
+			ginPost := s.createPost(alice, "Gin 入门", "内容")
+			gormPost := s.createPost(alice, "GORM 入门", "内容")
+
+			// 名称统一为小写并去重，已经添加过的标签会被忽略
+			data := s.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/posts/%d/tags", ginPost), alice,
+				map[string]any{"names": []string{"Go", " gin ", "go"}})
+			if got := termNames(data["post"].(map[string]any), "tags"); fmt.Sprint(got) != "[go gin]" {
+				t.Fatalf("标签不符合预期: %v", got)
+			}
+			s.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/posts/%d/tags", gormPost), alice,
+				map[string]any{"names": []string{"go", "gorm"}})
+			s.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/posts/%d/tags", ginPost), alice,
+				map[string]any{"names": []string{"go"}})
+
+			// 只有文章作者可以修改标签，参数不合法时返回 400
+			s.expect(http.StatusForbidden, http.MethodPost, fmt.Sprintf("/posts/%d/tags", ginPost), bob,
+				map[string]any{"names": []string{"spam"}})
+			s.expect(http.StatusBadRequest, http.MethodPost, fmt.Sprintf("/posts/%d/tags", ginPost), alice,
+				map[string]any{"names": []string{}})
+			s.expect(http.StatusNotFound, http.MethodPost, "/posts/999/tags", alice,
+				map[string]any{"names": []string{"go"}})
+
+			data = s.expect(http.StatusOK, http.MethodGet, "/tags", bob, nil)
+			if got := fmt.Sprint(data["tags"]); got != "[map[count:2 name:go] map[count:1 name:gin] map[count:1 name:gorm]]" {
+				t.Fatalf("标签统计不符合预期: %s", got)
+			}
+			data = s.expect(http.StatusOK, http.MethodGet, "/tags/Go/posts", bob, nil)
+			if ids := postIDs(data); len(ids) != 2 || ids[0] != gormPost || ids[1] != ginPost {
+				t.Fatalf("按标签查询的文章不符合预期: %v", ids)
+			}
+			data = s.expect(http.StatusOK, http.MethodGet, "/posts?tag=gorm", bob, nil)
+			if ids := postIDs(data); len(ids) != 1 || ids[0] != gormPost {
+				t.Fatalf("按 tag 参数过滤的文章不符合预期: %v", ids)
+			}
+			data = s.expect(http.StatusOK, http.MethodGet, "/posts/most-liked?tag=gorm", bob, nil)
+			if ids := postIDs(data); len(ids) != 1 || ids[0] != gormPost {
+				t.Fatalf("按 tag 参数过滤的排行榜不符合预期: %v", ids)
+			}
+			// tag 和 category 只用于文章列表，其他列表忽略这两个参数
+			s.createComment(bob, gormPost, "评论")
+			data = s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/posts/%d/comments?tag=gin&category=go", gormPost), bob, nil)
+			if comments := data["comments"].([]any); len(comments) != 1 {
+				t.Fatalf("评论列表不应该按标签过滤: %v", data)
+			}
+			data = s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/posts/%d", gormPost), bob, nil)
+			if got := termNames(data["post"].(map[string]any), "tags"); fmt.Sprint(got) != "[go gorm]" {
+				t.Fatalf("文章详情中的标签不符合预期: %v", got)
+			}
+
+			// 移除标签后不再出现在标签下的文章列表中，不存在的标签返回 404
+			data = s.expect(http.StatusOK, http.MethodDelete, fmt.Sprintf("/posts/%d/tags/go", ginPost), alice, nil)
+			if got := termNames(data["post"].(map[string]any), "tags"); fmt.Sprint(got) != "[gin]" {
+				t.Fatalf("移除后的标签不符合预期: %v", got)
+			}
+			s.expect(http.StatusNotFound, http.MethodDelete, fmt.Sprintf("/posts/%d/tags/nope", ginPost), alice, nil)
+			s.expect(http.StatusForbidden, http.MethodDelete, fmt.Sprintf("/posts/%d/tags/gin", ginPost), bob, nil)
+			data = s.expect(http.StatusOK, http.MethodGet, "/tags/go/posts", bob, nil)
+			if ids := postIDs(data); len(ids) != 1 || ids[0] != gormPost {
+				t.Fatalf("移除后按标签查询的文章不符合预期: %v", ids)
+			}
+
+			// 删除文章后不再计入标签统计
+			s.expect(http.StatusOK, http.MethodDelete, fmt.Sprintf("/posts/%d", ginPost), alice, nil)
+			data = s.expect(http.StatusOK, http.MethodGet, "/tags", bob, nil)
+			if got := fmt.Sprint(data["tags"]); got != "[map[count:1 name:go] map[count:1 name:gorm]]" {
+				t.Fatalf("删除文章后的标签统计不符合预期: %s", got)
+			}
+		})
+	}
+}
+
+func TestCategories(t *testing.T) {
+	for name, newServer := range map[string]func(*testing.T) *testServer{
+		"sqlite": newTestServer,
+		"memory": newMemoryTestServer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newServer(t)
+			_, alice := s.newUser("alice")
+			postID := s.createPost(alice, "Gin 入门", "内容")
+			s.createPost(alice, "随笔", "内容")
+
+			// 分类名称保留大小写
+			data := s.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/posts/%d/categories", postID), alice,
+				map[string]any{"names": []string{"Backend", "Tutorial"}})
+			if got := termNames(data["post"].(map[string]any), "categories"); fmt.Sprint(got) != "[Backend Tutorial]" {
+				t.Fatalf("分类不符合预期: %v", got)
+			}
+			data = s.expect(http.StatusOK, http.MethodGet, "/categories/Backend/posts", alice, nil)
+			if ids := postIDs(data); len(ids) != 1 || ids[0] != postID {
+				t.Fatalf("按分类查询的文章不符合预期: %v", ids)
+			}
+			s.expect(http.StatusOK, http.MethodDelete, fmt.Sprintf("/posts/%d/categories/Tutorial", postID), alice, nil)
+			data = s.expect(http.StatusOK, http.MethodGet, "/categories", alice, nil)
+			if got := fmt.Sprint(data["categories"]); got != "[map[count:1 name:Backend]]" {
+				t.Fatalf("分类统计不符合预期: %s", got)
+			}
+		})
+	}
+}
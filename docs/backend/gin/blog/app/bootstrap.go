@@ -0,0 +1,40 @@
+package app
+
+import (
+	"blog/config"
+	"blog/models"
+	"blog/repository"
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PromoteAdmins 把 admin.emails 中已经注册的用户提升为管理员。
+// 角色保存在 token 中，所以同时吊销这些用户现有的会话，让他们重新登录拿到新角色
+func (c *Container) PromoteAdmins(ctx context.Context) error {
+	for _, email := range c.Config.Admin.Emails {
+		user, err := c.Users.FindByEmail(ctx, email)
+		if errors.Is(err, repository.ErrNotFound) {
+			// 还没有注册，注册时会自动成为管理员
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if user.Role == models.RoleAdmin {
+			continue
+		}
+		if err := c.Users.SetRole(ctx, user.ID, models.RoleAdmin); err != nil {
+			return err
+		}
+		if err := c.Sessions.RevokeAllByUser(ctx, user.ID); err != nil {
+			return err
+		}
+		config.Log.WithFields(logrus.Fields{
+			"user_id": user.ID,
+			"email":   user.Email,
+		}).Info("已将用户提升为管理员")
+	}
+	return nil
+}
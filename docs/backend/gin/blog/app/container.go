@@ -0,0 +1,94 @@
+// Package app 负责把配置和各个依赖组装在一起，供路由和控制器使用
+package app
+
+import (
+	"blog/config"
+	"blog/events"
+	"blog/render"
+	"blog/repository"
+	"blog/search"
+	"blog/storage"
+
+	"gorm.io/gorm"
+)
+
+// Container 是依赖容器：控制器通过它拿到仓储，而不是直接访问全局数据库连接
+type Container struct {
+	Config     *config.Config
+	Users      repository.UserRepository
+	Posts      repository.PostRepository
+	Comments   repository.CommentRepository
+	Sessions   repository.SessionRepository
+	Revisions  repository.RevisionRepository
+	Tags       repository.TaxonomyRepository
+	Categories repository.TaxonomyRepository
+	Reactions  repository.ReactionRepository
+	Follows    repository.FollowRepository
+	Search     search.Backend
+
+	Notifications repository.NotificationRepository
+	// Events 是领域事件总线，Hub 把新通知推送给在线用户，见 notifications.go
+	Events *events.Bus
+	Hub    *events.Hub
+	// Renderer 缓存文章渲染后的 HTML，见 render.go
+	Renderer *render.Cache
+
+	// Media 保存上传文件的元数据，文件内容保存在 Storage 中，见 storage.go
+	Media   repository.MediaRepository
+	Storage storage.Storage
+}
+
+// NewContainer 使用 GORM 实现组装依赖
+func NewContainer(cfg *config.Config, db *gorm.DB) *Container {
+	c := &Container{
+		Config:     cfg,
+		Users:      repository.NewGormUserRepository(db),
+		Posts:      repository.NewGormPostRepository(db),
+		Comments:   repository.NewGormCommentRepository(db),
+		Sessions:   repository.NewGormSessionRepository(db),
+		Revisions:  repository.NewGormRevisionRepository(db),
+		Tags:       repository.NewGormTaxonomyRepository(db, repository.TaxonomyTag),
+		Categories: repository.NewGormTaxonomyRepository(db, repository.TaxonomyCategory),
+		Reactions:  repository.NewGormReactionRepository(db),
+		Follows:    repository.NewGormFollowRepository(db),
+		Search:     search.NewIndex(),
+
+		Notifications: repository.NewGormNotificationRepository(db),
+		Events:        events.NewBus(),
+		Hub:           events.NewHub(),
+		Renderer:      render.NewCache(cfg.Render.CacheSize),
+
+		Media:   repository.NewGormMediaRepository(db),
+		Storage: newStorage(cfg.Media),
+	}
+	c.subscribeNotifications()
+	return c
+}
+
+// NewMemoryContainer 使用内存实现组装依赖，不需要数据库，适合处理函数的单元测试
+func NewMemoryContainer(cfg *config.Config) *Container {
+	mem := repository.NewMemory()
+	c := &Container{
+		Config:     cfg,
+		Users:      mem.Users(),
+		Posts:      mem.Posts(),
+		Comments:   mem.Comments(),
+		Sessions:   mem.Sessions(),
+		Revisions:  mem.Revisions(),
+		Tags:       mem.Tags(),
+		Categories: mem.Categories(),
+		Reactions:  mem.Reactions(),
+		Follows:    mem.Follows(),
+		Search:     search.NewIndex(),
+
+		Notifications: mem.Notifications(),
+		Events:        events.NewBus(),
+		Hub:           events.NewHub(),
+		Renderer:      render.NewCache(cfg.Render.CacheSize),
+
+		Media:   mem.Media(),
+		Storage: newStorage(cfg.Media),
+	}
+	c.subscribeNotifications()
+	return c
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"blog/config"
+	"blog/events"
+	"blog/models"
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// subscribeNotifications 订阅会产生通知的领域事件
+func (c *Container) subscribeNotifications() {
+	c.Events.Subscribe(events.CommentCreated{}, func(ctx context.Context, e events.Event) {
+		ev := e.(events.CommentCreated)
+		// 待审核的评论只通知文章作者去审核，回复的通知等审核通过后再发
+		replied := uint(0)
+		if ev.Comment.Status == models.CommentApproved {
+			replied = c.notifyReply(ctx, e, &ev.Comment, 0)
+		}
+		// 文章作者已经收到回复通知时不再重复通知
+		if ev.Post.UserID != replied {
+			c.notify(ctx, e, models.Notification{
+				UserID: ev.Post.UserID, ActorID: ev.Comment.UserID, Type: models.NotificationComment,
+				PostID: &ev.Post.ID, CommentID: &ev.Comment.ID,
+			})
+		}
+	})
+	c.Events.Subscribe(events.CommentApproved{}, func(ctx context.Context, e events.Event) {
+		ev := e.(events.CommentApproved)
+		// 文章作者在评论提交时已经收到过通知
+		c.notifyReply(ctx, e, &ev.Comment, ev.Post.UserID)
+	})
+	c.Events.Subscribe(events.UserFollowed{}, func(ctx context.Context, e events.Event) {
+		ev := e.(events.UserFollowed)
+		c.notify(ctx, e, models.Notification{UserID: ev.FolloweeID, ActorID: ev.FollowerID, Type: models.NotificationFollow})
+	})
+}
+
+// notifyReply 通知被回复的评论的作者（除了 skip），返回通知的用户 ID，没有发出通知时返回 0
+func (c *Container) notifyReply(ctx context.Context, e events.Event, comment *models.Comment, skip uint) uint {
+	if comment.ParentID == nil {
+		return 0
+	}
+	parent, err := c.Comments.FindByID(ctx, *comment.ParentID)
+	if err != nil || parent.Status == models.CommentDeleted || parent.UserID == skip {
+		return 0
+	}
+	n := models.Notification{
+		UserID: parent.UserID, ActorID: comment.UserID, Type: models.NotificationReply,
+		PostID: &comment.PostID, CommentID: &comment.ID,
+	}
+	if !c.notify(ctx, e, n) {
+		return 0
+	}
+	return parent.UserID
+}
+
+// notify 保存通知并推送给在线的接收者，返回是否发出了通知。
+// 用户自己触发的事件不通知自己；保存失败只记录日志，不影响触发通知的操作
+func (c *Container) notify(ctx context.Context, e events.Event, n models.Notification) bool {
+	if n.UserID == 0 || n.UserID == n.ActorID {
+		return false
+	}
+	if err := c.Notifications.Create(ctx, &n); err != nil {
+		config.Log.WithFields(logrus.Fields{
+			"event":   e.EventName(),
+			"user_id": n.UserID,
+			"type":    n.Type,
+			"error":   err.Error(),
+		}).Error("保存通知失败")
+		return false
+	}
+	// 推送的通知带上触发者，和通知列表的内容一致
+	if actor, err := c.Users.FindByID(ctx, n.ActorID); err == nil {
+		n.Actor = *actor
+	}
+	c.Hub.Publish(n)
+	return true
+}
@@ -0,0 +1,17 @@
+package app
+
+import (
+	"blog/models"
+	"blog/render"
+)
+
+// RenderPost 把文章内容按格式渲染成安全的 HTML。
+// 结果按文章 ID 和版本号缓存，文章修改后版本号变化，自然不会再命中旧的结果
+func (c *Container) RenderPost(post *models.Post) string {
+	return c.Renderer.Get(render.Key{ID: post.ID, Version: post.Version}, func() string {
+		if post.Format == models.FormatMarkdown {
+			return render.Markdown(post.Content)
+		}
+		return render.Plain(post.Content)
+	})
+}
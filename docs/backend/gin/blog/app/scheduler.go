@@ -0,0 +1,51 @@
+package app
+
+import (
+	"blog/config"
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PublishDuePosts 发布所有到达发布时间的定时文章，并把它们加入搜索索引，返回发布的文章数
+func (c *Container) PublishDuePosts(ctx context.Context, now time.Time) (int, error) {
+	posts, err := c.Posts.PublishDue(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+	for i := range posts {
+		post := &posts[i]
+		// 索引只是数据库的副本，更新失败不影响发布
+		if err := c.IndexPost(ctx, post); err != nil {
+			config.Log.WithFields(logrus.Fields{
+				"post_id": post.ID,
+				"error":   err.Error(),
+			}).Warn("更新搜索索引失败")
+		}
+		config.Log.WithFields(logrus.Fields{
+			"post_id":      post.ID,
+			"published_at": post.PublishedAt,
+		}).Info("定时文章已发布")
+	}
+	return len(posts), nil
+}
+
+// RunScheduler 每隔 interval 发布一次到期的定时文章，直到 ctx 被取消。
+// 启动时立即执行一次，补上服务停止期间错过的文章
+func (c *Container) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := c.PublishDuePosts(ctx, time.Now()); err != nil {
+			config.Log.WithFields(logrus.Fields{
+				"error": err.Error(),
+			}).Error("发布定时文章失败")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"blog/models"
+	"blog/repository"
+	"blog/search"
+	"context"
+)
+
+// PostDocument 把文章转换成搜索文档
+func PostDocument(post *models.Post) search.Document {
+	return search.Document{
+		Kind:      search.KindPost,
+		ID:        post.ID,
+		PostID:    post.ID,
+		Title:     post.Title,
+		Content:   post.Content,
+		CreatedAt: post.CreatedAt,
+	}
+}
+
+// CommentDocument 把评论转换成搜索文档
+func CommentDocument(comment *models.Comment) search.Document {
+	return search.Document{
+		Kind:      search.KindComment,
+		ID:        comment.ID,
+		PostID:    comment.PostID,
+		Content:   comment.Content,
+		CreatedAt: comment.CreatedAt,
+	}
+}
+
+// IndexPost 同步文章及其预加载的评论的索引：只有已发布的文章和其中已公开的评论能被搜到，
+// 其他状态的文章连同评论一起从索引中移除
+func (c *Container) IndexPost(ctx context.Context, post *models.Post) error {
+	if post.Status != models.PostPublished {
+		return c.Search.RemovePost(ctx, post.ID)
+	}
+	if err := c.Search.Index(ctx, PostDocument(post)); err != nil {
+		return err
+	}
+	for i := range post.Comments {
+		if comment := &post.Comments[i]; comment.Status == models.CommentApproved {
+			if err := c.Search.Index(ctx, CommentDocument(comment)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Reindex 从数据库重建搜索索引：所有已发布的文章和其中已公开的评论。
+// 进程内索引不持久化，服务启动时调用一次
+func (c *Container) Reindex(ctx context.Context) error {
+	// ListOptions 的可见范围为零值，只会列出已发布的文章
+	opts := repository.ListOptions{Size: repository.MaxPageSize}
+	for {
+		page, err := c.Posts.List(ctx, opts)
+		if err != nil {
+			return err
+		}
+		for i := range page.Items {
+			if err := c.IndexPost(ctx, &page.Items[i]); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		if opts.Cursor, err = repository.DecodeCursor(page.NextCursor); err != nil {
+			return err
+		}
+	}
+}
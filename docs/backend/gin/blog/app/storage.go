@@ -0,0 +1,31 @@
+package app
+
+import (
+	"blog/config"
+	"blog/storage"
+	"net/http"
+	"time"
+)
+
+const (
+	// s3ResponseTimeout 是等待对象存储返回响应头的时间，对象存储没有响应时请求不会一直挂起
+	s3ResponseTimeout = 30 * time.Second
+	// s3RequestTimeout 是一次请求的总时间，包括上传和读取文件内容，留出传输大文件的时间
+	s3RequestTimeout = 10 * time.Minute
+)
+
+// newStorage 按配置创建保存上传文件的存储后端，配置已经由 config.Validate 检查过
+func newStorage(cfg config.MediaConfig) storage.Storage {
+	if cfg.Storage == "s3" {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.ResponseHeaderTimeout = s3ResponseTimeout
+		return storage.NewS3(storage.S3Config{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+		}, &http.Client{Transport: transport, Timeout: s3RequestTimeout})
+	}
+	return storage.NewLocal(cfg.Dir)
+}
@@ -0,0 +1,442 @@
+package config
+
+import (
+	"blog/models"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// PlaceholderSecret 是示例配置中的占位密钥，使用它启动服务会被拒绝
+const PlaceholderSecret = "insert_your_own_secret_key"
+
+// envPrefix 是所有环境变量覆盖项的统一前缀，例如 BLOG_DB_HOST
+const envPrefix = "BLOG_"
+
+// Config 是博客服务的全部配置，启动时加载一次，再注入到各个模块
+type Config struct {
+	Server    ServerConfig    `yaml:"server" toml:"server"`
+	Database  DatabaseConfig  `yaml:"database" toml:"database" env:"DB"`
+	JWT       JWTConfig       `yaml:"jwt" toml:"jwt"`
+	Log       LogConfig       `yaml:"log" toml:"log"`
+	Admin     AdminConfig     `yaml:"admin" toml:"admin"`
+	Scheduler SchedulerConfig `yaml:"scheduler" toml:"scheduler"`
+	Comments  CommentConfig   `yaml:"comments" toml:"comments"`
+	Reactions ReactionConfig  `yaml:"reactions" toml:"reactions"`
+
+	Notifications NotificationConfig `yaml:"notifications" toml:"notifications"`
+	Render        RenderConfig       `yaml:"render" toml:"render"`
+	Site          SiteConfig         `yaml:"site" toml:"site"`
+	Media         MediaConfig        `yaml:"media" toml:"media"`
+}
+
+type ServerConfig struct {
+	Addr           string   `yaml:"addr" toml:"addr"`                       // 监听地址，例如 ":8080"
+	Mode           string   `yaml:"mode" toml:"mode"`                       // Gin 运行模式：debug / release / test
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"` // 受信任的反向代理，为空表示不信任任何代理
+}
+
+type DatabaseConfig struct {
+	Driver   string `yaml:"driver" toml:"driver"` // 数据库驱动：mysql / postgres / sqlite
+	Username string `yaml:"username" toml:"username"`
+	Password string `yaml:"password" toml:"password"`
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	DBName   string `yaml:"dbname" toml:"dbname"`
+	SSLMode  string `yaml:"sslmode" toml:"sslmode"` // 仅 postgres 使用
+	Path     string `yaml:"path" toml:"path"`       // 仅 sqlite 使用：数据库文件路径，":memory:" 表示内存数据库
+}
+
+type JWTConfig struct {
+	Secret        string   `yaml:"secret" toml:"secret"`                 // 用于签名和验证 JWT 的密钥
+	Issuer        string   `yaml:"issuer" toml:"issuer"`                 // 签发者
+	Expire        Duration `yaml:"expire" toml:"expire"`                 // access token 有效期，例如 "15m"
+	RefreshExpire Duration `yaml:"refresh_expire" toml:"refresh_expire"` // refresh token 有效期，每次刷新都会重新计算
+}
+
+type LogConfig struct {
+	Level      string `yaml:"level" toml:"level"`             // 日志级别：debug / info / warn / error
+	Filename   string `yaml:"filename" toml:"filename"`       // 日志文件路径
+	MaxSize    int    `yaml:"max_size" toml:"max_size"`       // 单个日志文件最大尺寸（MB）
+	MaxBackups int    `yaml:"max_backups" toml:"max_backups"` // 最多保留旧日志文件的个数
+	MaxAge     int    `yaml:"max_age" toml:"max_age"`         // 保留最近多少天的日志
+	Compress   bool   `yaml:"compress" toml:"compress"`       // 是否压缩旧日志
+}
+
+type AdminConfig struct {
+	// 使用这些邮箱注册的用户自动成为管理员，已注册的用户在服务启动时被提升为管理员。
+	// 用于在没有数据库访问权限的情况下创建第一个管理员
+	Emails []string `yaml:"emails" toml:"emails"`
+}
+
+// IsAdminEmail 判断邮箱是否在管理员列表中，不区分大小写
+func (a AdminConfig) IsAdminEmail(email string) bool {
+	for _, e := range a.Emails {
+		if strings.EqualFold(e, email) {
+			return true
+		}
+	}
+	return false
+}
+
+type SchedulerConfig struct {
+	Interval Duration `yaml:"interval" toml:"interval"` // 检查定时发布文章的间隔，例如 "1m"
+}
+
+type CommentConfig struct {
+	MaxDepth int `yaml:"max_depth" toml:"max_depth"` // 评论最多嵌套几层（顶层评论是第 1 层），1 表示不允许回复
+}
+
+type ReactionConfig struct {
+	// Emojis 是点赞（like）之外可以使用的表情反应，为空时只能点赞
+	Emojis []string `yaml:"emojis" toml:"emojis"`
+}
+
+type NotificationConfig struct {
+	// Heartbeat 是实时推送连接（SSE）发送心跳的间隔，防止代理因为连接空闲而断开，例如 "30s"
+	Heartbeat Duration `yaml:"heartbeat" toml:"heartbeat"`
+}
+
+type RenderConfig struct {
+	// CacheSize 是缓存的渲染结果（文章 HTML）的条数，0 表示不缓存
+	CacheSize int `yaml:"cache_size" toml:"cache_size"`
+}
+
+type SiteConfig struct {
+	Title       string   `yaml:"title" toml:"title"`             // 公开页面的站点标题
+	Description string   `yaml:"description" toml:"description"` // 站点简介，显示在标题下方
+	PageSize    int      `yaml:"page_size" toml:"page_size"`     // 首页和作者页每页的文章数
+	MaxAge      Duration `yaml:"max_age" toml:"max_age"`         // 浏览器和共享缓存可以缓存页面多久，例如 "1m"，0 表示每次都要校验
+
+	// URL 是站点对外的地址，例如 "https://blog.example.com"，用于生成订阅源（RSS/Atom/JSON Feed）中的绝对链接；
+	// 为空时根据请求的 Host 推断，部署在反向代理之后时应该明确配置
+	URL      string `yaml:"url" toml:"url"`
+	FeedSize int    `yaml:"feed_size" toml:"feed_size"` // 订阅源中最多包含最近的多少篇文章
+}
+
+type MediaConfig struct {
+	Storage string `yaml:"storage" toml:"storage"`   // 上传文件的存储后端：local / s3
+	Dir     string `yaml:"dir" toml:"dir"`           // 仅 local 使用：保存文件的目录
+	MaxSize int    `yaml:"max_size" toml:"max_size"` // 单个文件最大尺寸（MB）
+	// AllowedTypes 是允许上传的文件类型，类型根据文件内容检测，不信任客户端声明的 Content-Type
+	AllowedTypes []string `yaml:"allowed_types" toml:"allowed_types"`
+	S3           S3Config `yaml:"s3" toml:"s3"` // 仅 s3 使用
+}
+
+// S3Config 是兼容 S3 协议的对象存储（AWS S3、MinIO、Cloudflare R2 等）的连接信息
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint" toml:"endpoint"` // 服务地址，例如 "https://s3.us-east-1.amazonaws.com"
+	Region    string `yaml:"region" toml:"region"`
+	Bucket    string `yaml:"bucket" toml:"bucket"`
+	AccessKey string `yaml:"access_key" toml:"access_key"`
+	SecretKey string `yaml:"secret_key" toml:"secret_key"`
+}
+
+// Allowed 判断 kind 是否是可以使用的反应
+func (r ReactionConfig) Allowed(kind string) bool {
+	return kind == models.ReactionLike || slices.Contains(r.Emojis, kind)
+}
+
+// Duration 让配置文件和环境变量可以直接写 "24h"、"15m" 这样的时间长度
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Default 返回默认配置，配置文件和环境变量只需要覆盖需要修改的项
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr: ":8080",
+			Mode: "debug",
+		},
+		Database: DatabaseConfig{
+			Driver:   "mysql",
+			Username: "testuser",
+			Host:     "127.0.0.1",
+			Port:     3306,
+			DBName:   "ginTestDB",
+			SSLMode:  "disable",
+			Path:     "blog.db",
+		},
+		JWT: JWTConfig{
+			Secret:        PlaceholderSecret,
+			Issuer:        "blog",
+			Expire:        Duration(15 * time.Minute),
+			RefreshExpire: Duration(7 * 24 * time.Hour),
+		},
+		Scheduler: SchedulerConfig{
+			Interval: Duration(time.Minute),
+		},
+		Comments: CommentConfig{
+			MaxDepth: 5,
+		},
+		Reactions: ReactionConfig{
+			Emojis: []string{"❤️", "😄", "🎉", "😮", "😢"},
+		},
+		Notifications: NotificationConfig{
+			Heartbeat: Duration(30 * time.Second),
+		},
+		Render: RenderConfig{
+			CacheSize: 1000,
+		},
+		Site: SiteConfig{
+			Title:    "Gin 博客",
+			PageSize: 10,
+			MaxAge:   Duration(time.Minute),
+			FeedSize: 20,
+		},
+		Media: MediaConfig{
+			Storage:      "local",
+			Dir:          "uploads",
+			MaxSize:      10,
+			AllowedTypes: []string{"image/jpeg", "image/png", "image/gif", "image/webp", "application/pdf"},
+			S3: S3Config{
+				Region: "us-east-1",
+			},
+		},
+		Log: LogConfig{
+			Level:      "info",
+			Filename:   "logs/app.log",
+			MaxSize:    10,
+			MaxBackups: 5,
+			MaxAge:     30,
+			Compress:   true,
+		},
+	}
+}
+
+// Load 按 默认值 -> 配置文件 -> 环境变量 的顺序加载配置
+// path 为空时跳过配置文件，文件格式根据扩展名（.yaml/.yml/.toml）判断
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		if err := loadFile(path, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyEnv(reflect.ValueOf(cfg).Elem(), envPrefix); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".toml":
+		err = toml.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("不支持的配置文件格式: %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("解析配置文件失败: %w", err)
+	}
+	return nil
+}
+
+// applyEnv 递归遍历配置结构体，用 BLOG_<SECTION>_<FIELD> 形式的环境变量覆盖字段
+// 环境变量名默认取自 yaml 标签的大写形式，结构体可以用 env 标签指定更短的段名
+func applyEnv(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("env")
+		if name == "" {
+			name = strings.ToUpper(field.Tag.Get("yaml"))
+		}
+		key := prefix + name
+		fv := v.Field(i)
+
+		if fv.Kind() == reflect.Struct {
+			if err := applyEnv(fv, key+"_"); err != nil {
+				return err
+			}
+			continue
+		}
+		raw, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		if err := setField(fv, raw); err != nil {
+			return fmt.Errorf("环境变量 %s 格式错误: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func setField(fv reflect.Value, raw string) error {
+	if u, ok := fv.Addr().Interface().(interface{ UnmarshalText([]byte) error }); ok {
+		return u.UnmarshalText([]byte(raw))
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, s := range strings.Split(raw, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				items = append(items, s)
+			}
+		}
+		fv.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("不支持的字段类型 %s", fv.Kind())
+	}
+	return nil
+}
+
+// Validate 检查配置是否可以用于启动服务，任何一项不合法都会拒绝启动
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("server.addr 不能为空"))
+	}
+	switch c.Server.Mode {
+	case "debug", "release", "test":
+	default:
+		errs = append(errs, fmt.Errorf("server.mode 只能是 debug/release/test，当前为 %q", c.Server.Mode))
+	}
+	errs = append(errs, c.Database.validate()...)
+	switch {
+	case c.JWT.Secret == "" || c.JWT.Secret == PlaceholderSecret:
+		errs = append(errs, errors.New("jwt.secret 未设置，请通过配置文件或 BLOG_JWT_SECRET 提供自己的密钥"))
+	case len(c.JWT.Secret) < 16:
+		errs = append(errs, errors.New("jwt.secret 长度至少为 16 个字符"))
+	}
+	if c.JWT.Expire <= 0 || c.JWT.RefreshExpire <= 0 {
+		errs = append(errs, errors.New("jwt.expire 和 jwt.refresh_expire 必须大于 0"))
+	} else if c.JWT.RefreshExpire < c.JWT.Expire {
+		errs = append(errs, errors.New("jwt.refresh_expire 不能小于 jwt.expire"))
+	}
+	if c.Scheduler.Interval <= 0 {
+		errs = append(errs, errors.New("scheduler.interval 必须大于 0"))
+	}
+	if c.Comments.MaxDepth < 1 {
+		errs = append(errs, errors.New("comments.max_depth 至少为 1"))
+	}
+	for _, e := range c.Reactions.Emojis {
+		if n := utf8.RuneCountInString(e); n == 0 || n > 20 {
+			errs = append(errs, fmt.Errorf("reactions.emojis 中的 %q 长度应为 1 到 20 个字符", e))
+		}
+	}
+	if c.Notifications.Heartbeat <= 0 {
+		errs = append(errs, errors.New("notifications.heartbeat 必须大于 0"))
+	}
+	if c.Render.CacheSize < 0 {
+		errs = append(errs, errors.New("render.cache_size 不能小于 0"))
+	}
+	if c.Site.Title == "" {
+		errs = append(errs, errors.New("site.title 不能为空"))
+	}
+	if c.Site.PageSize < 1 || c.Site.PageSize > 100 {
+		errs = append(errs, fmt.Errorf("site.page_size 应为 1 到 100，当前为 %d", c.Site.PageSize))
+	}
+	if c.Site.MaxAge < 0 {
+		errs = append(errs, errors.New("site.max_age 不能小于 0"))
+	}
+	if c.Site.URL != "" {
+		if u, err := url.Parse(c.Site.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("site.url 应为 http:// 或 https:// 开头的地址，当前为 %q", c.Site.URL))
+		}
+	}
+	if c.Site.FeedSize < 1 || c.Site.FeedSize > 100 {
+		errs = append(errs, fmt.Errorf("site.feed_size 应为 1 到 100，当前为 %d", c.Site.FeedSize))
+	}
+	errs = append(errs, c.Media.validate()...)
+	if _, err := logrus.ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log.level 不合法: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+func (m MediaConfig) validate() []error {
+	var errs []error
+	switch m.Storage {
+	case "local":
+		if m.Dir == "" {
+			errs = append(errs, errors.New("media.dir 不能为空"))
+		}
+	case "s3":
+		if u, err := url.Parse(m.S3.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("media.s3.endpoint 应为 http:// 或 https:// 开头的地址，当前为 %q", m.S3.Endpoint))
+		}
+		if m.S3.Region == "" || m.S3.Bucket == "" || m.S3.AccessKey == "" || m.S3.SecretKey == "" {
+			errs = append(errs, errors.New("media.s3 的 region、bucket、access_key 和 secret_key 都不能为空"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("media.storage 只能是 local/s3，当前为 %q", m.Storage))
+	}
+	if m.MaxSize < 1 {
+		errs = append(errs, errors.New("media.max_size 至少为 1（MB）"))
+	}
+	if len(m.AllowedTypes) == 0 {
+		errs = append(errs, errors.New("media.allowed_types 不能为空"))
+	}
+	for _, t := range m.AllowedTypes {
+		if _, _, ok := strings.Cut(t, "/"); !ok {
+			errs = append(errs, fmt.Errorf("media.allowed_types 中的 %q 不是合法的文件类型", t))
+		}
+	}
+	return errs
+}
+
+func (d DatabaseConfig) validate() []error {
+	if _, err := LookupDriver(d.Driver); err != nil {
+		return []error{err}
+	}
+	// 嵌入式数据库只需要文件路径
+	if d.Driver == "sqlite" {
+		if d.Path == "" {
+			return []error{errors.New("database.path 不能为空")}
+		}
+		return nil
+	}
+	var errs []error
+	if d.Host == "" || d.Username == "" || d.DBName == "" {
+		errs = append(errs, errors.New("database.host/username/dbname 不能为空"))
+	}
+	if d.Port <= 0 || d.Port > 65535 {
+		errs = append(errs, fmt.Errorf("database.port 不合法: %d", d.Port))
+	}
+	return errs
+}
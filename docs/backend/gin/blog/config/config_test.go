@@ -0,0 +1,219 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig 返回可以通过校验的配置，各个用例在它的基础上修改
+func validConfig() *Config {
+	cfg := Default()
+	cfg.JWT.Secret = "0123456789abcdef"
+	return cfg
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	yamlFile := writeFile(t, "config.yaml", `
+server:
+  addr: ":9000"
+database:
+  driver: sqlite
+  host: db.example.com
+jwt:
+  expire: 30m
+site:
+  title: 文件中的标题
+`)
+	tomlFile := writeFile(t, "config.toml", `
+[server]
+addr = ":9000"
+
+[database]
+driver = "sqlite"
+host = "db.example.com"
+
+[jwt]
+expire = "30m"
+
+[site]
+title = "文件中的标题"
+`)
+	for _, path := range []string{yamlFile, tomlFile} {
+		t.Run(filepath.Ext(path), func(t *testing.T) {
+			// 环境变量覆盖配置文件，配置文件覆盖默认值，两者都没有设置的项保持默认值
+			t.Setenv("BLOG_DB_HOST", "env.example.com")
+			t.Setenv("BLOG_DB_PORT", "5432")
+			t.Setenv("BLOG_JWT_REFRESH_EXPIRE", "48h")
+			t.Setenv("BLOG_SERVER_TRUSTED_PROXIES", " 10.0.0.1, ,10.0.0.2")
+			t.Setenv("BLOG_LOG_COMPRESS", "false")
+			t.Setenv("BLOG_ADMIN_EMAILS", "root@example.com")
+			t.Setenv("BLOG_MEDIA_S3_BUCKET", "blog")
+			cfg, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checks := []struct {
+				name      string
+				got, want any
+			}{
+				{"server.addr（文件）", cfg.Server.Addr, ":9000"},
+				{"server.mode（默认值）", cfg.Server.Mode, "debug"},
+				{"database.driver（文件）", cfg.Database.Driver, "sqlite"},
+				{"database.host（环境变量覆盖文件）", cfg.Database.Host, "env.example.com"},
+				{"database.port（环境变量）", cfg.Database.Port, 5432},
+				{"jwt.expire（文件）", cfg.JWT.Expire, Duration(30 * time.Minute)},
+				{"jwt.refresh_expire（环境变量）", cfg.JWT.RefreshExpire, Duration(48 * time.Hour)},
+				{"server.trusted_proxies（环境变量）", strings.Join(cfg.Server.TrustedProxies, ","), "10.0.0.1,10.0.0.2"},
+				{"log.compress（环境变量）", cfg.Log.Compress, false},
+				{"admin.emails（环境变量）", cfg.Admin.IsAdminEmail("Root@Example.com"), true},
+				{"media.s3.bucket（嵌套的段）", cfg.Media.S3.Bucket, "blog"},
+				{"media.s3.region（默认值）", cfg.Media.S3.Region, "us-east-1"},
+				{"site.title（文件）", cfg.Site.Title, "文件中的标题"},
+			}
+			for _, c := range checks {
+				if c.got != c.want {
+					t.Errorf("%s = %v，期望 %v", c.name, c.got, c.want)
+				}
+			}
+		})
+	}
+
+	t.Run("不使用配置文件", func(t *testing.T) {
+		t.Setenv("BLOG_JWT_SECRET", "0123456789abcdef")
+		cfg, err := Load("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.JWT.Secret != "0123456789abcdef" || cfg.Database.Driver != "mysql" {
+			t.Fatalf("配置不符合预期: %+v", cfg)
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Fatalf("默认值加上密钥应该可以通过校验: %v", err)
+		}
+	})
+}
+
+func TestLoadErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		path string
+		env  map[string]string
+		want string
+	}{
+		{"文件不存在", filepath.Join(t.TempDir(), "missing.yaml"), nil, "读取配置文件失败"},
+		{"不支持的格式", writeFile(t, "config.json", "{}"), nil, "不支持的配置文件格式"},
+		{"YAML 格式错误", writeFile(t, "bad.yaml", "server: [\n"), nil, "解析配置文件失败"},
+		{"TOML 格式错误", writeFile(t, "bad.toml", "[server\n"), nil, "解析配置文件失败"},
+		{"文件中的时间长度错误", writeFile(t, "expire.yaml", "jwt:\n  expire: 十分钟\n"), nil, "解析配置文件失败"},
+		{"环境变量中的整数错误", "", map[string]string{"BLOG_DB_PORT": "abc"}, "BLOG_DB_PORT"},
+		{"环境变量中的布尔值错误", "", map[string]string{"BLOG_LOG_COMPRESS": "maybe"}, "BLOG_LOG_COMPRESS"},
+		{"环境变量中的时间长度错误", "", map[string]string{"BLOG_JWT_EXPIRE": "1 分钟"}, "BLOG_JWT_EXPIRE"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for k, v := range c.env {
+				t.Setenv(k, v)
+			}
+			_, err := Load(c.path)
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Fatalf("期望包含 %q 的错误，实际 %v", c.want, err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatalf("基础配置应该可以通过校验: %v", err)
+	}
+	cases := []struct {
+		name   string
+		modify func(*Config)
+		want   string // 为空表示应该通过校验
+	}{
+		{"监听地址为空", func(c *Config) { c.Server.Addr = "" }, "server.addr"},
+		{"运行模式不合法", func(c *Config) { c.Server.Mode = "prod" }, "server.mode"},
+		{"数据库驱动不支持", func(c *Config) { c.Database.Driver = "oracle" }, "不支持的数据库驱动"},
+		{"sqlite 路径为空", func(c *Config) { c.Database.Driver, c.Database.Path = "sqlite", "" }, "database.path"},
+		{"sqlite 不需要连接参数", func(c *Config) { c.Database.Driver, c.Database.Host, c.Database.Port = "sqlite", "", 0 }, ""},
+		{"数据库连接参数为空", func(c *Config) { c.Database.Username = "" }, "database.host/username/dbname"},
+		{"数据库端口不合法", func(c *Config) { c.Database.Port = 70000 }, "database.port"},
+		{"密钥为空", func(c *Config) { c.JWT.Secret = "" }, "jwt.secret 未设置"},
+		{"密钥为占位值", func(c *Config) { c.JWT.Secret = PlaceholderSecret }, "jwt.secret 未设置"},
+		{"密钥太短", func(c *Config) { c.JWT.Secret = "short" }, "jwt.secret 长度"},
+		{"有效期为 0", func(c *Config) { c.JWT.Expire = 0 }, "必须大于 0"},
+		{"刷新有效期小于有效期", func(c *Config) { c.JWT.RefreshExpire = Duration(time.Minute) }, "jwt.refresh_expire 不能小于"},
+		{"定时发布间隔为 0", func(c *Config) { c.Scheduler.Interval = 0 }, "scheduler.interval"},
+		{"评论层数为 0", func(c *Config) { c.Comments.MaxDepth = 0 }, "comments.max_depth"},
+		{"表情为空", func(c *Config) { c.Reactions.Emojis = []string{""} }, "reactions.emojis"},
+		{"表情太长", func(c *Config) { c.Reactions.Emojis = []string{strings.Repeat("a", 21)} }, "reactions.emojis"},
+		{"心跳间隔为 0", func(c *Config) { c.Notifications.Heartbeat = 0 }, "notifications.heartbeat"},
+		{"渲染缓存为负数", func(c *Config) { c.Render.CacheSize = -1 }, "render.cache_size"},
+		{"站点标题为空", func(c *Config) { c.Site.Title = "" }, "site.title"},
+		{"每页文章数为 0", func(c *Config) { c.Site.PageSize = 0 }, "site.page_size"},
+		{"每页文章数太多", func(c *Config) { c.Site.PageSize = 101 }, "site.page_size"},
+		{"页面缓存时间为负数", func(c *Config) { c.Site.MaxAge = -1 }, "site.max_age"},
+		{"站点地址不是 http", func(c *Config) { c.Site.URL = "ftp://blog.example.com" }, "site.url"},
+		{"站点地址没有域名", func(c *Config) { c.Site.URL = "https://" }, "site.url"},
+		{"站点地址合法", func(c *Config) { c.Site.URL = "https://blog.example.com" }, ""},
+		{"订阅源文章数为 0", func(c *Config) { c.Site.FeedSize = 0 }, "site.feed_size"},
+		{"存储后端不支持", func(c *Config) { c.Media.Storage = "ftp" }, "media.storage"},
+		{"本地目录为空", func(c *Config) { c.Media.Dir = "" }, "media.dir"},
+		{"S3 地址不合法", func(c *Config) {
+			c.Media.Storage = "s3"
+			c.Media.S3 = S3Config{Endpoint: "minio:9000", Region: "us-east-1", Bucket: "blog", AccessKey: "a", SecretKey: "s"}
+		}, "media.s3.endpoint"},
+		{"S3 缺少密钥", func(c *Config) {
+			c.Media.Storage = "s3"
+			c.Media.S3 = S3Config{Endpoint: "http://127.0.0.1:9000", Region: "us-east-1", Bucket: "blog", AccessKey: "a"}
+		}, "media.s3 的 region"},
+		{"S3 配置完整", func(c *Config) {
+			c.Media.Storage, c.Media.Dir = "s3", ""
+			c.Media.S3 = S3Config{Endpoint: "http://127.0.0.1:9000", Region: "us-east-1", Bucket: "blog", AccessKey: "a", SecretKey: "s"}
+		}, ""},
+		{"文件大小限制为 0", func(c *Config) { c.Media.MaxSize = 0 }, "media.max_size"},
+		{"允许的文件类型为空", func(c *Config) { c.Media.AllowedTypes = nil }, "media.allowed_types 不能为空"},
+		{"文件类型不合法", func(c *Config) { c.Media.AllowedTypes = []string{"png"} }, `"png" 不是合法的文件类型`},
+		{"日志级别不合法", func(c *Config) { c.Log.Level = "verbose" }, "log.level"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := validConfig()
+			c.modify(cfg)
+			err := cfg.Validate()
+			if c.want == "" {
+				if err != nil {
+					t.Fatalf("应该通过校验: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Fatalf("期望包含 %q 的错误，实际 %v", c.want, err)
+			}
+		})
+	}
+
+	// 所有错误一起返回，而不是遇到第一个就停止
+	cfg := validConfig()
+	cfg.Server.Addr = ""
+	cfg.Database.Port = 0
+	cfg.Log.Level = "verbose"
+	err := cfg.Validate()
+	for _, want := range []string{"server.addr", "database.port", "log.level"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("错误中缺少 %s: %v", want, err)
+		}
+	}
+}
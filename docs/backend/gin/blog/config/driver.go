@@ -0,0 +1,146 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// MemoryPath 是 SQLite 内存数据库的路径，常用于本地调试和测试
+const MemoryPath = ":memory:"
+
+// Driver 描述一种数据库驱动：如何打开连接，以及连接前如何确保数据库存在
+type Driver interface {
+	// Dialector 根据配置生成 GORM 的方言（连接器）
+	Dialector(cfg DatabaseConfig) gorm.Dialector
+	// CreateDatabase 连接数据库服务器并创建 cfg.DBName，嵌入式数据库直接返回 nil
+	CreateDatabase(cfg DatabaseConfig) error
+}
+
+// drivers 保存所有可以通过 database.driver 选择的驱动
+var drivers = map[string]Driver{
+	"mysql":    mysqlDriver{},
+	"postgres": postgresDriver{},
+	"sqlite":   sqliteDriver{},
+}
+
+// LookupDriver 根据名称查找驱动
+func LookupDriver(name string) (Driver, error) {
+	d, ok := drivers[name]
+	if !ok {
+		return nil, fmt.Errorf("不支持的数据库驱动 %q，可选值: %s", name, strings.Join(driverNames(), "/"))
+	}
+	return d, nil
+}
+
+func driverNames() []string {
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// gormConfig 是所有驱动共用的 GORM 配置
+func gormConfig() *gorm.Config {
+	return &gorm.Config{
+		DisableForeignKeyConstraintWhenMigrating: true, // 禁用自动创建外键约束(禁用实体外键)
+		TranslateError:                           true, // 把各驱动的唯一约束等错误统一转换成 gorm.ErrDuplicatedKey
+		// 记录不存在由仓储层转换为 ErrNotFound 处理，不需要打印成错误日志
+		Logger: logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+			SlowThreshold:             200 * time.Millisecond,
+			LogLevel:                  logger.Warn,
+			IgnoreRecordNotFoundError: true,
+			Colorful:                  true,
+		}),
+	}
+}
+
+type mysqlDriver struct{}
+
+func (mysqlDriver) dsn(cfg DatabaseConfig, dbname string) string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local", cfg.Username, cfg.Password, cfg.Host, cfg.Port, dbname)
+}
+
+func (d mysqlDriver) Dialector(cfg DatabaseConfig) gorm.Dialector {
+	return mysql.Open(d.dsn(cfg, cfg.DBName))
+}
+
+func (d mysqlDriver) CreateDatabase(cfg DatabaseConfig) error {
+	//连接MySQL服务器（不指定数据库）
+	db, err := gorm.Open(mysql.Open(d.dsn(cfg, "")), gormConfig())
+	if err != nil {
+		return fmt.Errorf("MySQL连接失败: %w", err)
+	}
+	defer closeDB(db)
+	createDBSQL := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;", cfg.DBName)
+	return db.Exec(createDBSQL).Error
+}
+
+type postgresDriver struct{}
+
+func (postgresDriver) dsn(cfg DatabaseConfig, dbname string) string {
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=Local",
+		cfg.Host, cfg.Username, cfg.Password, dbname, cfg.Port, cfg.SSLMode)
+}
+
+func (d postgresDriver) Dialector(cfg DatabaseConfig) gorm.Dialector {
+	return postgres.Open(d.dsn(cfg, cfg.DBName))
+}
+
+func (d postgresDriver) CreateDatabase(cfg DatabaseConfig) error {
+	// PostgreSQL 没有 CREATE DATABASE IF NOT EXISTS，先连接默认的 postgres 库查询是否已存在
+	db, err := gorm.Open(postgres.Open(d.dsn(cfg, "postgres")), gormConfig())
+	if err != nil {
+		return fmt.Errorf("PostgreSQL连接失败: %w", err)
+	}
+	defer closeDB(db)
+	var count int64
+	if err := db.Raw("SELECT COUNT(*) FROM pg_database WHERE datname = ?", cfg.DBName).Scan(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return db.Exec(fmt.Sprintf("CREATE DATABASE %q ENCODING 'UTF8'", cfg.DBName)).Error
+}
+
+type sqliteDriver struct{}
+
+// dsn 在路径后追加连接参数，路径中已经带有参数（例如 "file:blog.db?cache=shared"）时接在原有参数后面
+func (sqliteDriver) dsn(cfg DatabaseConfig) string {
+	if cfg.Path == MemoryPath {
+		return MemoryPath
+	}
+	sep := "?"
+	if strings.Contains(cfg.Path, "?") {
+		sep = "&"
+	}
+	// busy_timeout 避免并发写入时立即返回 database is locked
+	return cfg.Path + sep + "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+}
+
+func (d sqliteDriver) Dialector(cfg DatabaseConfig) gorm.Dialector {
+	return sqlite.Open(d.dsn(cfg))
+}
+
+// CreateDatabase SQLite 在第一次连接时自动创建文件，不需要额外处理
+func (sqliteDriver) CreateDatabase(cfg DatabaseConfig) error {
+	return nil
+}
+
+func closeDB(db *gorm.DB) {
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
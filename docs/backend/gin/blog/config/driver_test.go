@@ -0,0 +1,66 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLookupDriver(t *testing.T) {
+	for name, want := range map[string]Driver{
+		"mysql":    mysqlDriver{},
+		"postgres": postgresDriver{},
+		"sqlite":   sqliteDriver{},
+	} {
+		d, err := LookupDriver(name)
+		if err != nil || d != want {
+			t.Errorf("LookupDriver(%q) = %T, %v", name, d, err)
+		}
+	}
+	for _, name := range []string{"", "MySQL", "sqlite3"} {
+		_, err := LookupDriver(name)
+		if err == nil || !strings.Contains(err.Error(), "mysql/postgres/sqlite") {
+			t.Errorf("LookupDriver(%q) 应该返回列出可选值的错误，实际 %v", name, err)
+		}
+	}
+}
+
+func TestDSN(t *testing.T) {
+	cfg := DatabaseConfig{Username: "user", Password: "pass", Host: "db", Port: 3306, DBName: "blog", SSLMode: "disable"}
+	cases := []struct {
+		name, got, want string
+	}{
+		{"mysql", mysqlDriver{}.dsn(cfg, cfg.DBName), "user:pass@tcp(db:3306)/blog?charset=utf8mb4&parseTime=True&loc=Local"},
+		{"mysql 不指定数据库", mysqlDriver{}.dsn(cfg, ""), "user:pass@tcp(db:3306)/?charset=utf8mb4&parseTime=True&loc=Local"},
+		{"postgres", postgresDriver{}.dsn(cfg, cfg.DBName), "host=db user=user password=pass dbname=blog port=3306 sslmode=disable TimeZone=Local"},
+		{"sqlite 内存数据库", sqliteDriver{}.dsn(DatabaseConfig{Path: MemoryPath}), MemoryPath},
+		{"sqlite 文件", sqliteDriver{}.dsn(DatabaseConfig{Path: "data/blog.db"}),
+			"data/blog.db?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"},
+		{"sqlite 路径带参数", sqliteDriver{}.dsn(DatabaseConfig{Path: "file:blog.db?cache=shared"}),
+			"file:blog.db?cache=shared&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"},
+	}
+	for _, c := range cases {
+		if c.got != c.want {
+			t.Errorf("%s: DSN = %q，期望 %q", c.name, c.got, c.want)
+		}
+	}
+}
+
+// TestSQLiteOpen 检查生成的 DSN 可以真正打开数据库，路径中已有的参数仍然生效
+func TestSQLiteOpen(t *testing.T) {
+	for _, path := range []string{
+		MemoryPath,
+		filepath.Join(t.TempDir(), "blog.db"),
+		"file:" + filepath.Join(t.TempDir(), "blog.db") + "?mode=rwc",
+	} {
+		db := InitDB(DatabaseConfig{Driver: "sqlite", Path: path})
+		var mode string
+		if err := db.Raw("PRAGMA journal_mode").Scan(&mode).Error; err != nil {
+			t.Fatal(err)
+		}
+		if path != MemoryPath && mode != "wal" {
+			t.Errorf("%s: journal_mode = %q，期望 wal", path, mode)
+		}
+		closeDB(db)
+	}
+}
@@ -2,68 +2,92 @@ package config
 
 import (
 	modles "blog/models"
-	"fmt"
 	"log"
 
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
-var DB *gorm.DB
-
-var (
-	username = "testuser"
-	password = "123456test"
-	host     = "127.0.0.1"
-	port     = 3306
-	dbname   = "ginTestDB"
-)
-
-func InitDB() {
+// InitDB 连接数据库并返回连接，由启动代码注入到仓储层
+func InitDB(cfg DatabaseConfig) *gorm.DB {
+	driver, err := LookupDriver(cfg.Driver)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
 	//连接数据库
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local", username, password, host, port, dbname)
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
-		DisableForeignKeyConstraintWhenMigrating: true, // 禁用自动创建外键约束(禁用实体外键)
-	})
-
+	db, err := gorm.Open(driver.Dialector(cfg), gormConfig())
 	if err != nil {
 		log.Fatalf("❌ 连接数据库失败: %v", err) //打印错误信息 并立即终止程序（os.Exit(1))
 	}
+	// SQLite 内存数据库的每个连接都是一个独立的库，只保留一个连接才能共享同一份数据
+	if cfg.Driver == "sqlite" && cfg.Path == MemoryPath {
+		sqlDB, err := db.DB()
+		if err != nil {
+			log.Fatalf("❌ 获取数据库连接池失败: %v", err)
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
 
-	log.Println("✅ 数据库连接成功！")
-	DB = db
-
+	log.Printf("✅ 数据库连接成功！(%s)", cfg.Driver)
+	return db
 }
 
-func CreatDB() {
-	//连接MySQL服务器
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/", username, password, host, port)
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
-		DisableForeignKeyConstraintWhenMigrating: true, // 禁用自动创建外键约束(禁用实体外键)
-	})
-
+func CreatDB(cfg DatabaseConfig) {
+	driver, err := LookupDriver(cfg.Driver)
 	if err != nil {
-		log.Fatalf("❌ MySQL连接失败: %v", err) //打印错误信息 并立即终止程序（os.Exit(1))
+		log.Fatalf("❌ %v", err)
 	}
-	log.Println("✅ MySQL连接成功！")
-	DB = db
-	//创建数据库
-	createDBSQL := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;", dbname)
-	err = DB.Exec(createDBSQL).Error
-	if err != nil {
+	//创建数据库（SQLite 会在连接时自动创建，这里直接跳过）
+	if err := driver.CreateDatabase(cfg); err != nil {
 		log.Fatalf("❌ 创建数据库失败: %v", err) //打印错误信息 并立即终止程序（os.Exit(1))
 	}
 	log.Println("✅ 数据库创建成功！")
 }
 
-func MigrateDB() {
-	err := DB.AutoMigrate(
+func MigrateDB(db *gorm.DB) {
+	err := db.AutoMigrate(
 		&modles.User{},
 		&modles.Post{},
 		&modles.Comment{},
+		&modles.Session{},
+		&modles.Tag{},
+		&modles.Category{},
+		&modles.PostRevision{},
+		&modles.Reaction{},
+		&modles.Follow{},
+		&modles.Notification{},
+		&modles.Media{},
 	)
 	if err != nil {
 		log.Fatalf("❌ 数据库迁移失败: %v", err) //打印错误信息 并立即终止程序（os.Exit(1))
 	}
+	if err := migratePublishedAt(db); err != nil {
+		log.Fatalf("❌ 数据库迁移失败: %v", err)
+	}
 	log.Println("✅ 数据库迁移成功！")
 }
+
+// postIndexes 是按发布时间分页需要的索引：首页时间线按作者过滤后按 (published_at, id) 排序，
+// 公开页面和订阅源直接按 (published_at, id) 排序。索引包含 id，GORM 的结构体标签无法给 gorm.Model 的字段加索引，所以手动创建
+var postIndexes = map[string]string{
+	"idx_posts_user_published": "user_id, published_at, id",
+	"idx_posts_published":      "published_at, id",
+}
+
+// migratePublishedAt 给发布状态加入之前发布的文章补上发布时间（使用创建时间），
+// 之后已发布的文章一定有 published_at，排序和游标分页可以直接使用这一列和它的索引
+func migratePublishedAt(db *gorm.DB) error {
+	err := db.Model(&modles.Post{}).Where("published_at IS NULL AND status = ?", modles.PostPublished).
+		UpdateColumn("published_at", gorm.Expr("created_at")).Error
+	if err != nil {
+		return err
+	}
+	for name, columns := range postIndexes {
+		if db.Migrator().HasIndex(&modles.Post{}, name) {
+			continue
+		}
+		if err := db.Exec("CREATE INDEX " + name + " ON posts (" + columns + ")").Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
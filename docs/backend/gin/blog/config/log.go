@@ -10,14 +10,14 @@ import (
 
 var Log = logrus.New()
 
-func InitLog() {
+func InitLog(cfg LogConfig) {
 	// 1. 配置 lumberjack 日志切割归档功能
 	fileWriter := &lumberjack.Logger{
-		Filename:   "logs/app.log", // 日志文件路径
-		MaxSize:    10,             // 单个日志文件最大尺寸（单位：MB），超过就会切割
-		MaxBackups: 5,              // 最多保留旧日志文件的个数
-		MaxAge:     30,             // 保留最近多少天的日志
-		Compress:   true,           // 是否压缩旧日志（变成 .gz 格式，节省磁盘空间）
+		Filename:   cfg.Filename,   // 日志文件路径
+		MaxSize:    cfg.MaxSize,    // 单个日志文件最大尺寸（单位：MB），超过就会切割
+		MaxBackups: cfg.MaxBackups, // 最多保留旧日志文件的个数
+		MaxAge:     cfg.MaxAge,     // 保留最近多少天的日志
+		Compress:   cfg.Compress,   // 是否压缩旧日志（变成 .gz 格式，节省磁盘空间）
 	}
 
 	// 2. 组合输出：同时输出到“控制台”和“日志文件”
@@ -32,8 +32,12 @@ func InitLog() {
 		TimestampFormat: "2006-01-02 15:04:05", // 时间格式
 	})
 
-	// 4. 设置日志级别
-	Log.SetLevel(logrus.InfoLevel)
+	// 4. 设置日志级别（Validate 已经校验过，解析失败时退回 Info）
+	level, err := logrus.ParseLevel(cfg.Level)
+	if err != nil {
+		level = logrus.InfoLevel
+	}
+	Log.SetLevel(level)
 
 	// 知道是哪行代码打印的日志，消耗一点点性能
 	// Log.SetReportCaller(true)
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"blog/config"
+	"blog/models"
+	"blog/repository"
+	"errors"
+	"gin-demo/res"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// 以下是管理员接口，路由上通过 RequirePermission(models.PermManageUsers) 限制访问
+
+func (h *Handler) ListUsers(c *gin.Context) {
+	// 用户列表默认按注册时间倒序，只支持分页和排序，不接受 author_id、tag 等文章的过滤参数
+	opts, err := bindPageOptions(c, true)
+	if err != nil {
+		res.FailBind(c, err)
+		return
+	}
+	page, err := h.Users.List(c.Request.Context(), opts)
+	if err != nil {
+		// [日志] 记录查询用户失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Error("获取用户列表失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "获取用户列表失败")
+		return
+	}
+	res.OkData(c, gin.H{"users": page.Items, "pagination": paginationMeta(opts, page)})
+}
+
+func (h *Handler) SetUserRole(c *gin.Context) {
+	// 当前管理员 ID（从 JWT 提取）
+	adminID := c.GetUint("user_id")
+	userID, ok := paramID(c, "user_id")
+	if !ok {
+		res.FailCode(c, res.CodeInvalidParams, "无效的用户 ID")
+		return
+	}
+	var input struct {
+		Role models.Role `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		res.FailBind(c, err)
+		return
+	}
+	if !input.Role.Valid() {
+		res.FailCode(c, res.CodeInvalidParams, "无效的角色，可选值: admin/moderator/author/reader")
+		return
+	}
+	// 防止管理员误操作把自己降级，导致没有人能管理用户
+	if userID == adminID {
+		res.FailCode(c, res.CodeInvalidParams, "不能修改自己的角色")
+		return
+	}
+	if err := h.Users.SetRole(c.Request.Context(), userID, input.Role); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			res.FailCode(c, res.CodeNotFound, "用户未找到")
+			return
+		}
+		// [日志] 记录修改角色失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"admin_id": adminID,
+			"user_id":  userID,
+			"error":    err.Error(),
+		}).Error("修改角色失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "修改角色失败")
+		return
+	}
+	// 角色保存在 token 中，吊销该用户的全部会话，让新角色立即生效
+	if err := h.Sessions.RevokeAllByUser(c.Request.Context(), userID); err != nil {
+		config.Log.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("修改角色失败：吊销会话失败")
+		res.FailCode(c, res.CodeServerError, "修改角色失败")
+		return
+	}
+	// [日志] 记录角色变更，便于审计
+	config.Log.WithFields(logrus.Fields{
+		"admin_id": adminID,
+		"user_id":  userID,
+		"role":     input.Role,
+	}).Info("管理员修改了用户角色")
+	res.Ok(c, gin.H{"user_id": userID, "role": input.Role}, "角色修改成功")
+}
+
+func (h *Handler) DeleteUser(c *gin.Context) {
+	// 当前管理员 ID（从 JWT 提取）
+	adminID := c.GetUint("user_id")
+	userID, ok := paramID(c, "user_id")
+	if !ok {
+		res.FailCode(c, res.CodeInvalidParams, "无效的用户 ID")
+		return
+	}
+	if userID == adminID {
+		res.FailCode(c, res.CodeInvalidParams, "不能删除自己")
+		return
+	}
+	if err := h.Users.Delete(c.Request.Context(), userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			res.FailCode(c, res.CodeNotFound, "用户未找到")
+			return
+		}
+		// [日志] 记录删除用户失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"admin_id": adminID,
+			"user_id":  userID,
+			"error":    err.Error(),
+		}).Error("删除用户失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "删除用户失败")
+		return
+	}
+	// 被删除的用户立即下线
+	if err := h.Sessions.RevokeAllByUser(c.Request.Context(), userID); err != nil {
+		config.Log.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("删除用户失败：吊销会话失败")
+		res.FailCode(c, res.CodeServerError, "删除用户失败")
+		return
+	}
+	// [日志] 记录删除用户，便于审计
+	config.Log.WithFields(logrus.Fields{
+		"admin_id": adminID,
+		"user_id":  userID,
+	}).Info("管理员删除了用户")
+	res.OkMsg(c, "用户删除成功")
+}
@@ -4,14 +4,17 @@ import (
 	"blog/config"
 	"blog/middle"
 	"blog/models"
-	"net/http"
+	"blog/repository"
+	"errors"
+	"gin-demo/res"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func Register(c *gin.Context) {
+func (h *Handler) Register(c *gin.Context) {
 	// 定义输入结构体
 	var input struct {
 		Name     string `json:"name" binding:"required"`
@@ -26,7 +29,7 @@ func Register(c *gin.Context) {
 			"error": err.Error(),
 		}).Warn("注册失败：参数格式错误")
 		// 返回错误响应
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		res.FailBind(c, err)
 		return
 	}
 	// 对密码进行哈希处理
@@ -38,7 +41,7 @@ func Register(c *gin.Context) {
 			"error": err.Error(),
 		}).Error("注册失败：密码加密失败")
 		// 返回错误响应
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "密码加密失败"})
+		res.FailCode(c, res.CodeServerError, "密码加密失败")
 		return
 	}
 	// 创建用户实例
@@ -46,9 +49,22 @@ func Register(c *gin.Context) {
 		Name:     input.Name,
 		Email:    input.Email,
 		Password: string(hashedPassword),
+		Role:     models.RoleAuthor,
+	}
+	// 配置中指定的邮箱注册后直接成为管理员
+	if h.Config.Admin.IsAdminEmail(input.Email) {
+		user.Role = models.RoleAdmin
 	}
 	// 保存用户到数据库
-	if err := config.DB.Create(&user).Error; err != nil {
+	if err := h.Users.Create(c.Request.Context(), &user); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			config.Log.WithFields(logrus.Fields{
+				"ip":    c.ClientIP(),
+				"email": input.Email,
+			}).Warn("注册失败：邮箱已被注册")
+			res.FailCode(c, res.CodeConflict, "邮箱已被注册")
+			return
+		}
 		// [日志] 记录用户创建失败的信息
 		config.Log.WithFields(logrus.Fields{
 			"ip":    c.ClientIP(),
@@ -56,18 +72,18 @@ func Register(c *gin.Context) {
 			"error": err.Error(),
 		}).Error("注册失败：用户创建失败")
 		// 返回错误响应
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "用户创建失败"})
+		res.FailCode(c, res.CodeServerError, "用户创建失败")
 		return
 	}
 	// 返回成功响应
 	User_ID := user.ID
-	c.JSON(http.StatusOK, gin.H{
+	res.Ok(c, gin.H{
 		"user_id": User_ID,
-		"message": "用户注册成功",
-	})
+		"role":    user.Role,
+	}, "用户注册成功")
 }
 
-func Login(c *gin.Context) {
+func (h *Handler) Login(c *gin.Context) {
 	// 定义输入结构体
 	var input struct {
 		ID       uint   `json:"id"  binding:"omitempty"`
@@ -82,34 +98,34 @@ func Login(c *gin.Context) {
 			"error": err.Error(),
 		}).Warn("登录失败：参数格式错误")
 		// 返回错误响应
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		res.FailBind(c, err)
 		return
 	}
 
-	var user models.User
-	//基础查询
-	query := config.DB.Model(&user)
-
+	var (
+		user *models.User
+		err  error
+	)
 	//根据用户ID查询
 	if input.ID != 0 {
-		query = query.Where("id = ?", input.ID)
+		user, err = h.Users.FindByID(c.Request.Context(), input.ID)
 		//根据邮箱查询
 	} else if input.Email != "" {
-		query = query.Where("email = ?", input.Email)
+		user, err = h.Users.FindByEmail(c.Request.Context(), input.Email)
 	} else {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "请输入用户名或邮箱"})
+		res.FailCode(c, res.CodeInvalidParams, "请输入用户名或邮箱")
 		return
 	}
 
-	//执行查询
-	if err := query.First(&user).Error; err != nil {
+	//检查查询结果
+	if err != nil {
 		// [日志] 记录用户不存在的信息
 		config.Log.WithFields(logrus.Fields{
 			"ip":    c.ClientIP(),
 			"error": err.Error(),
 		}).Warn("登录失败：用户不存在")
 		// 返回错误响应
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+		res.FailCode(c, res.CodeUnauthorized, "用户不存在")
 		return
 	}
 
@@ -120,11 +136,11 @@ func Login(c *gin.Context) {
 			"ip": c.ClientIP(),
 		}).Warn("登录失败：密码错误")
 		// 返回错误响应
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "密码错误"})
+		res.FailCode(c, res.CodeUnauthorized, "密码错误")
 		return
 	}
-	// 生成JWT令牌
-	token, err := middle.GenerateToken(user.ID, c)
+	// 创建会话并生成JWT令牌
+	tokens, err := h.startSession(c, user)
 	if err != nil {
 		// [日志] 记录令牌生成失败的信息
 		config.Log.WithFields(logrus.Fields{
@@ -133,12 +149,146 @@ func Login(c *gin.Context) {
 			"error":   err.Error(),
 		}).Error("登录失败：令牌生成失败")
 		// 返回错误响应
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "令牌生成失败"})
+		res.FailCode(c, res.CodeServerError, "令牌生成失败")
 		return
 	}
 	// 返回成功响应和令牌
-	c.JSON(http.StatusOK, gin.H{
-		"message": "登录成功",
-		"token":   token,
-	})
+	res.Ok(c, tokens, "登录成功")
+}
+
+// startSession 为用户创建新的登录会话，并签发 access token 和 refresh token
+func (h *Handler) startSession(c *gin.Context, user *models.User) (*middle.TokenPair, error) {
+	session := models.Session{
+		JTI:        middle.NewJTI(),
+		UserID:     user.ID,
+		RefreshJTI: middle.NewJTI(),
+		ExpiresAt:  middle.RefreshExpiry(time.Now()),
+	}
+	if err := h.Sessions.Create(c.Request.Context(), &session); err != nil {
+		return nil, err
+	}
+	return middle.GenerateTokenPair(user, session.JTI, session.RefreshJTI)
+}
+
+// RefreshToken 用 refresh token 换取新的一对 token。
+// refresh token 只能使用一次：每次刷新都会轮换，旧的 refresh token 再次出现说明可能被盗用，此时吊销整个会话
+func (h *Handler) RefreshToken(c *gin.Context) {
+	var input struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		// [日志] 记录参数绑定失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"ip":    c.ClientIP(),
+			"error": err.Error(),
+		}).Warn("刷新令牌失败：参数格式错误")
+		res.FailBind(c, err)
+		return
+	}
+	claims, err := middle.ParseToken(input.RefreshToken, middle.TokenTypeRefresh)
+	if err != nil {
+		// [日志] 记录 refresh token 验证失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"ip":    c.ClientIP(),
+			"error": err.Error(),
+		}).Warn("刷新令牌失败：refresh token 无效")
+		res.FailCode(c, res.CodeUnauthorized, "无效的 refresh token")
+		return
+	}
+
+	// 重新读取用户，让新 token 带上最新的角色；用户已被删除时不再续期
+	user, err := h.Users.FindByID(c.Request.Context(), claims.ID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		config.Log.WithFields(logrus.Fields{
+			"user_id": claims.ID,
+			"error":   err.Error(),
+		}).Error("刷新令牌失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "刷新令牌失败")
+		return
+	}
+	if err != nil {
+		if err := h.Sessions.Revoke(c.Request.Context(), claims.SessionID); err != nil {
+			config.Log.WithError(err).Error("刷新令牌失败：吊销会话失败")
+		}
+		config.Log.WithFields(logrus.Fields{
+			"ip":      c.ClientIP(),
+			"user_id": claims.ID,
+			"error":   err.Error(),
+		}).Warn("刷新令牌失败：用户不存在")
+		res.FailCode(c, res.CodeUnauthorized, "登录已失效，请重新登录")
+		return
+	}
+
+	newRefresh := middle.NewJTI()
+	err = h.Sessions.Rotate(c.Request.Context(), claims.SessionID, claims.RegisteredClaims.ID, newRefresh, middle.RefreshExpiry(time.Now()))
+	if errors.Is(err, repository.ErrNotFound) {
+		// 会话不存在、已吊销，或者这个 refresh token 已经被用过：一律吊销会话，强制重新登录
+		if err := h.Sessions.Revoke(c.Request.Context(), claims.SessionID); err != nil {
+			config.Log.WithError(err).Error("刷新令牌失败：吊销会话失败")
+		}
+		// [日志] 记录 refresh token 重复使用的信息
+		config.Log.WithFields(logrus.Fields{
+			"ip":         c.ClientIP(),
+			"user_id":    claims.ID,
+			"session_id": claims.SessionID,
+		}).Warn("刷新令牌失败：refresh token 已失效或被重复使用，会话已吊销")
+		res.FailCode(c, res.CodeUnauthorized, "登录已失效，请重新登录")
+		return
+	}
+	if err != nil {
+		// [日志] 记录会话更新失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"session_id": claims.SessionID,
+			"error":      err.Error(),
+		}).Error("刷新令牌失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "刷新令牌失败")
+		return
+	}
+
+	tokens, err := middle.GenerateTokenPair(user, claims.SessionID, newRefresh)
+	if err != nil {
+		res.FailCode(c, res.CodeServerError, "令牌生成失败")
+		return
+	}
+	res.Ok(c, tokens, "刷新成功")
+}
+
+// Logout 吊销当前会话；请求体为 {"all": true} 时吊销该用户的所有会话（所有设备下线）
+func (h *Handler) Logout(c *gin.Context) {
+	var input struct {
+		All bool `json:"all"`
+	}
+	// 请求体是可选的
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			res.FailBind(c, err)
+			return
+		}
+	}
+	userID := c.GetUint("user_id")
+	sessionID := c.GetString("session_id")
+
+	var err error
+	if input.All {
+		err = h.Sessions.RevokeAllByUser(c.Request.Context(), userID)
+	} else {
+		err = h.Sessions.Revoke(c.Request.Context(), sessionID)
+	}
+	if err != nil {
+		// [日志] 记录注销失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"user_id":    userID,
+			"session_id": sessionID,
+			"error":      err.Error(),
+		}).Error("注销失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "注销失败")
+		return
+	}
+	// [日志] 记录注销成功的信息
+	config.Log.WithFields(logrus.Fields{
+		"user_id":    userID,
+		"session_id": sessionID,
+		"all":        input.All,
+	}).Info("用户注销")
+	res.OkMsg(c, "注销成功")
 }
@@ -2,19 +2,32 @@ package controllers
 
 import (
 	"blog/config"
+	"blog/events"
+	"blog/middle"
 	"blog/models"
-	"net/http"
+	"blog/repository"
+	"fmt"
+	"gin-demo/res"
+	"slices"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
-func CreateComment(c *gin.Context) {
-	var comment models.Comment
+// commentInput 是 POST /comments 的请求体。作者、审核状态和点赞数由服务端决定，
+// 不能直接绑定 models.Comment：请求体中嵌套的 User、Post 会被 GORM 当作关联一起写入数据库
+type commentInput struct {
+	PostID   uint   `json:"post_id" binding:"required"`
+	ParentID *uint  `json:"parent_id"` // 回复的评论，为空或 0 时是顶层评论
+	Content  string `json:"content" binding:"required,max=500"`
+}
+
+func (h *Handler) CreateComment(c *gin.Context) {
+	var input commentInput
 	// 获取用户ID
 	userID := c.GetUint("user_id")
-	// 绑定 JSON 数据到 comment 结构体(传入评论)
-	if err := c.ShouldBindJSON(&comment); err != nil {
+	// 绑定 JSON 数据到输入结构体
+	if err := c.ShouldBindJSON(&input); err != nil {
 		// [日志] 记录参数绑定失败的信息
 		config.Log.WithFields(logrus.Fields{
 			"ip":         c.ClientIP(),
@@ -23,50 +36,205 @@ func CreateComment(c *gin.Context) {
 			"error":      err.Error(),
 		}).Warn("创建评论失败：参数格式错误")
 		// 返回错误响应
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		res.FailBind(c, err)
+		return
+	}
+	comment := models.Comment{
+		Content:  input.Content,
+		UserID:   userID,
+		PostID:   input.PostID,
+		ParentID: input.ParentID,
+	}
+	// 确认文章存在，并根据文章的评论模式决定评论是否需要审核
+	post, err := h.Posts.FindByID(c.Request.Context(), comment.PostID)
+	if err != nil {
+		// [日志] 记录文章未找到的信息
+		config.Log.WithFields(logrus.Fields{
+			"user_id": userID,
+			"post_id": comment.PostID,
+			"error":   err.Error(),
+		}).Warn("创建评论失败：文章未找到")
+		res.FailCode(c, res.CodeNotFound, "文章未找到")
+		return
+	}
+	if !canViewPost(c, post) {
+		res.FailCode(c, res.CodeNotFound, "文章未找到")
+		return
+	}
+	if post.Status == models.PostArchived {
+		res.FailCode(c, res.CodeForbidden, "文章已归档，不能评论")
 		return
 	}
-	comment.UserID = userID
+	if !h.attachParent(c, post, &comment) {
+		return
+	}
+	comment.Status = models.CommentApproved
+	if post.CommentMode == models.CommentModeReview && !canModerateComments(c, post) {
+		comment.Status = models.CommentPending
+	}
 	// 保存评论到数据库
-	if err := config.DB.Create(&comment).Error; err != nil {
+	if err := h.Comments.Create(c.Request.Context(), &comment); err != nil {
 		// [日志] 记录评论创建失败的信息
 		config.Log.WithFields(logrus.Fields{
 			"user_id": userID,
 			"error":   err.Error(),
 		}).Error("创建评论失败：数据库错误")
 		// 返回错误响应
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建评论失败"})
+		res.FailCode(c, res.CodeServerError, "创建评论失败")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "评论创建成功", "comment": comment})
+	message := "评论创建成功"
+	if comment.Status == models.CommentPending {
+		message = "评论已提交，等待作者审核"
+	}
+	h.indexComment(c, post, &comment)
+	h.Events.Publish(c.Request.Context(), events.CommentCreated{Comment: comment, Post: *post})
+	res.Ok(c, gin.H{"comment": comment}, message)
 }
 
-func GetCommentsByPost(c *gin.Context) {
-	var comments []models.Comment
+// attachParent 检查回复的评论并计算嵌套层数，失败时已经写好响应。
+// parent_id 为空或 0 时是顶层评论
+func (h *Handler) attachParent(c *gin.Context, post *models.Post, comment *models.Comment) bool {
+	comment.Depth = 0
+	if comment.ParentID == nil || *comment.ParentID == 0 {
+		comment.ParentID = nil
+		return true
+	}
+	parent, err := h.Comments.FindByID(c.Request.Context(), *comment.ParentID)
+	// 回复别的文章下的评论、回复自己看不到的待审核评论都当作评论不存在
+	if err != nil || parent.PostID != post.ID || !commentVisibleTo(commentVisibility(c, post), parent) {
+		res.FailCode(c, res.CodeNotFound, "回复的评论不存在")
+		return false
+	}
+	if parent.Status == models.CommentDeleted {
+		res.FailCode(c, res.CodeInvalidParams, "不能回复已删除的评论")
+		return false
+	}
+	if maxDepth := h.Config.Comments.MaxDepth; parent.Depth+1 >= maxDepth {
+		res.FailCode(c, res.CodeInvalidParams, fmt.Sprintf("评论最多嵌套 %d 层", maxDepth))
+		return false
+	}
+	comment.Depth = parent.Depth + 1
+	return true
+}
+
+// commentQuery 是评论列表特有的查询参数
+type commentQuery struct {
+	Tree bool `form:"tree"` // 为 true 时按回复关系返回评论树，分页只作用于顶层评论
+}
+
+// commentNode 是评论树中的一个节点，回复按时间正序排列
+type commentNode struct {
+	models.Comment
+	Replies []*commentNode `json:"replies"`
+}
+
+// buildCommentTree 把文章下的评论组装成树，返回顶层评论（保持 comments 的顺序）。
+// 上级评论不在 comments 中（例如对当前用户不可见）的回复不会出现在树中
+func buildCommentTree(comments []models.Comment) []*commentNode {
+	nodes := make(map[uint]*commentNode, len(comments))
+	for i := range comments {
+		nodes[comments[i].ID] = &commentNode{Comment: comments[i], Replies: []*commentNode{}}
+	}
+	roots := []*commentNode{}
+	for i := range comments {
+		node := nodes[comments[i].ID]
+		if node.ParentID == nil {
+			roots = append(roots, node)
+		} else if parent, ok := nodes[*node.ParentID]; ok {
+			parent.Replies = append(parent.Replies, node)
+		}
+	}
+	return roots
+}
+
+func (h *Handler) GetCommentsByPost(c *gin.Context) {
 	// 从 URL 获取文章 ID
-	postID := c.Param("post_id")
-	// 查询该文章的所有评论
-	if err := config.DB.Preload("User").Preload("Post").Where("post_id = ?", postID).Find(&comments).Error; err != nil {
+	postID, ok := paramID(c, "post_id")
+	if !ok {
+		res.FailCode(c, res.CodeInvalidParams, "无效的文章 ID")
+		return
+	}
+	// 解析分页、排序和过滤参数，评论默认按时间正序
+	opts, err := bindListOptions(c, false)
+	if err != nil {
+		res.FailBind(c, err)
+		return
+	}
+	post, err := h.Posts.FindByID(c.Request.Context(), postID)
+	if err != nil || !canViewPost(c, post) {
+		res.FailCode(c, res.CodeNotFound, "文章未找到")
+		return
+	}
+	opts.Visibility = commentVisibility(c, post)
+	var q commentQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		res.FailBind(c, err)
+		return
+	}
+	if q.Tree {
+		h.getCommentTree(c, postID, opts)
+		return
+	}
+	// 查询该文章的评论
+	page, err := h.Comments.ListByPost(c.Request.Context(), postID, opts)
+	if err != nil {
 		// [日志] 记录查询评论失败的信息
 		config.Log.WithFields(logrus.Fields{
 			"post_id": postID,
 			"error":   err.Error(),
 		}).Error("获取评论失败：数据库错误")
 		// 返回错误响应
-		c.JSON(http.StatusNotFound, gin.H{"error": "文章未找到"})
+		res.FailCode(c, res.CodeNotFound, "文章未找到")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"comments": comments})
+	for i := range page.Items {
+		redactDeleted(&page.Items[i])
+	}
+	res.OkData(c, gin.H{"comments": page.Items, "pagination": paginationMeta(opts, page)})
 }
 
-func DeleteComment(c *gin.Context) {
-	var comment models.Comment
+// getCommentTree 一次读出文章下的全部评论，在内存中组装成树后按顶层评论分页
+func (h *Handler) getCommentTree(c *gin.Context, postID uint, opts repository.ListOptions) {
+	if opts.Cursor != nil {
+		res.FailCode(c, res.CodeInvalidParams, "评论树不支持游标分页，请使用 page 参数")
+		return
+	}
+	comments, err := h.Comments.ListTree(c.Request.Context(), postID, opts.Visibility)
+	if err != nil {
+		// [日志] 记录查询评论失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"post_id": postID,
+			"error":   err.Error(),
+		}).Error("获取评论树失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "获取评论失败")
+		return
+	}
+	for i := range comments {
+		redactDeleted(&comments[i])
+	}
+	roots := buildCommentTree(comments)
+	if opts.Desc {
+		slices.Reverse(roots)
+	}
+	page := &repository.Page[*commentNode]{Total: int64(len(roots))}
+	start := min((opts.Page-1)*opts.Size, len(roots))
+	page.Items = roots[start:min(start+opts.Size, len(roots))]
+	res.OkData(c, gin.H{"comments": page.Items, "pagination": paginationMeta(opts, page)})
+}
+
+func (h *Handler) DeleteComment(c *gin.Context) {
 	// 当前用户 ID（从 JWT 提取）
 	userID := c.GetUint("user_id")
 	// 从 URL 获取评论 ID
-	commentID := c.Param("comment_id")
+	commentID, ok := paramID(c, "comment_id")
+	if !ok {
+		res.FailCode(c, res.CodeInvalidParams, "无效的评论 ID")
+		return
+	}
 	// 查找评论
-	if err := config.DB.First(&comment, commentID).Error; err != nil {
+	comment, err := h.Comments.FindByID(c.Request.Context(), commentID)
+	if err != nil {
 		// [日志] 记录评论未找到的信息
 		config.Log.WithFields(logrus.Fields{
 			"comment_id": commentID,
@@ -74,22 +242,22 @@ func DeleteComment(c *gin.Context) {
 			"error":      err.Error(),
 		}).Warn("删除评论失败：评论未找到")
 		// 返回错误响应
-		c.JSON(http.StatusNotFound, gin.H{"error": "评论未找到"})
+		res.FailCode(c, res.CodeNotFound, "评论未找到")
 		return
 	}
-	// 检查当前用户是否是评论的作者
-	if comment.UserID != userID {
+	// 评论作者、文章作者和拥有删除任意评论权限的角色（版主、管理员）可以删除
+	if comment.UserID != userID && comment.Post.UserID != userID && !middle.CurrentRole(c).Can(models.PermDeleteAnyComment) {
 		// [日志] 记录无权限删除评论的信息
 		config.Log.WithFields(logrus.Fields{
 			"comment_id": commentID,
 			"user_id":    userID,
 		}).Warn("删除评论失败：无权限删除此评论")
 		// 返回错误响应
-		c.JSON(http.StatusForbidden, gin.H{"error": "无权限删除此评论"})
+		res.FailCode(c, res.CodeForbidden, "无权限删除此评论")
 		return
 	}
-	// 删除评论
-	if err := config.DB.Delete(&comment).Error; err != nil {
+	// 删除评论，有回复的评论会保留为占位符
+	if err := h.Comments.Delete(c.Request.Context(), comment); err != nil {
 		// [日志] 记录删除评论失败的信息
 		config.Log.WithFields(logrus.Fields{
 			"comment_id": commentID,
@@ -97,8 +265,96 @@ func DeleteComment(c *gin.Context) {
 			"error":      err.Error(),
 		}).Error("删除评论失败：数据库错误")
 		// 返回错误响应
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除评论失败"})
+		res.FailCode(c, res.CodeServerError, "删除评论失败")
+		return
+	}
+	h.unindexComment(c, comment.ID)
+	if comment.Status == models.CommentDeleted {
+		res.OkMsg(c, "评论删除成功，回复仍然保留")
+		return
+	}
+	res.OkMsg(c, "评论删除成功")
+}
+
+// ApproveComment 审核通过一条待审核的评论，只有文章作者和拥有审核权限的角色可以操作
+func (h *Handler) ApproveComment(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	commentID, ok := paramID(c, "comment_id")
+	if !ok {
+		res.FailCode(c, res.CodeInvalidParams, "无效的评论 ID")
+		return
+	}
+	comment, err := h.Comments.FindByID(c.Request.Context(), commentID)
+	// 已删除评论的占位符不能被审核恢复
+	if err != nil || comment.Status == models.CommentDeleted {
+		res.FailCode(c, res.CodeNotFound, "评论未找到")
+		return
+	}
+	if !canModerateComments(c, &comment.Post) {
+		// [日志] 记录无权限审核评论的信息
+		config.Log.WithFields(logrus.Fields{
+			"comment_id": commentID,
+			"user_id":    userID,
+		}).Warn("审核评论失败：无权限审核此评论")
+		res.FailCode(c, res.CodeForbidden, "无权限审核此评论")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "评论删除成功"})
+	if err := h.Comments.SetStatus(c.Request.Context(), commentID, models.CommentApproved); err != nil {
+		// [日志] 记录审核评论失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"comment_id": commentID,
+			"user_id":    userID,
+			"error":      err.Error(),
+		}).Error("审核评论失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "审核评论失败")
+		return
+	}
+	pending := comment.Status == models.CommentPending
+	comment.Status = models.CommentApproved
+	h.indexComment(c, &comment.Post, comment)
+	if pending {
+		h.Events.Publish(c.Request.Context(), events.CommentApproved{Comment: *comment, Post: comment.Post})
+	}
+	comment.Post = models.Post{}
+	res.Ok(c, gin.H{"comment": comment}, "评论已通过审核")
+}
+
+// canModerateComments 判断当前用户能否审核文章下的评论：文章作者，或拥有审核任意评论权限的角色
+func canModerateComments(c *gin.Context, post *models.Post) bool {
+	return post.UserID == c.GetUint("user_id") || middle.CurrentRole(c).Can(models.PermModerateAnyComment)
+}
+
+// commentVisibility 返回当前用户在文章下能看到的评论范围：
+// 能审核的人看到全部评论，其他人只能看到已公开的评论和自己的评论
+func commentVisibility(c *gin.Context, post *models.Post) repository.Visibility {
+	if canModerateComments(c, post) {
+		return repository.Visibility{All: true}
+	}
+	return repository.Visibility{ViewerID: c.GetUint("user_id")}
+}
+
+// commentVisibleTo 判断评论在可见范围 v 内，和仓储层的过滤条件一致
+func commentVisibleTo(v repository.Visibility, comment *models.Comment) bool {
+	return v.All || comment.Status == models.CommentApproved || comment.Status == models.CommentDeleted ||
+		(v.ViewerID != 0 && comment.UserID == v.ViewerID)
+}
+
+// redactDeleted 隐藏已删除评论的作者，占位符只保留在评论树中的位置
+func redactDeleted(comment *models.Comment) {
+	if comment.Status == models.CommentDeleted {
+		comment.UserID, comment.User = 0, models.User{}
+	}
+}
+
+// hideInvisibleComments 从预加载的文章评论中去掉当前用户不可见的评论，并隐藏已删除评论的作者
+func hideInvisibleComments(c *gin.Context, post *models.Post) {
+	v := commentVisibility(c, post)
+	visible := post.Comments[:0]
+	for _, comment := range post.Comments {
+		if commentVisibleTo(v, &comment) {
+			redactDeleted(&comment)
+			visible = append(visible, comment)
+		}
+	}
+	post.Comments = visible
 }
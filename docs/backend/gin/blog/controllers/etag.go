@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"blog/config"
+	"blog/models"
+	"blog/repository"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin-demo/res"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// postETag 生成文章的 ETag，格式为 "版本号-摘要"。
+// 版本号只在修改文章本身时递增，用于 If-Match 的并发检查；
+// 摘要覆盖返回给当前用户的全部内容（包括评论、标签），用于 If-None-Match 的缓存校验
+func postETag(post *models.Post) string {
+	data, _ := json.Marshal(post)
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf(`"%d-%s"`, post.Version, hex.EncodeToString(sum[:8]))
+}
+
+// etagVersion 从 ETag 中取出版本号。If-Match 按 RFC 9110 使用强比较，
+// 弱校验的 ETag（W/ 前缀）永远不匹配，返回 false
+func etagVersion(tag string) (uint, bool) {
+	if strings.HasPrefix(tag, "W/") {
+		return 0, false
+	}
+	tag = strings.Trim(tag, `"`)
+	v, _, _ := strings.Cut(tag, "-")
+	n, err := strconv.ParseUint(v, 10, 64)
+	return uint(n), err == nil
+}
+
+// checkIfMatch 检查修改请求的 If-Match 请求头，失败时已经写好响应：
+// 没有带 If-Match 返回 428，版本号和文章当前的版本不一致返回 412。
+// 只比较版本号，新增评论等不改变文章本身的操作不会导致冲突
+func checkIfMatch(c *gin.Context, post *models.Post) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		res.FailCode(c, res.CodePreconditionRequired, "修改文章需要带上 If-Match 请求头，值为获取文章时返回的 ETag")
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if v, ok := etagVersion(tag); ok && v == post.Version {
+			return true
+		}
+	}
+	// [日志] 记录版本冲突的信息
+	config.Log.WithFields(logrus.Fields{
+		"ip":       c.ClientIP(),
+		"user_id":  c.GetUint("user_id"),
+		"post_id":  post.ID,
+		"if_match": header,
+		"version":  post.Version,
+	}).Warn("修改文章失败：版本不一致")
+	res.FailCode(c, res.CodePreconditionFailed, "文章已被修改，请重新获取后再提交")
+	return false
+}
+
+// failStale 在 err 是 repository.ErrStale 时返回 412 并返回 true：
+// If-Match 检查通过之后、保存之前，文章被其他请求修改了
+func failStale(c *gin.Context, err error) bool {
+	if !errors.Is(err, repository.ErrStale) {
+		return false
+	}
+	res.FailCode(c, res.CodePreconditionFailed, "文章已被修改，请重新获取后再提交")
+	return true
+}
+
+// notModified 设置 ETag 响应头，If-None-Match 中有相同的 ETag 时返回 304 并返回 true
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	// 文章对不同用户可见的评论不同，只允许客户端自己缓存，并且每次使用前都要重新校验
+	c.Header("Cache-Control", "private, no-cache")
+	if matchETag(c.GetHeader("If-None-Match"), etag) {
+		c.AbortWithStatus(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// matchETag 判断 If-None-Match 请求头中是否有和 etag 相同的 ETag。
+// 按 RFC 9110 使用弱比较，忽略 W/ 前缀
+func matchETag(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"blog/config"
+	"blog/feed"
+	"blog/models"
+	"blog/repository"
+	"fmt"
+	"gin-demo/res"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// PostFeed 返回输出 format 格式订阅源的处理函数，包含最近发布的 site.feed_size 篇文章。
+// 同一个处理函数用于全站、作者（路径中有 :user_id）和标签（路径中有 :name）三种订阅源
+func (h *Handler) PostFeed(format feed.Format) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		base := h.siteURL(c)
+		f := &feed.Feed{
+			Title:       h.Config.Site.Title,
+			Description: h.Config.Site.Description,
+			Link:        base + "/",
+			FeedURL:     base + c.Request.URL.Path,
+		}
+		// 零值的 Visibility 只返回已发布的文章，订阅源对所有人相同；
+		// 按发布时间取最近的文章，创建得早、发布得晚的定时文章也会出现在订阅源中
+		opts := repository.ListOptions{Size: h.Config.Site.FeedSize, Desc: true, ByPublished: true}
+		if c.Param("user_id") != "" {
+			userID, ok := paramID(c, "user_id")
+			if !ok {
+				res.FailCode(c, res.CodeInvalidParams, "无效的用户 ID")
+				return
+			}
+			author, err := h.Users.FindByID(c.Request.Context(), userID)
+			if err != nil {
+				res.FailCode(c, res.CodeNotFound, "用户未找到")
+				return
+			}
+			opts.AuthorID = userID
+			f.Title = fmt.Sprintf("%s - %s", author.Name, h.Config.Site.Title)
+			f.Link = fmt.Sprintf("%s/u/%d", base, userID)
+		}
+		// 还没有文章的标签返回空的订阅源，而不是 404，读者可以提前订阅
+		if name := c.Param("name"); name != "" {
+			opts.Tag = normalizeTerm(repository.TaxonomyTag, name)
+			f.Title = fmt.Sprintf("#%s - %s", opts.Tag, h.Config.Site.Title)
+		}
+
+		page, err := h.Posts.List(c.Request.Context(), opts)
+		if err != nil {
+			// [日志] 记录生成订阅源失败的信息
+			config.Log.WithFields(logrus.Fields{
+				"path":  c.Request.URL.Path,
+				"error": err.Error(),
+			}).Error("生成订阅源失败：数据库错误")
+			res.FailCode(c, res.CodeServerError, "生成订阅源失败")
+			return
+		}
+		for i := range page.Items {
+			post := &page.Items[i]
+			f.Items = append(f.Items, h.feedItem(base, post))
+			if post.UpdatedAt.After(f.Updated) {
+				f.Updated = post.UpdatedAt
+			}
+		}
+		data, err := feed.Encode(f, format)
+		if err != nil {
+			// [日志] 记录生成订阅源失败的信息
+			config.Log.WithFields(logrus.Fields{
+				"path":  c.Request.URL.Path,
+				"error": err.Error(),
+			}).Error("生成订阅源失败：编码错误")
+			res.FailCode(c, res.CodeServerError, "生成订阅源失败")
+			return
+		}
+		// Last-Modified 取最近更新的文章；文章被删除时它不会变化，但 ETag 会变，
+		// 所以客户端同时带上两个条件时以 If-None-Match 为准
+		if h.publicNotModified(c, data, f.Updated) {
+			return
+		}
+		c.Data(http.StatusOK, format.ContentType(), data)
+	}
+}
+
+// feedItem 把文章转换成订阅源中的一项，链接指向公开的文章页和作者页，不包含作者的邮箱
+func (h *Handler) feedItem(base string, post *models.Post) feed.Item {
+	item := feed.Item{
+		Link:        fmt.Sprintf("%s/p/%d", base, post.ID),
+		Title:       post.Title,
+		ContentHTML: h.RenderPost(post),
+		Author:      feed.Author{Name: post.User.Name, URL: fmt.Sprintf("%s/u/%d", base, post.UserID)},
+		Published:   post.CreatedAt,
+		Updated:     post.UpdatedAt,
+	}
+	if post.PublishedAt != nil {
+		item.Published = *post.PublishedAt
+	}
+	for _, tag := range post.Tags {
+		item.Tags = append(item.Tags, tag.Name)
+	}
+	return item
+}
+
+// siteURL 返回站点对外的地址（不带结尾的 /），没有配置 site.url 时根据请求推断
+func (h *Handler) siteURL(c *gin.Context) string {
+	if h.Config.Site.URL != "" {
+		return strings.TrimRight(h.Config.Site.URL, "/")
+	}
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"blog/config"
+	"blog/events"
+	"blog/models"
+	"blog/repository"
+	"context"
+	"errors"
+	"fmt"
+	"gin-demo/res"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// followEntry 是关注列表中的一项，只返回公开信息，不包含邮箱
+type followEntry struct {
+	ID         uint      `json:"id"`
+	Name       string    `json:"name"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// FollowUser 关注用户，重复关注不会报错
+func (h *Handler) FollowUser(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	followeeID, ok := paramID(c, "user_id")
+	if !ok {
+		res.FailCode(c, res.CodeInvalidParams, "无效的用户 ID")
+		return
+	}
+	if followeeID == userID {
+		res.FailCode(c, res.CodeInvalidParams, "不能关注自己")
+		return
+	}
+	if _, err := h.Users.FindByID(c.Request.Context(), followeeID); err != nil {
+		res.FailCode(c, res.CodeNotFound, "用户未找到")
+		return
+	}
+	created, err := h.Follows.Follow(c.Request.Context(), userID, followeeID)
+	if err != nil {
+		// [日志] 记录关注失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"user_id":     userID,
+			"followee_id": followeeID,
+			"error":       err.Error(),
+		}).Error("关注用户失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "关注失败")
+		return
+	}
+	if created {
+		h.Events.Publish(c.Request.Context(), events.UserFollowed{FollowerID: userID, FolloweeID: followeeID})
+	}
+	res.Ok(c, gin.H{"followee_id": followeeID}, "关注成功")
+}
+
+// UnfollowUser 取消关注，没有关注时返回 404
+func (h *Handler) UnfollowUser(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	followeeID, ok := paramID(c, "user_id")
+	if !ok {
+		res.FailCode(c, res.CodeInvalidParams, "无效的用户 ID")
+		return
+	}
+	if err := h.Follows.Unfollow(c.Request.Context(), userID, followeeID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			res.FailCode(c, res.CodeNotFound, "没有关注该用户")
+			return
+		}
+		// [日志] 记录取消关注失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"user_id":     userID,
+			"followee_id": followeeID,
+			"error":       err.Error(),
+		}).Error("取消关注失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "取消关注失败")
+		return
+	}
+	res.Ok(c, nil, "已取消关注")
+}
+
+// ListFollowers 返回关注该用户的人，默认最近关注的在前
+func (h *Handler) ListFollowers(c *gin.Context) {
+	h.listFollows(c, "followers", h.Follows.Followers, func(f models.Follow) models.User { return f.Follower })
+}
+
+// ListFollowing 返回该用户关注的人，默认最近关注的在前
+func (h *Handler) ListFollowing(c *gin.Context) {
+	h.listFollows(c, "following", h.Follows.Following, func(f models.Follow) models.User { return f.Followee })
+}
+
+// listFollows 是两个关注列表共用的处理逻辑，other 取出列表中要展示的一方
+func (h *Handler) listFollows(c *gin.Context, key string,
+	list func(ctx context.Context, userID uint, opts repository.ListOptions) (*repository.Page[models.Follow], error),
+	other func(models.Follow) models.User) {
+	userID, ok := paramID(c, "user_id")
+	if !ok {
+		res.FailCode(c, res.CodeInvalidParams, "无效的用户 ID")
+		return
+	}
+	opts, err := bindPageOptions(c, true)
+	if err != nil {
+		res.FailBind(c, err)
+		return
+	}
+	if _, err := h.Users.FindByID(c.Request.Context(), userID); err != nil {
+		res.FailCode(c, res.CodeNotFound, "用户未找到")
+		return
+	}
+	page, err := list(c.Request.Context(), userID, opts)
+	if err != nil {
+		// [日志] 记录获取关注列表失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"user_id": userID,
+			"list":    key,
+			"error":   err.Error(),
+		}).Error("获取关注列表失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "获取关注列表失败")
+		return
+	}
+	entries := make([]followEntry, 0, len(page.Items))
+	for _, f := range page.Items {
+		u := other(f)
+		entries = append(entries, followEntry{ID: u.ID, Name: u.Name, FollowedAt: f.CreatedAt})
+	}
+	res.OkData(c, gin.H{key: entries, "pagination": paginationMeta(opts, page)})
+}
+
+// feedUnsupported 是列表接口中时间线不支持的参数，传入时返回 400，而不是悄悄忽略
+var feedUnsupported = []string{"sort", "author_id", "from", "to", "tag", "category", "status"}
+
+// GetFeed 返回当前用户关注的人最近发布的文章（首页时间线），按发布时间倒序。
+// 时间线只支持游标分页：第一页不带参数，之后传上一页返回的 next_cursor；不支持排序和过滤
+func (h *Handler) GetFeed(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	for _, key := range feedUnsupported {
+		if _, ok := c.GetQuery(key); ok {
+			res.FailCode(c, res.CodeInvalidParams, fmt.Sprintf("时间线不支持 %s 参数", key))
+			return
+		}
+	}
+	opts, err := bindPageOptions(c, true)
+	if err != nil {
+		res.FailBind(c, err)
+		return
+	}
+	if opts.Page > 1 {
+		res.FailCode(c, res.CodeInvalidParams, "时间线不支持页码分页，请使用 cursor 参数")
+		return
+	}
+	page, err := h.Posts.Feed(c.Request.Context(), userID, opts)
+	if err != nil {
+		// [日志] 记录获取时间线失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("获取时间线失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "获取时间线失败")
+		return
+	}
+	for i := range page.Items {
+		hideInvisibleComments(c, &page.Items[i])
+	}
+	// 时间线不统计总数，关注的人很多时统计的代价和查询本身差不多
+	res.OkData(c, gin.H{"posts": page.Items, "pagination": gin.H{"size": opts.Size, "next_cursor": page.NextCursor}})
+}
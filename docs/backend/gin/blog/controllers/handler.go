@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"blog/app"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler 持有控制器需要的依赖，所有路由处理函数都是它的方法
+type Handler struct {
+	*app.Container
+}
+
+func NewHandler(c *app.Container) *Handler {
+	return &Handler{Container: c}
+}
+
+// paramID 从 URL 路径参数中解析 uint 类型的 ID
+func paramID(c *gin.Context, key string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(key), 10, 64)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,340 @@
+package controllers
+
+import (
+	"blog/config"
+	"blog/middle"
+	"blog/models"
+	"blog/storage"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gin-demo/res"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// sniffLen 是检测文件类型时读取的字节数，和 http.DetectContentType 一致
+const sniffLen = 512
+
+// mediaExtensions 是检测出的文件类型对应的扩展名，保存的文件名只使用这里的扩展名，不使用上传时的文件名
+var mediaExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"image/bmp":       ".bmp",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+	"video/mp4":       ".mp4",
+	"audio/mpeg":      ".mp3",
+}
+
+// UploadMedia 上传文章的图片或附件，只有文章作者可以上传。
+// 请求为 multipart/form-data，文件放在 file 字段中；文件类型根据内容检测，不信任文件名和客户端声明的类型
+func (h *Handler) UploadMedia(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	post, ok := h.findOwnPost(c, "上传文件")
+	if !ok {
+		return
+	}
+	// 限制整个请求体的大小，multipart 的边界和表单字段额外预留 1MB，文件本身的大小在下面检查
+	maxSize := int64(h.Config.Media.MaxSize) << 20
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			res.FailCode(c, res.CodePayloadTooLarge, fmt.Sprintf("文件不能超过 %d MB", h.Config.Media.MaxSize))
+			return
+		}
+		res.FailCode(c, res.CodeInvalidParams, "请在 file 字段中上传文件")
+		return
+	}
+	if header.Size > maxSize {
+		res.FailCode(c, res.CodePayloadTooLarge, fmt.Sprintf("文件不能超过 %d MB", h.Config.Media.MaxSize))
+		return
+	}
+	if header.Size == 0 {
+		res.FailCode(c, res.CodeInvalidParams, "文件不能为空")
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		res.FailCode(c, res.CodeInvalidParams, "读取上传的文件失败")
+		return
+	}
+	defer file.Close()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		res.FailCode(c, res.CodeInvalidParams, "读取上传的文件失败")
+		return
+	}
+	head = head[:n]
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !slices.Contains(h.Config.Media.AllowedTypes, contentType) {
+		// [日志] 记录上传不支持的文件类型的信息
+		config.Log.WithFields(logrus.Fields{
+			"user_id":      userID,
+			"post_id":      post.ID,
+			"filename":     header.Filename,
+			"content_type": contentType,
+		}).Warn("上传文件失败：不支持的文件类型")
+		res.FailCode(c, res.CodeUnsupportedMediaType, "不支持的文件类型 "+contentType)
+		return
+	}
+
+	media := &models.Media{
+		PostID:      post.ID,
+		UserID:      userID,
+		Key:         newMediaKey() + mediaExtensions[contentType],
+		Filename:    mediaFilename(header.Filename),
+		ContentType: contentType,
+		Size:        header.Size,
+	}
+	// 已经读出的开头部分和剩下的内容拼起来保存
+	body := io.MultiReader(bytes.NewReader(head), file)
+	if err := h.Storage.Put(c.Request.Context(), media.Key, body, media.Size, media.ContentType); err != nil {
+		// [日志] 记录保存文件失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"user_id": userID,
+			"post_id": post.ID,
+			"key":     media.Key,
+			"error":   err.Error(),
+		}).Error("上传文件失败：保存文件失败")
+		res.FailCode(c, res.CodeServerError, "保存文件失败")
+		return
+	}
+	if err := h.Media.Create(c.Request.Context(), media); err != nil {
+		// [日志] 记录保存文件信息失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"user_id": userID,
+			"post_id": post.ID,
+			"key":     media.Key,
+			"error":   err.Error(),
+		}).Error("上传文件失败：数据库错误")
+		// 没有元数据的文件无法访问，直接删除
+		h.deleteStoredMedia(c, media)
+		res.FailCode(c, res.CodeServerError, "保存文件失败")
+		return
+	}
+	setMediaURL(media)
+	res.Ok(c, gin.H{"media": media, "markdown": mediaMarkdown(media)}, "文件上传成功")
+}
+
+// ListPostMedia 返回文章的全部文件，能看到文章的用户都可以查看
+func (h *Handler) ListPostMedia(c *gin.Context) {
+	postID, ok := paramID(c, "post_id")
+	if !ok {
+		res.FailCode(c, res.CodeInvalidParams, "无效的文章 ID")
+		return
+	}
+	post, err := h.Posts.FindByID(c.Request.Context(), postID)
+	if err != nil || !canViewPost(c, post) {
+		res.FailCode(c, res.CodeNotFound, "文章未找到")
+		return
+	}
+	media, err := h.Media.ListByPost(c.Request.Context(), post.ID)
+	if err != nil {
+		// [日志] 记录获取文件列表失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"post_id": post.ID,
+			"error":   err.Error(),
+		}).Error("获取文件列表失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "获取文件列表失败")
+		return
+	}
+	for i := range media {
+		setMediaURL(&media[i])
+	}
+	res.OkData(c, gin.H{"media": media})
+}
+
+// ServeMedia 输出上传的文件。key 是随机生成的，地址本身就是访问凭证，所以不需要认证；
+// 文章被删除后文件随之失效。文件内容不会变化，但只有公开文章的文件允许共享缓存长期保存，
+// 草稿和定时文章的文件仍然可以访问（作者预览需要），每次都要回源验证，文章撤回为草稿后缓存不会继续提供
+func (h *Handler) ServeMedia(c *gin.Context) {
+	key := c.Param("key")
+	if storage.ValidKey(key) != nil {
+		res.FailCode(c, res.CodeNotFound, "文件不存在")
+		return
+	}
+	media, err := h.Media.FindByKey(c.Request.Context(), key)
+	if err != nil {
+		res.FailCode(c, res.CodeNotFound, "文件不存在")
+		return
+	}
+	// 文章删除时会删除文件，这里再检查一次，删除文件失败留下的记录也无法访问
+	post, err := h.Posts.FindByID(c.Request.Context(), media.PostID)
+	if err != nil {
+		res.FailCode(c, res.CodeNotFound, "文件不存在")
+		return
+	}
+	// key 对应的内容不会变化，key 本身就可以作为 ETag
+	etag := `"` + media.Key + `"`
+	cacheControl := "private, no-cache"
+	if post.Status == models.PostPublished || post.Status == models.PostArchived {
+		cacheControl = "public, max-age=31536000, immutable"
+	}
+	cacheHeaders := func() {
+		c.Header("ETag", etag)
+		c.Header("Cache-Control", cacheControl)
+	}
+	if matchETag(c.GetHeader("If-None-Match"), etag) {
+		cacheHeaders()
+		c.Status(http.StatusNotModified)
+		return
+	}
+	file, err := h.Storage.Open(c.Request.Context(), media.Key)
+	if err != nil {
+		// [日志] 记录读取文件失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"media_id": media.ID,
+			"key":      media.Key,
+			"error":    err.Error(),
+		}).Error("读取文件失败：存储错误")
+		if errors.Is(err, storage.ErrNotExist) {
+			res.FailCode(c, res.CodeNotFound, "文件不存在")
+			return
+		}
+		res.FailCode(c, res.CodeServerError, "读取文件失败")
+		return
+	}
+	defer file.Close()
+	// 浏览器只按检测出的类型处理文件；图片直接显示，其他文件一律下载，避免上传的文件在本站的域名下被当作页面执行
+	disposition := "attachment"
+	if media.IsImage() {
+		disposition = "inline"
+	}
+	cacheHeaders()
+	c.DataFromReader(http.StatusOK, media.Size, media.ContentType, file, map[string]string{
+		"X-Content-Type-Options":  "nosniff",
+		"Content-Security-Policy": "default-src 'none'; sandbox",
+		"Content-Disposition":     mime.FormatMediaType(disposition, map[string]string{"filename": media.Filename}),
+	})
+}
+
+// DeleteMedia 删除文件，上传者（也就是文章作者）和拥有删除任意文章权限的角色（管理员）可以删除
+func (h *Handler) DeleteMedia(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	mediaID, ok := paramID(c, "media_id")
+	if !ok {
+		res.FailCode(c, res.CodeInvalidParams, "无效的文件 ID")
+		return
+	}
+	media, err := h.Media.FindByID(c.Request.Context(), mediaID)
+	if err != nil {
+		res.FailCode(c, res.CodeNotFound, "文件不存在")
+		return
+	}
+	if media.UserID != userID && !middle.CurrentRole(c).Can(models.PermDeleteAnyPost) {
+		// [日志] 记录无权限删除文件的信息
+		config.Log.WithFields(logrus.Fields{
+			"media_id": mediaID,
+			"user_id":  userID,
+		}).Warn("删除文件失败：无权限删除此文件")
+		res.FailCode(c, res.CodeForbidden, "无权限删除此文件")
+		return
+	}
+	if err := h.Media.Delete(c.Request.Context(), media.ID); err != nil {
+		// [日志] 记录删除文件失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"media_id": mediaID,
+			"user_id":  userID,
+			"error":    err.Error(),
+		}).Error("删除文件失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "删除文件失败")
+		return
+	}
+	// 先删除元数据，文件立即无法访问；删除文件失败只会留下无法访问的孤儿文件
+	h.deleteStoredMedia(c, media)
+	res.OkMsg(c, "文件删除成功")
+}
+
+// deletePostMedia 删除文章的全部文件，在文章删除之后调用。
+// 文章已经删除，失败时只记录日志：ServeMedia 会检查文章是否存在，留下的文件也无法访问
+func (h *Handler) deletePostMedia(c *gin.Context, postID uint) {
+	media, err := h.Media.ListByPost(c.Request.Context(), postID)
+	if err != nil {
+		// [日志] 记录获取文章的文件失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"post_id": postID,
+			"error":   err.Error(),
+		}).Warn("删除文章的文件失败：数据库错误，文件需要手动清理")
+		return
+	}
+	for i := range media {
+		if err := h.Media.Delete(c.Request.Context(), media[i].ID); err != nil {
+			// [日志] 记录删除文件信息失败的信息
+			config.Log.WithFields(logrus.Fields{
+				"post_id":  postID,
+				"media_id": media[i].ID,
+				"error":    err.Error(),
+			}).Warn("删除文章的文件失败：数据库错误，文件需要手动清理")
+			continue
+		}
+		h.deleteStoredMedia(c, &media[i])
+	}
+}
+
+// deleteStoredMedia 从存储中删除文件，失败时只记录日志
+func (h *Handler) deleteStoredMedia(c *gin.Context, media *models.Media) {
+	if err := h.Storage.Delete(c.Request.Context(), media.Key); err != nil {
+		// [日志] 记录删除存储中的文件失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"media_id": media.ID,
+			"key":      media.Key,
+			"error":    err.Error(),
+		}).Warn("删除文件失败：存储错误，文件需要手动清理")
+	}
+}
+
+// newMediaKey 生成随机的文件 key，无法被猜到
+func newMediaKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// setMediaURL 填充文件的访问地址。使用相对地址，文章内容不依赖站点的域名
+func setMediaURL(media *models.Media) {
+	media.URL = "/media/" + media.Key
+}
+
+// mediaFilename 整理上传时的文件名：去掉路径和控制字符，限制长度，为空时使用 "file"
+func mediaFilename(name string) string {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if runes := []rune(name); len(runes) > 100 {
+		name = string(runes[:100])
+	}
+	if name == "" {
+		return "file"
+	}
+	return name
+}
+
+// mediaMarkdown 返回插入文章内容的 Markdown：图片显示在正文中，其他文件是下载链接
+func mediaMarkdown(media *models.Media) string {
+	text := strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`).Replace(media.Filename)
+	if media.IsImage() {
+		return "![" + text + "](" + media.URL + ")"
+	}
+	return "[" + text + "](" + media.URL + ")"
+}
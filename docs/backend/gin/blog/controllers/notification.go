@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"blog/config"
+	"blog/models"
+	"blog/repository"
+	"errors"
+	"gin-demo/res"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// notificationQuery 是通知列表特有的查询参数
+type notificationQuery struct {
+	Unread bool `form:"unread"` // 为 true 时只返回未读通知
+}
+
+// notificationEntry 是通知列表中的一项，带上触发者的名字，客户端不需要再查询用户
+type notificationEntry struct {
+	models.Notification
+	ActorName string `json:"actor_name"`
+}
+
+func newNotificationEntry(n models.Notification) notificationEntry {
+	return notificationEntry{Notification: n, ActorName: n.Actor.Name}
+}
+
+// ListNotifications 返回当前用户的通知，最新的在前，同时返回未读数
+func (h *Handler) ListNotifications(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	var q notificationQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		res.FailBind(c, err)
+		return
+	}
+	opts, err := bindListOptions(c, true)
+	if err != nil {
+		res.FailBind(c, err)
+		return
+	}
+	page, err := h.Notifications.List(c.Request.Context(), userID, q.Unread, opts)
+	if err != nil {
+		h.failNotifications(c, userID, err)
+		return
+	}
+	unread, err := h.Notifications.UnreadCount(c.Request.Context(), userID)
+	if err != nil {
+		h.failNotifications(c, userID, err)
+		return
+	}
+	entries := make([]notificationEntry, 0, len(page.Items))
+	for _, n := range page.Items {
+		entries = append(entries, newNotificationEntry(n))
+	}
+	res.OkData(c, gin.H{"notifications": entries, "unread_count": unread, "pagination": paginationMeta(opts, page)})
+}
+
+func (h *Handler) failNotifications(c *gin.Context, userID uint, err error) {
+	// [日志] 记录获取通知失败的信息
+	config.Log.WithFields(logrus.Fields{
+		"user_id": userID,
+		"error":   err.Error(),
+	}).Error("获取通知失败：数据库错误")
+	res.FailCode(c, res.CodeServerError, "获取通知失败")
+}
+
+// MarkNotificationRead 把一条通知标记为已读，只能标记自己的通知
+func (h *Handler) MarkNotificationRead(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	id, ok := paramID(c, "notification_id")
+	if !ok {
+		res.FailCode(c, res.CodeInvalidParams, "无效的通知 ID")
+		return
+	}
+	if err := h.Notifications.MarkRead(c.Request.Context(), userID, id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			res.FailCode(c, res.CodeNotFound, "通知未找到")
+			return
+		}
+		// [日志] 记录标记已读失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"user_id":         userID,
+			"notification_id": id,
+			"error":           err.Error(),
+		}).Error("标记通知已读失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "标记已读失败")
+		return
+	}
+	res.OkMsg(c, "已标记为已读")
+}
+
+// MarkAllNotificationsRead 把当前用户的全部未读通知标记为已读
+func (h *Handler) MarkAllNotificationsRead(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	count, err := h.Notifications.MarkAllRead(c.Request.Context(), userID)
+	if err != nil {
+		// [日志] 记录标记已读失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("标记全部通知已读失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "标记已读失败")
+		return
+	}
+	res.Ok(c, gin.H{"marked": count}, "已全部标记为已读")
+}
+
+// StreamNotifications 通过 Server-Sent Events 实时推送新通知，连接一直保持到客户端断开。
+// 连接建立后先发送一次 unread 事件（当前未读数），之后每条新通知是一个 notification 事件，
+// 空闲时按配置的间隔发送注释行作为心跳
+func (h *Handler) StreamNotifications(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	// 先订阅再统计未读数，两者之间产生的通知不会丢失
+	ch, cancel := h.Hub.Subscribe(userID)
+	defer cancel()
+	unread, err := h.Notifications.UnreadCount(c.Request.Context(), userID)
+	if err != nil {
+		h.failNotifications(c, userID, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	// 让 Nginx 等反向代理不要缓冲响应，否则事件会积压到缓冲区满才发出
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("unread", gin.H{"count": unread})
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(time.Duration(h.Config.Notifications.Heartbeat))
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case n := <-ch:
+			c.SSEvent("notification", newNotificationEntry(n))
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		}
+		return true
+	})
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"blog/repository"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pageQuery 是所有列表接口共用的分页和排序参数
+type pageQuery struct {
+	Page   int    `form:"page" binding:"omitempty,min=1"`
+	Size   int    `form:"size" binding:"omitempty,min=1,max=100"`
+	Cursor string `form:"cursor"`
+	Sort   string `form:"sort" binding:"omitempty,oneof=created_at -created_at"`
+}
+
+// listQuery 在分页参数之外还可以按作者和创建时间过滤，用于记录带有作者（user_id）的列表
+// 例如 GET /posts/1/comments?size=10&sort=-created_at&author_id=1&from=2024-01-01&to=2024-01-31&cursor=xxx
+type listQuery struct {
+	pageQuery
+	AuthorID uint   `form:"author_id"`
+	From     string `form:"from"` // RFC3339 时间或 2006-01-02 日期
+	To       string `form:"to"`   // 同上，日期表示包含当天
+}
+
+// postListQuery 是文章列表的查询参数，还可以按标签、分类和状态过滤
+// 例如 GET /posts?author_id=1&tag=go&status=published
+type postListQuery struct {
+	listQuery
+	Tag      string `form:"tag"`
+	Category string `form:"category"`
+	Status   string `form:"status" binding:"omitempty,oneof=draft scheduled published archived"`
+}
+
+// bindPageOptions 只解析分页和排序参数，用于没有作者等过滤条件的列表
+func bindPageOptions(c *gin.Context, desc bool) (repository.ListOptions, error) {
+	var q pageQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		return repository.ListOptions{}, err
+	}
+	return q.options(desc)
+}
+
+// bindListOptions 解析分页、排序以及按作者和时间过滤的参数
+func bindListOptions(c *gin.Context, desc bool) (repository.ListOptions, error) {
+	var q listQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		return repository.ListOptions{}, err
+	}
+	return q.options(desc)
+}
+
+// bindPostListOptions 解析文章列表的全部参数
+func bindPostListOptions(c *gin.Context, desc bool) (repository.ListOptions, error) {
+	var q postListQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		return repository.ListOptions{}, err
+	}
+	return q.options(desc)
+}
+
+func (q pageQuery) options(desc bool) (repository.ListOptions, error) {
+	opts := repository.ListOptions{Page: q.Page, Size: q.Size, Desc: desc}
+	switch q.Sort {
+	case "created_at":
+		opts.Desc = false
+	case "-created_at":
+		opts.Desc = true
+	}
+	if q.Cursor != "" {
+		cursor, err := repository.DecodeCursor(q.Cursor)
+		if err != nil {
+			return opts, err
+		}
+		opts.Cursor = cursor
+	}
+	return opts.Normalize(), nil
+}
+
+func (q listQuery) options(desc bool) (repository.ListOptions, error) {
+	opts, err := q.pageQuery.options(desc)
+	if err != nil {
+		return opts, err
+	}
+	opts.AuthorID = q.AuthorID
+	if opts.From, err = parseTimeParam(q.From, false); err != nil {
+		return opts, fmt.Errorf("from 参数格式错误: %w", err)
+	}
+	if opts.To, err = parseTimeParam(q.To, true); err != nil {
+		return opts, fmt.Errorf("to 参数格式错误: %w", err)
+	}
+	if opts.From != nil && opts.To != nil && !opts.From.Before(*opts.To) {
+		return opts, errors.New("from 必须早于 to")
+	}
+	return opts, nil
+}
+
+func (q postListQuery) options(desc bool) (repository.ListOptions, error) {
+	opts, err := q.listQuery.options(desc)
+	if err != nil {
+		return opts, err
+	}
+	opts.Tag = normalizeTerm(repository.TaxonomyTag, q.Tag)
+	opts.Category = normalizeTerm(repository.TaxonomyCategory, q.Category)
+	opts.Status = q.Status
+	return opts, nil
+}
+
+// parseTimeParam 解析 RFC3339 时间或日期，endOfDay 为 true 时日期会被转换为第二天零点（用作开区间的上界）
+func parseTimeParam(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return nil, errors.New("应为 RFC3339 时间或 YYYY-MM-DD 日期")
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// paginationMeta 生成列表响应中的分页信息
+func paginationMeta[T any](opts repository.ListOptions, page *repository.Page[T]) gin.H {
+	meta := gin.H{
+		"total":       page.Total,
+		"size":        opts.Size,
+		"next_cursor": page.NextCursor,
+	}
+	// 游标分页时页码没有意义
+	if opts.Cursor == nil {
+		meta["page"] = opts.Page
+	}
+	return meta
+}
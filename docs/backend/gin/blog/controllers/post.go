@@ -2,19 +2,43 @@ package controllers
 
 import (
 	"blog/config"
+	"blog/middle"
 	"blog/models"
-	"net/http"
+	"blog/repository"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin-demo/res"
+	"reflect"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 	"github.com/sirupsen/logrus"
 )
 
-func CreatePost(c *gin.Context) {
-	var post models.Post
+// newPostInput 是 POST /posts 的请求体，只包含客户端可以设置的字段。
+// 作者、发布时间和点赞数等由服务端决定，不能直接绑定 models.Post：
+// 请求体中嵌套的 User、Comments 会被 GORM 当作关联一起写入数据库
+type newPostInput struct {
+	Title       string     `json:"title" binding:"required,max=100"`
+	Content     string     `json:"content" binding:"required,max=100000"`
+	CommentMode string     `json:"comment_mode" binding:"omitempty,oneof=auto review"`
+	Format      string     `json:"format" binding:"omitempty,oneof=plain markdown"`
+	Status      string     `json:"status" binding:"omitempty,oneof=draft scheduled published archived"`
+	PublishAt   *time.Time `json:"publish_at"` // 定时发布的时间，只传 publish_at 表示定时发布
+	Tags        []string   `json:"tags" binding:"omitempty,max=10,dive,required,max=30"`
+	Categories  []string   `json:"categories" binding:"omitempty,max=10,dive,required,max=30"`
+}
+
+func (h *Handler) CreatePost(c *gin.Context) {
+	var input newPostInput
 	// 获取用户ID
 	userID := c.GetUint("user_id")
-	// 绑定 JSON 数据到 post 结构体(传入文章)
-	if err := c.ShouldBindJSON(&post); err != nil {
+	// 绑定 JSON 数据到输入结构体
+	if err := c.ShouldBindJSON(&input); err != nil {
 		// [日志] 记录参数绑定失败的信息
 		config.Log.WithFields(logrus.Fields{
 			"ip":      c.ClientIP(),
@@ -22,12 +46,36 @@ func CreatePost(c *gin.Context) {
 			"error":   err.Error(),
 		}).Warn("创建文章失败：参数格式错误")
 		// 返回错误响应
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		res.FailBind(c, err)
+		return
+	}
+	post := models.Post{
+		Title:       input.Title,
+		Content:     input.Content,
+		CommentMode: input.CommentMode,
+		Format:      input.Format,
+		Status:      input.Status,
+		UserID:      userID,
+	}
+	if post.CommentMode == "" {
+		post.CommentMode = models.CommentModeAuto
+	}
+	if post.Format == "" {
+		post.Format = models.FormatPlain
+	}
+	// 没有指定状态时：带 publish_at 表示定时发布，否则立即发布
+	if post.Status == "" {
+		post.Status = models.PostPublished
+		if input.PublishAt != nil {
+			post.Status = models.PostScheduled
+		}
+	}
+	if err := applyStatus(&post, post.Status, input.PublishAt, time.Now()); err != nil {
+		res.FailCode(c, res.CodeInvalidParams, err.Error())
 		return
 	}
-	post.UserID = userID
 	// 保存文章到数据库
-	if err := config.DB.Create(&post).Error; err != nil {
+	if err := h.Posts.Create(c.Request.Context(), &post); err != nil {
 		// [日志] 记录文章创建失败的信息
 		config.Log.WithFields(logrus.Fields{
 			"post_id": post.ID,
@@ -35,15 +83,56 @@ func CreatePost(c *gin.Context) {
 			"error":   err.Error(),
 		}).Error("创建文章失败：数据库错误")
 		// 返回错误响应
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建文章失败"})
+		res.FailCode(c, res.CodeServerError, "创建文章失败")
+		return
+	}
+	h.indexPost(c, &post)
+	if len(input.Tags) == 0 && len(input.Categories) == 0 {
+		res.Ok(c, gin.H{"post": post}, "文章创建成功")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "文章创建成功", "post": post})
+	// 标签和分类在文章保存之后添加，和 POST /posts/:post_id/tags 一样，不存在的名称会自动创建
+	terms := map[repository.Taxonomy][]string{
+		repository.TaxonomyTag:      input.Tags,
+		repository.TaxonomyCategory: input.Categories,
+	}
+	for _, t := range []repository.Taxonomy{repository.TaxonomyTag, repository.TaxonomyCategory} {
+		names := normalizeTerms(t, terms[t])
+		if len(names) == 0 {
+			continue
+		}
+		if err := h.taxonomy(t).Attach(c.Request.Context(), post.ID, names); err != nil {
+			// [日志] 记录添加失败的信息
+			config.Log.WithFields(logrus.Fields{
+				"user_id": userID,
+				"post_id": post.ID,
+				"names":   names,
+				"error":   err.Error(),
+			}).Error("创建文章失败：添加" + termLabel(t) + "失败")
+			res.FailCode(c, res.CodeServerError, fmt.Sprintf("文章已创建（ID %d），但添加%s失败", post.ID, termLabel(t)))
+			return
+		}
+	}
+	h.respondPost(c, post.ID, "文章创建成功")
 }
 
-func GetAllPosts(c *gin.Context) {
-	var posts []models.Post
-	if err := config.DB.Preload("User").Preload("Comments").Find(&posts).Error; err != nil {
+func (h *Handler) GetAllPosts(c *gin.Context) {
+	// 解析分页、排序和过滤参数，默认最新的文章在前
+	opts, err := bindPostListOptions(c, true)
+	if err != nil {
+		// [日志] 记录参数解析失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"ip":    c.ClientIP(),
+			"query": c.Request.URL.RawQuery,
+			"error": err.Error(),
+		}).Warn("获取文章列表失败：参数格式错误")
+		// 返回错误响应
+		res.FailBind(c, err)
+		return
+	}
+	opts.Visibility = postVisibility(c)
+	page, err := h.Posts.List(c.Request.Context(), opts)
+	if err != nil {
 		// [日志] 记录获取文章列表失败的信息
 		config.Log.WithFields(logrus.Fields{
 			"ip":      c.ClientIP(),
@@ -51,16 +140,23 @@ func GetAllPosts(c *gin.Context) {
 			"error":   err.Error(),
 		}).Error("获取文章列表失败：数据库错误")
 		// 返回错误响应
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文章列表失败"})
+		res.FailCode(c, res.CodeServerError, "获取文章列表失败")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"posts": posts})
+	for i := range page.Items {
+		hideInvisibleComments(c, &page.Items[i])
+	}
+	res.OkData(c, gin.H{"posts": page.Items, "pagination": paginationMeta(opts, page)})
 }
 
-func GetPostByID(c *gin.Context) {
-	var post models.Post
-	postID := c.Param("post_id")
-	if err := config.DB.Preload("User").Preload("Comments").First(&post, postID).Error; err != nil {
+func (h *Handler) GetPostByID(c *gin.Context) {
+	postID, ok := paramID(c, "post_id")
+	if !ok {
+		res.FailCode(c, res.CodeInvalidParams, "无效的文章 ID")
+		return
+	}
+	post, err := h.Posts.FindByID(c.Request.Context(), postID)
+	if err != nil {
 		// [日志] 记录获取文章失败的信息
 		config.Log.WithFields(logrus.Fields{
 			"ip":      c.ClientIP(),
@@ -68,16 +164,46 @@ func GetPostByID(c *gin.Context) {
 			"error":   err.Error(),
 		}).Error("获取文章失败：文章未找到")
 		// 返回错误响应
-		c.JSON(http.StatusNotFound, gin.H{"error": "文章未找到"})
+		res.FailCode(c, res.CodeNotFound, "文章未找到")
+		return
+	}
+	// 草稿和未到发布时间的文章对其他用户来说不存在
+	if !canViewPost(c, post) {
+		res.FailCode(c, res.CodeNotFound, "文章未找到")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"post": post})
+	hideInvisibleComments(c, post)
+	// render=html 时额外返回渲染后的 HTML，ETag 覆盖它，两种表示的缓存互不影响
+	switch c.Query("render") {
+	case "":
+	case "html":
+		post.ContentHTML = h.RenderPost(post)
+	default:
+		res.FailCode(c, res.CodeInvalidParams, "render 参数只能是 html")
+		return
+	}
+	if notModified(c, postETag(post)) {
+		return
+	}
+	res.OkData(c, gin.H{"post": post})
 }
 
-func GetPostsByUser(c *gin.Context) {
-	var posts []models.Post
-	userID := c.Param("user_id")
-	if err := config.DB.Preload("User").Preload("Comments").Where("user_id = ?", userID).Find(&posts).Error; err != nil {
+func (h *Handler) GetPostsByUser(c *gin.Context) {
+	userID, ok := paramID(c, "user_id")
+	if !ok {
+		res.FailCode(c, res.CodeInvalidParams, "无效的用户 ID")
+		return
+	}
+	opts, err := bindPostListOptions(c, true)
+	if err != nil {
+		res.FailBind(c, err)
+		return
+	}
+	// 路径中的用户 ID 优先于 author_id 参数
+	opts.AuthorID = userID
+	opts.Visibility = postVisibility(c)
+	page, err := h.Posts.List(c.Request.Context(), opts)
+	if err != nil {
 		// [日志] 记录获取文章失败的信息
 		config.Log.WithFields(logrus.Fields{
 			"ip":      c.ClientIP(),
@@ -85,19 +211,66 @@ func GetPostsByUser(c *gin.Context) {
 			"error":   err.Error(),
 		}).Error("获取文章失败：文章未找到")
 		// 返回错误响应
-		c.JSON(http.StatusNotFound, gin.H{"error": "用户未找到"})
+		res.FailCode(c, res.CodeNotFound, "用户未找到")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"posts": posts})
+	for i := range page.Items {
+		hideInvisibleComments(c, &page.Items[i])
+	}
+	res.OkData(c, gin.H{"posts": page.Items, "pagination": paginationMeta(opts, page)})
+}
+
+// postInput 是 PUT /posts/:post_id 的请求体。PUT 替换整篇文章，标题和内容都必须提供，
+// 避免只传标题时把内容清空
+type postInput struct {
+	Title       string  `json:"title" binding:"required,max=100"`
+	Content     string  `json:"content" binding:"required,max=100000"`
+	CommentMode *string `json:"comment_mode" binding:"omitempty,oneof=auto review"` // 不传时保持不变
+	Format      *string `json:"format" binding:"omitempty,oneof=plain markdown"`    // 不传时保持不变
+}
+
+// postPatch 是 PATCH /posts/:post_id 可以修改的字段。请求体中出现的字段才会被校验和修改，
+// 所以这里的 required 表示字段出现时不能为空或 null
+type postPatch struct {
+	Title       string `json:"title" binding:"required,max=100"`
+	Content     string `json:"content" binding:"required,max=100000"`
+	CommentMode string `json:"comment_mode" binding:"required,oneof=auto review"`
+	Format      string `json:"format" binding:"required,oneof=plain markdown"`
+}
+
+// validatePatch 只校验 mask 中的字段。gin 的校验器被替换成其他实现时无法只校验部分字段，
+// 这时用文章当前的值补齐没有修改的字段，再校验整个结构体
+func validatePatch(patch *postPatch, post *models.Post, mask []string) error {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		return v.StructPartial(patch, mask...)
+	}
+	full := postPatch{Title: post.Title, Content: post.Content, CommentMode: post.CommentMode, Format: post.Format}
+	src, dst := reflect.ValueOf(patch).Elem(), reflect.ValueOf(&full).Elem()
+	for _, field := range mask {
+		dst.FieldByName(field).Set(src.FieldByName(field))
+	}
+	return binding.Validator.ValidateStruct(&full)
+}
+
+// patchFields 是请求体中的字段名到 postPatch 字段名的映射，也就是 PATCH 允许修改的字段
+var patchFields = map[string]string{
+	"title":        "Title",
+	"content":      "Content",
+	"comment_mode": "CommentMode",
+	"format":       "Format",
 }
 
-func UpdatePost(c *gin.Context) {
-	var post models.Post
+func (h *Handler) UpdatePost(c *gin.Context) {
 	// 当前用户 ID（从 JWT 提取）
 	userID := c.GetUint("user_id")
 	// 从 URL 获取文章 ID
-	postID := c.Param("post_id")
-	if err := config.DB.First(&post, postID).Error; err != nil {
+	postID, ok := paramID(c, "post_id")
+	if !ok {
+		res.FailCode(c, res.CodeInvalidParams, "无效的文章 ID")
+		return
+	}
+	post, err := h.Posts.FindByID(c.Request.Context(), postID)
+	if err != nil {
 		// [日志] 记录获取文章失败的信息
 		config.Log.WithFields(logrus.Fields{
 			"ip":      c.ClientIP(),
@@ -105,7 +278,7 @@ func UpdatePost(c *gin.Context) {
 			"error":   err.Error(),
 		}).Error("获取文章失败：文章未找到")
 		// 返回错误响应
-		c.JSON(http.StatusNotFound, gin.H{"error": "文章未找到"})
+		res.FailCode(c, res.CodeNotFound, "文章未找到")
 		return
 	}
 	// 判断当前用户是否为文章的作者
@@ -116,14 +289,14 @@ func UpdatePost(c *gin.Context) {
 			"user_id": userID,
 		}).Error("获取文章失败：没有权限更新此文章")
 		// 返回错误响应
-		c.JSON(http.StatusForbidden, gin.H{"error": "没有权限更新此文章"})
+		res.FailCode(c, res.CodeForbidden, "没有权限更新此文章")
 		return
 	}
-	// 绑定更新数据
-	var input struct {
-		Title   string `json:"title"`
-		Content string `json:"content"`
+	if !checkIfMatch(c, post) {
+		return
 	}
+	// 绑定更新数据
+	var input postInput
 	// 绑定 JSON 数据到输入结构体
 	if err := c.ShouldBindJSON(&input); err != nil {
 		// [日志] 记录参数绑定失败的信息
@@ -133,34 +306,124 @@ func UpdatePost(c *gin.Context) {
 			"error":   err.Error(),
 		}).Warn("更新文章失败：参数格式错误")
 		// 返回错误响应
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		res.FailBind(c, err)
 		return
 	}
 	// 更新文章字段
 	post.Title = input.Title
 	post.Content = input.Content
-	if err := config.DB.Save(&post).Error; err != nil {
-		// [日志] 记录参数绑定失败的信息
+	if input.CommentMode != nil {
+		post.CommentMode = *input.CommentMode
+	}
+	if input.Format != nil {
+		post.Format = *input.Format
+	}
+	h.savePost(c, post, "文章更新成功")
+}
+
+// PatchPost 按 JSON Merge Patch（RFC 7386）部分更新文章：只修改请求体中出现的字段，
+// 没有出现的字段保持不变。例如 {"title": "新标题"} 只修改标题
+func (h *Handler) PatchPost(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	post, ok := h.findOwnPost(c, "更新文章")
+	if !ok || !checkIfMatch(c, post) {
+		return
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		res.FailCode(c, res.CodeInvalidParams, "读取请求体失败")
+		return
+	}
+	// 先解析出请求体中有哪些字段，作为这次修改的字段掩码
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			res.FailCode(c, res.CodeInvalidParams, "请求体必须是 JSON 对象")
+			return
+		}
+		res.FailBind(c, err)
+		return
+	}
+	mask := make([]string, 0, len(raw))
+	for key := range raw {
+		// 和 PUT 一样，字段名不区分大小写
+		field, ok := patchFields[strings.ToLower(key)]
+		if !ok {
+			res.FailCode(c, res.CodeInvalidParams, fmt.Sprintf("不支持修改字段 %s", key))
+			return
+		}
+		mask = append(mask, field)
+	}
+	if len(mask) == 0 {
+		res.FailCode(c, res.CodeInvalidParams, "没有需要修改的字段")
+		return
+	}
+	var patch postPatch
+	if err := json.Unmarshal(body, &patch); err != nil {
+		res.FailBind(c, err)
+		return
+	}
+	// 只校验出现的字段；值为 null 时解析成空字符串，会被 required 拒绝
+	if err := validatePatch(&patch, post, mask); err != nil {
+		// [日志] 记录参数校验失败的信息
 		config.Log.WithFields(logrus.Fields{
 			"ip":      c.ClientIP(),
 			"user_id": userID,
-			"post_id": postID,
+			"post_id": post.ID,
+			"error":   err.Error(),
+		}).Warn("更新文章失败：参数格式错误")
+		res.FailBind(c, err)
+		return
+	}
+	for _, field := range mask {
+		switch field {
+		case "Title":
+			post.Title = patch.Title
+		case "Content":
+			post.Content = patch.Content
+		case "CommentMode":
+			post.CommentMode = patch.CommentMode
+		case "Format":
+			post.Format = patch.Format
+		}
+	}
+	h.savePost(c, post, "文章更新成功")
+}
+
+// savePost 保存修改后的文章，并同步搜索索引、返回新的 ETag，失败时已经写好响应
+func (h *Handler) savePost(c *gin.Context, post *models.Post, message string) {
+	if err := h.Posts.Update(c.Request.Context(), post); err != nil {
+		if failStale(c, err) {
+			return
+		}
+		// [日志] 记录更新文章失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"ip":      c.ClientIP(),
+			"user_id": c.GetUint("user_id"),
+			"post_id": post.ID,
 			"error":   err.Error(),
 		}).Warn("更新文章失败：数据库错误")
-		// 返回错误响应
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新文章失败"})
+		res.FailCode(c, res.CodeServerError, "更新文章失败")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "文章更新成功", "post": post})
+	h.indexPost(c, post)
+	hideInvisibleComments(c, post)
+	c.Header("ETag", postETag(post))
+	res.Ok(c, gin.H{"post": post}, message)
 }
 
-func DeletePost(c *gin.Context) {
-	var post models.Post
-	// 从 URL 获取文章 ID
-	userID := c.GetUint("user_id")
+func (h *Handler) DeletePost(c *gin.Context) {
 	// 当前用户 ID（从 JWT 提取）
-	postID := c.Param("post_id")
-	if err := config.DB.First(&post, postID).Error; err != nil {
+	userID := c.GetUint("user_id")
+	// 从 URL 获取文章 ID
+	postID, ok := paramID(c, "post_id")
+	if !ok {
+		res.FailCode(c, res.CodeInvalidParams, "无效的文章 ID")
+		return
+	}
+	post, err := h.Posts.FindByID(c.Request.Context(), postID)
+	if err != nil {
 		// [日志] 记录获取文章失败的信息
 		config.Log.WithFields(logrus.Fields{
 			"ip":      c.ClientIP(),
@@ -169,11 +432,11 @@ func DeletePost(c *gin.Context) {
 			"error":   err.Error(),
 		}).Error("获取文章失败：文章未找到")
 		// 返回错误响应
-		c.JSON(http.StatusNotFound, gin.H{"error": "文章未找到"})
+		res.FailCode(c, res.CodeNotFound, "文章未找到")
 		return
 	}
-	// 判断当前用户是否为文章的作者
-	if post.UserID != userID {
+	// 只有文章作者和拥有删除任意文章权限的角色（管理员）可以删除
+	if post.UserID != userID && !middle.CurrentRole(c).Can(models.PermDeleteAnyPost) {
 		// [日志] 记录获取文章失败的信息
 		config.Log.WithFields(logrus.Fields{
 			"ip":      c.ClientIP(),
@@ -181,11 +444,11 @@ func DeletePost(c *gin.Context) {
 			"post_id": postID,
 		}).Error("获取文章失败：没有权限删除此文章")
 		// 返回错误响应
-		c.JSON(http.StatusForbidden, gin.H{"error": "没有权限删除此文章"})
+		res.FailCode(c, res.CodeForbidden, "没有权限删除此文章")
 		return
 	}
 	// 删除文章
-	if err := config.DB.Delete(&post).Error; err != nil {
+	if err := h.Posts.Delete(c.Request.Context(), post); err != nil {
 		// [日志] 记录参数绑定失败的信息
 		config.Log.WithFields(logrus.Fields{
 			"ip":      c.ClientIP(),
@@ -194,8 +457,107 @@ func DeletePost(c *gin.Context) {
 			"error":   err.Error(),
 		}).Warn("删除文章失败：数据库错误")
 		// 返回错误响应
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除文章失败"})
+		res.FailCode(c, res.CodeServerError, "删除文章失败")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "文章删除成功"})
+	h.unindexPost(c, post.ID)
+	h.deletePostMedia(c, post.ID)
+	res.OkMsg(c, "文章删除成功")
+}
+
+// SetPostStatus 修改文章的发布状态（草稿、定时发布、发布、归档），只有文章作者可以操作
+func (h *Handler) SetPostStatus(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	post, ok := h.findOwnPost(c, "修改文章状态")
+	if !ok || !checkIfMatch(c, post) {
+		return
+	}
+	postID := post.ID
+	var input struct {
+		Status    string     `json:"status" binding:"required,oneof=draft scheduled published archived"`
+		PublishAt *time.Time `json:"publish_at"` // status 为 scheduled 时必填
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		res.FailBind(c, err)
+		return
+	}
+	if err := applyStatus(post, input.Status, input.PublishAt, time.Now()); err != nil {
+		res.FailCode(c, res.CodeInvalidParams, err.Error())
+		return
+	}
+	if err := h.Posts.Update(c.Request.Context(), post); err != nil {
+		if failStale(c, err) {
+			return
+		}
+		// [日志] 记录修改文章状态失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"user_id": userID,
+			"post_id": postID,
+			"error":   err.Error(),
+		}).Error("修改文章状态失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "修改文章状态失败")
+		return
+	}
+	h.indexPost(c, post)
+	hideInvisibleComments(c, post)
+	c.Header("ETag", postETag(post))
+	res.Ok(c, gin.H{"post": post}, "文章状态已更新")
+}
+
+// findOwnPost 读取路径中的文章，并检查当前用户是文章作者，失败时已经写好响应。
+// action 用于日志，例如 "修改文章状态"
+func (h *Handler) findOwnPost(c *gin.Context, action string) (*models.Post, bool) {
+	userID := c.GetUint("user_id")
+	postID, ok := paramID(c, "post_id")
+	if !ok {
+		res.FailCode(c, res.CodeInvalidParams, "无效的文章 ID")
+		return nil, false
+	}
+	post, err := h.Posts.FindByID(c.Request.Context(), postID)
+	if err != nil || !canViewPost(c, post) {
+		res.FailCode(c, res.CodeNotFound, "文章未找到")
+		return nil, false
+	}
+	if post.UserID != userID {
+		// [日志] 记录无权限操作文章的信息
+		config.Log.WithFields(logrus.Fields{
+			"ip":      c.ClientIP(),
+			"user_id": userID,
+			"post_id": postID,
+		}).Warn(action + "失败：没有权限修改此文章")
+		res.FailCode(c, res.CodeForbidden, "没有权限修改此文章")
+		return nil, false
+	}
+	return post, true
+}
+
+// applyStatus 按发布流程修改文章状态：定时发布必须指定晚于 now 的 publishAt，
+// 发布时记录第一次发布的时间，其他状态会清除定时发布时间
+func applyStatus(post *models.Post, status string, publishAt *time.Time, now time.Time) error {
+	switch status {
+	case models.PostScheduled:
+		if publishAt == nil || !publishAt.After(now) {
+			return errors.New("定时发布需要指定晚于当前时间的 publish_at")
+		}
+		post.PublishAt = publishAt
+	case models.PostPublished:
+		post.PublishAt = nil
+		if post.PublishedAt == nil {
+			post.PublishedAt = &now
+		}
+	default:
+		post.PublishAt = nil
+	}
+	post.Status = status
+	return nil
+}
+
+// canViewPost 判断当前用户能否查看文章：已发布和已归档的文章所有人可见，草稿和定时发布的文章只有作者可见
+func canViewPost(c *gin.Context, post *models.Post) bool {
+	return post.Status == models.PostPublished || post.Status == models.PostArchived || post.UserID == c.GetUint("user_id")
+}
+
+// postVisibility 返回当前用户在文章列表中能看到的范围：已发布的文章和自己的全部文章
+func postVisibility(c *gin.Context) repository.Visibility {
+	return repository.Visibility{ViewerID: c.GetUint("user_id")}
 }
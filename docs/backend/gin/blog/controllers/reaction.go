@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"blog/config"
+	"blog/models"
+	"blog/repository"
+	"errors"
+	"gin-demo/res"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// reactionInput 是设置反应的请求体，例如 {"kind": "like"} 或 {"kind": "🎉"}
+type reactionInput struct {
+	Kind string `json:"kind" binding:"required,max=20"`
+}
+
+// 文章和评论的反应处理逻辑相同，下面的导出方法只是指定目标类型
+
+func (h *Handler) ReactToPost(c *gin.Context)      { h.react(c, models.ReactionTargetPost) }
+func (h *Handler) UnreactPost(c *gin.Context)      { h.unreact(c, models.ReactionTargetPost) }
+func (h *Handler) GetPostReactions(c *gin.Context) { h.getReactions(c, models.ReactionTargetPost) }
+func (h *Handler) ReactToComment(c *gin.Context)   { h.react(c, models.ReactionTargetComment) }
+func (h *Handler) UnreactComment(c *gin.Context)   { h.unreact(c, models.ReactionTargetComment) }
+func (h *Handler) GetCommentReactions(c *gin.Context) {
+	h.getReactions(c, models.ReactionTargetComment)
+}
+
+// findReactionTarget 读取路径中的文章或评论，并检查当前用户能看到它，失败时已经写好响应。
+// 已删除评论的占位符不能再做出反应
+func (h *Handler) findReactionTarget(c *gin.Context, targetType string) (uint, bool) {
+	if targetType == models.ReactionTargetPost {
+		postID, ok := paramID(c, "post_id")
+		if !ok {
+			res.FailCode(c, res.CodeInvalidParams, "无效的文章 ID")
+			return 0, false
+		}
+		post, err := h.Posts.FindByID(c.Request.Context(), postID)
+		if err != nil || !canViewPost(c, post) {
+			res.FailCode(c, res.CodeNotFound, "文章未找到")
+			return 0, false
+		}
+		return post.ID, true
+	}
+	commentID, ok := paramID(c, "comment_id")
+	if !ok {
+		res.FailCode(c, res.CodeInvalidParams, "无效的评论 ID")
+		return 0, false
+	}
+	comment, err := h.Comments.FindByID(c.Request.Context(), commentID)
+	if err != nil || comment.Status == models.CommentDeleted || !canViewPost(c, &comment.Post) ||
+		!commentVisibleTo(commentVisibility(c, &comment.Post), comment) {
+		res.FailCode(c, res.CodeNotFound, "评论未找到")
+		return 0, false
+	}
+	return comment.ID, true
+}
+
+func (h *Handler) react(c *gin.Context, targetType string) {
+	userID := c.GetUint("user_id")
+	targetID, ok := h.findReactionTarget(c, targetType)
+	if !ok {
+		return
+	}
+	var input reactionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		res.FailBind(c, err)
+		return
+	}
+	if !h.Config.Reactions.Allowed(input.Kind) {
+		res.FailCode(c, res.CodeInvalidParams, "不支持的反应："+input.Kind)
+		return
+	}
+	reaction := models.Reaction{UserID: userID, TargetType: targetType, TargetID: targetID, Kind: input.Kind}
+	if err := h.Reactions.Set(c.Request.Context(), &reaction); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			res.FailCode(c, res.CodeConflict, "请勿重复提交")
+			return
+		}
+		// [日志] 记录设置反应失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"user_id":     userID,
+			"target_type": targetType,
+			"target_id":   targetID,
+			"error":       err.Error(),
+		}).Error("设置反应失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "设置反应失败")
+		return
+	}
+	h.respondReactions(c, targetType, targetID, "已做出反应")
+}
+
+func (h *Handler) unreact(c *gin.Context, targetType string) {
+	userID := c.GetUint("user_id")
+	targetID, ok := h.findReactionTarget(c, targetType)
+	if !ok {
+		return
+	}
+	if err := h.Reactions.Remove(c.Request.Context(), userID, targetType, targetID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			res.FailCode(c, res.CodeNotFound, "还没有做出反应")
+			return
+		}
+		// [日志] 记录取消反应失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"user_id":     userID,
+			"target_type": targetType,
+			"target_id":   targetID,
+			"error":       err.Error(),
+		}).Error("取消反应失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "取消反应失败")
+		return
+	}
+	h.respondReactions(c, targetType, targetID, "已取消反应")
+}
+
+func (h *Handler) getReactions(c *gin.Context, targetType string) {
+	targetID, ok := h.findReactionTarget(c, targetType)
+	if !ok {
+		return
+	}
+	h.respondReactions(c, targetType, targetID, "")
+}
+
+// respondReactions 返回目标上的反应统计和当前用户的反应
+func (h *Handler) respondReactions(c *gin.Context, targetType string, targetID uint, message string) {
+	summary, err := h.Reactions.Summary(c.Request.Context(), targetType, targetID, c.GetUint("user_id"))
+	if err != nil {
+		// [日志] 记录统计反应失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"target_type": targetType,
+			"target_id":   targetID,
+			"error":       err.Error(),
+		}).Error("获取反应失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "获取反应失败")
+		return
+	}
+	if message == "" {
+		res.OkData(c, gin.H{"reactions": summary})
+		return
+	}
+	res.Ok(c, gin.H{"reactions": summary}, message)
+}
+
+// GetMostLikedPosts 按点赞数从多到少返回已发布的文章，支持和文章列表相同的过滤参数，
+// 例如 GET /posts/most-liked?from=2024-01-01 查看某段时间内发布的最受欢迎的文章
+func (h *Handler) GetMostLikedPosts(c *gin.Context) {
+	opts, err := bindPostListOptions(c, true)
+	if err != nil {
+		res.FailBind(c, err)
+		return
+	}
+	if opts.Cursor != nil {
+		res.FailCode(c, res.CodeInvalidParams, "排行榜不支持游标分页，请使用 page 参数")
+		return
+	}
+	page, err := h.Posts.MostLiked(c.Request.Context(), opts)
+	if err != nil {
+		// [日志] 记录获取排行榜失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"ip":    c.ClientIP(),
+			"error": err.Error(),
+		}).Error("获取最受欢迎的文章失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "获取文章列表失败")
+		return
+	}
+	for i := range page.Items {
+		hideInvisibleComments(c, &page.Items[i])
+	}
+	res.OkData(c, gin.H{"posts": page.Items, "pagination": paginationMeta(opts, page)})
+}
@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"blog/config"
+	"blog/diff"
+	"blog/models"
+	"blog/repository"
+	"errors"
+	"fmt"
+	"gin-demo/res"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// diffQuery 是比较两个修订的查询参数，例如 GET /posts/1/revisions/diff?from=1&to=3
+type diffQuery struct {
+	From    int  `form:"from" binding:"required,min=1"`
+	To      int  `form:"to" binding:"omitempty,min=1"`             // 不传时和最新的修订比较
+	Context *int `form:"context" binding:"omitempty,min=0,max=20"` // unified diff 中每处修改前后保留的行数，默认 3
+}
+
+// ListRevisions 分页返回文章的修订历史，最新的在前，只有文章作者可以查看
+func (h *Handler) ListRevisions(c *gin.Context) {
+	post, ok := h.findOwnPost(c, "查看修订历史")
+	if !ok {
+		return
+	}
+	opts, err := bindListOptions(c, true)
+	if err != nil {
+		res.FailBind(c, err)
+		return
+	}
+	page, err := h.Revisions.ListByPost(c.Request.Context(), post.ID, opts)
+	if err != nil {
+		// [日志] 记录查询修订失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"post_id": post.ID,
+			"error":   err.Error(),
+		}).Error("获取修订历史失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "获取修订历史失败")
+		return
+	}
+	res.OkData(c, gin.H{"revisions": page.Items, "pagination": paginationMeta(opts, page)})
+}
+
+// GetRevision 返回文章的某个修订
+func (h *Handler) GetRevision(c *gin.Context) {
+	post, ok := h.findOwnPost(c, "查看修订")
+	if !ok {
+		return
+	}
+	rev, ok := h.findRevision(c, post.ID, c.Param("rev"))
+	if !ok {
+		return
+	}
+	res.OkData(c, gin.H{"revision": rev})
+}
+
+// DiffRevisions 逐行比较文章的两个修订，返回结构化的差异和 unified diff 文本
+func (h *Handler) DiffRevisions(c *gin.Context) {
+	post, ok := h.findOwnPost(c, "比较修订")
+	if !ok {
+		return
+	}
+	var q diffQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		res.FailBind(c, err)
+		return
+	}
+	from, ok := h.findRevision(c, post.ID, strconv.Itoa(q.From))
+	if !ok {
+		return
+	}
+	// to 为 0 时 findRevision 返回最新的修订
+	to, ok := h.findRevision(c, post.ID, strconv.Itoa(q.To))
+	if !ok {
+		return
+	}
+	context := 3
+	if q.Context != nil {
+		context = *q.Context
+	}
+	changes, err := diff.Text(from.Content, to.Content)
+	if err != nil {
+		res.FailCode(c, res.CodeUnprocessable, fmt.Sprintf("两个修订的差异超过 %d 行，无法比较", diff.MaxEdits))
+		return
+	}
+	// 标题最多 100 个字符，差异不会超过限制
+	title, _ := diff.Text(from.Title, to.Title)
+	res.OkData(c, gin.H{
+		"from":    from.Number,
+		"to":      to.Number,
+		"title":   title,
+		"changes": changes,
+		"stats":   diff.Count(changes),
+		"unified": diff.Unified(changes, fmt.Sprintf("revision %d", from.Number), fmt.Sprintf("revision %d", to.Number), context),
+	})
+}
+
+// RestoreRevision 把文章的标题、内容和格式恢复成某个修订，恢复本身也会产生一个新的修订，不会丢失历史
+func (h *Handler) RestoreRevision(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	post, ok := h.findOwnPost(c, "恢复修订")
+	if !ok || !checkIfMatch(c, post) {
+		return
+	}
+	rev, ok := h.findRevision(c, post.ID, c.Param("rev"))
+	if !ok {
+		return
+	}
+	post.Title, post.Content, post.Format = rev.Title, rev.Content, rev.Format
+	if err := h.Posts.Update(c.Request.Context(), post); err != nil {
+		if failStale(c, err) {
+			return
+		}
+		// [日志] 记录恢复修订失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"user_id":  userID,
+			"post_id":  post.ID,
+			"revision": rev.Number,
+			"error":    err.Error(),
+		}).Error("恢复修订失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "恢复修订失败")
+		return
+	}
+	h.indexPost(c, post)
+	hideInvisibleComments(c, post)
+	c.Header("ETag", postETag(post))
+	res.Ok(c, gin.H{"post": post}, fmt.Sprintf("已恢复到修订 %d", rev.Number))
+}
+
+// findRevision 按修订号读取文章的修订，number 为 "0" 时返回最新的修订，失败时已经写好响应
+func (h *Handler) findRevision(c *gin.Context, postID uint, number string) (*models.PostRevision, bool) {
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 0 {
+		res.FailCode(c, res.CodeInvalidParams, "无效的修订号")
+		return nil, false
+	}
+	rev, err := h.Revisions.Find(c.Request.Context(), postID, n)
+	if errors.Is(err, repository.ErrNotFound) {
+		res.FailCode(c, res.CodeNotFound, "修订不存在")
+		return nil, false
+	}
+	if err != nil {
+		// [日志] 记录查询修订失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"post_id":  postID,
+			"revision": n,
+			"error":    err.Error(),
+		}).Error("获取修订失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "获取修订失败")
+		return nil, false
+	}
+	return rev, true
+}
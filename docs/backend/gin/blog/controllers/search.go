@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"blog/app"
+	"blog/config"
+	"blog/models"
+	"blog/search"
+	"gin-demo/res"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// searchQuery 是搜索接口的查询参数，例如 GET /search?q=gorm 事务&type=post&page=2
+type searchQuery struct {
+	Q    string `form:"q" binding:"required,max=100"`
+	Type string `form:"type" binding:"omitempty,oneof=post comment"`
+	Page int    `form:"page" binding:"omitempty,min=1"`
+	Size int    `form:"size" binding:"omitempty,min=1,max=100"`
+}
+
+func (h *Handler) Search(c *gin.Context) {
+	var q searchQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		res.FailBind(c, err)
+		return
+	}
+	if q.Page == 0 {
+		q.Page = 1
+	}
+	if q.Size == 0 {
+		q.Size = 20
+	}
+	result, err := h.Container.Search.Search(c.Request.Context(), search.Query{
+		Text:   q.Q,
+		Kind:   search.Kind(q.Type),
+		Offset: (q.Page - 1) * q.Size,
+		Limit:  q.Size,
+	})
+	if err != nil {
+		// [日志] 记录搜索失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"query": q.Q,
+			"error": err.Error(),
+		}).Error("搜索失败：搜索后端错误")
+		res.FailCode(c, res.CodeServerError, "搜索失败")
+		return
+	}
+	res.OkData(c, gin.H{
+		"results":    result.Hits,
+		"pagination": gin.H{"total": result.Total, "page": q.Page, "size": q.Size},
+	})
+}
+
+// 以下方法在文章和评论变更后同步搜索索引。
+// 索引只是数据库的副本，更新失败只记录日志，不影响请求本身；重启服务会重建索引
+
+func (h *Handler) indexPost(c *gin.Context, post *models.Post) {
+	h.logIndexError(h.IndexPost(c.Request.Context(), post), "post", post.ID)
+}
+
+func (h *Handler) indexComment(c *gin.Context, post *models.Post, comment *models.Comment) {
+	// 待审核的评论和未发布文章下的评论不能被搜到
+	if comment.Status != models.CommentApproved || post.Status != models.PostPublished {
+		return
+	}
+	h.logIndexError(h.Container.Search.Index(c.Request.Context(), app.CommentDocument(comment)), "comment", comment.ID)
+}
+
+func (h *Handler) unindexPost(c *gin.Context, postID uint) {
+	h.logIndexError(h.Container.Search.RemovePost(c.Request.Context(), postID), "post", postID)
+}
+
+func (h *Handler) unindexComment(c *gin.Context, commentID uint) {
+	h.logIndexError(h.Container.Search.Remove(c.Request.Context(), search.KindComment, commentID), "comment", commentID)
+}
+
+func (h *Handler) logIndexError(err error, kind string, id uint) {
+	if err != nil {
+		config.Log.WithFields(logrus.Fields{
+			"type":  kind,
+			"id":    id,
+			"error": err.Error(),
+		}).Warn("更新搜索索引失败")
+	}
+}
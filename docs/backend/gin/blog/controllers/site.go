@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"blog/config"
+	"blog/models"
+	"blog/repository"
+	"blog/site"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// 公开的 HTML 页面不需要登录，所有访问者看到的内容完全相同（已发布的文章和公开的评论），
+// 所以可以让浏览器和 CDN 等共享缓存按 site.max_age 缓存，过期后再用 ETag 校验
+
+// siteQuery 是公开页面的查询参数，只支持页码分页，页码会出现在链接里，方便被搜索引擎收录
+type siteQuery struct {
+	Page int `form:"page" binding:"omitempty,min=1"`
+}
+
+// sitePagination 是页面底部的翻页链接，没有上一页或下一页时对应的 URL 为空
+type sitePagination struct {
+	Page    int
+	Pages   int
+	PrevURL string
+	NextURL string
+}
+
+func newSitePagination(base string, page, size int, total int64) sitePagination {
+	p := sitePagination{Page: page, Pages: max(1, int((total+int64(size)-1)/int64(size)))}
+	pageURL := func(n int) string {
+		if n == 1 {
+			return base
+		}
+		return fmt.Sprintf("%s?page=%d", base, n)
+	}
+	if page > 1 {
+		p.PrevURL = pageURL(page - 1)
+	}
+	if page < p.Pages {
+		p.NextURL = pageURL(page + 1)
+	}
+	return p
+}
+
+// SiteHome 是首页，按发布时间倒序分页展示已发布的文章
+func (h *Handler) SiteHome(c *gin.Context) {
+	h.sitePostList(c, "home.html", "/", 0, gin.H{})
+}
+
+// SiteAuthor 是作者页，分页展示该作者已发布的文章
+func (h *Handler) SiteAuthor(c *gin.Context) {
+	userID, ok := paramID(c, "user_id")
+	if !ok {
+		h.siteError(c, http.StatusNotFound, "作者不存在")
+		return
+	}
+	author, err := h.Users.FindByID(c.Request.Context(), userID)
+	if err != nil {
+		h.siteError(c, http.StatusNotFound, "作者不存在")
+		return
+	}
+	h.sitePostList(c, "author.html", fmt.Sprintf("/u/%d", userID), userID, gin.H{"Title": author.Name, "Author": author})
+}
+
+// sitePostList 渲染首页和作者页共用的文章列表，base 是翻页链接的路径，authorID 为 0 时不按作者过滤
+func (h *Handler) sitePostList(c *gin.Context, name, base string, authorID uint, data gin.H) {
+	var q siteQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		h.siteError(c, http.StatusBadRequest, "页码不合法")
+		return
+	}
+	// 零值的 Visibility 只返回已发布的文章，和访问者是否登录无关；按发布时间排序，定时发布的文章在发布时排到最前面
+	opts := repository.ListOptions{Page: q.Page, Size: h.Config.Site.PageSize, Desc: true, AuthorID: authorID, ByPublished: true}.Normalize()
+	page, err := h.Posts.List(c.Request.Context(), opts)
+	if err != nil {
+		// [日志] 记录获取文章失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"path":  c.Request.URL.Path,
+			"error": err.Error(),
+		}).Error("渲染页面失败：获取文章失败")
+		h.siteError(c, http.StatusInternalServerError, "服务器开小差了，请稍后再试")
+		return
+	}
+	pagination := newSitePagination(base, opts.Page, opts.Size, page.Total)
+	if opts.Page > pagination.Pages {
+		h.siteError(c, http.StatusNotFound, "没有这一页")
+		return
+	}
+	data["Posts"] = page.Items
+	data["Pagination"] = pagination
+	h.sitePage(c, name, data)
+}
+
+// SitePost 是文章页，展示渲染后的文章内容和公开的评论
+func (h *Handler) SitePost(c *gin.Context) {
+	postID, ok := paramID(c, "post_id")
+	if !ok {
+		h.siteError(c, http.StatusNotFound, "文章不存在")
+		return
+	}
+	post, err := h.Posts.FindByID(c.Request.Context(), postID)
+	// 草稿和定时发布的文章即使是作者本人访问也不展示，保证页面对所有人相同
+	if err != nil || (post.Status != models.PostPublished && post.Status != models.PostArchived) {
+		h.siteError(c, http.StatusNotFound, "文章不存在")
+		return
+	}
+	comments, err := h.Comments.ListTree(c.Request.Context(), postID, repository.Visibility{})
+	if err != nil {
+		// [日志] 记录查询评论失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"post_id": postID,
+			"error":   err.Error(),
+		}).Error("渲染页面失败：获取评论失败")
+		h.siteError(c, http.StatusInternalServerError, "服务器开小差了，请稍后再试")
+		return
+	}
+	for i := range comments {
+		redactDeleted(&comments[i])
+	}
+	h.sitePage(c, "post.html", gin.H{
+		"Title": post.Title,
+		"Post":  post,
+		// RenderPost 的结果已经过滤掉不安全的标签和属性，可以直接输出
+		"Content":  template.HTML(h.RenderPost(post)),
+		"Comments": buildCommentTree(comments),
+	})
+}
+
+// sitePage 渲染页面并设置缓存响应头，客户端缓存的页面没有变化时返回 304。
+// 先渲染到缓冲区再按内容计算 ETag，模板执行出错时也还来得及返回错误页面
+func (h *Handler) sitePage(c *gin.Context, name string, data gin.H) {
+	data["Site"] = h.Config.Site
+	var buf bytes.Buffer
+	if err := site.Pages.Execute(&buf, name, data); err != nil {
+		// [日志] 记录渲染页面失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"path":  c.Request.URL.Path,
+			"page":  name,
+			"error": err.Error(),
+		}).Error("渲染页面失败：模板错误")
+		h.siteError(c, http.StatusInternalServerError, "服务器开小差了，请稍后再试")
+		return
+	}
+	if h.publicNotModified(c, buf.Bytes(), time.Time{}) {
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// publicNotModified 为所有人相同的响应（公开页面、订阅源）设置缓存响应头：按内容计算的 ETag、
+// modified 不为零时的 Last-Modified，以及按 site.max_age 允许共享缓存的 Cache-Control。
+// 客户端缓存的内容没有变化时返回 304 并返回 true。
+// 按 RFC 9110，带了 If-None-Match 时忽略 If-Modified-Since
+func (h *Handler) publicNotModified(c *gin.Context, body []byte, modified time.Time) bool {
+	sum := sha256.Sum256(body)
+	etag := fmt.Sprintf(`"%s"`, hex.EncodeToString(sum[:8]))
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(time.Duration(h.Config.Site.MaxAge).Seconds())))
+	if !modified.IsZero() {
+		c.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	notModified := false
+	if header := c.GetHeader("If-None-Match"); header != "" {
+		notModified = matchETag(header, etag)
+	} else if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && !modified.IsZero() {
+		// HTTP 日期只精确到秒
+		notModified = !modified.Truncate(time.Second).After(since)
+	}
+	if notModified {
+		c.AbortWithStatus(http.StatusNotModified)
+	}
+	return notModified
+}
+
+// siteError 渲染错误页面，错误页面不缓存，避免临时的错误被共享缓存保存下来
+func (h *Handler) siteError(c *gin.Context, status int, message string) {
+	c.Header("Cache-Control", "no-store")
+	c.HTML(status, "error.html", gin.H{
+		"Site":    h.Config.Site,
+		"Title":   http.StatusText(status),
+		"Message": message,
+	})
+}
@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"blog/config"
+	"blog/repository"
+	"errors"
+	"gin-demo/res"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// termsInput 是给文章添加标签或分类的请求体，例如 {"names": ["go", "gin"]}
+type termsInput struct {
+	Names []string `json:"names" binding:"required,min=1,max=10,dive,required,max=30"`
+}
+
+// 标签和分类的处理逻辑完全相同，下面的导出方法只是指定操作哪一种
+
+func (h *Handler) AttachTags(c *gin.Context)       { h.attachTerms(c, repository.TaxonomyTag) }
+func (h *Handler) DetachTag(c *gin.Context)        { h.detachTerm(c, repository.TaxonomyTag) }
+func (h *Handler) ListTags(c *gin.Context)         { h.listTerms(c, repository.TaxonomyTag) }
+func (h *Handler) GetPostsByTag(c *gin.Context)    { h.getPostsByTerm(c, repository.TaxonomyTag) }
+func (h *Handler) AttachCategories(c *gin.Context) { h.attachTerms(c, repository.TaxonomyCategory) }
+func (h *Handler) DetachCategory(c *gin.Context)   { h.detachTerm(c, repository.TaxonomyCategory) }
+func (h *Handler) ListCategories(c *gin.Context)   { h.listTerms(c, repository.TaxonomyCategory) }
+func (h *Handler) GetPostsByCategory(c *gin.Context) {
+	h.getPostsByTerm(c, repository.TaxonomyCategory)
+}
+
+func (h *Handler) taxonomy(t repository.Taxonomy) repository.TaxonomyRepository {
+	if t == repository.TaxonomyCategory {
+		return h.Categories
+	}
+	return h.Tags
+}
+
+// termLabel 返回用于提示信息的中文名称
+func termLabel(t repository.Taxonomy) string {
+	if t == repository.TaxonomyCategory {
+		return "分类"
+	}
+	return "标签"
+}
+
+// normalizeTerm 去掉名称首尾的空白，标签统一为小写，避免 "Go" 和 "go" 成为两个标签
+func normalizeTerm(t repository.Taxonomy, name string) string {
+	name = strings.TrimSpace(name)
+	if t == repository.TaxonomyTag {
+		name = strings.ToLower(name)
+	}
+	return name
+}
+
+// normalizeTerms 整理一组名称，去掉空的和重复的，保持原来的顺序
+func normalizeTerms(t repository.Taxonomy, names []string) []string {
+	result := make([]string, 0, len(names))
+	seen := map[string]bool{}
+	for _, name := range names {
+		if name = normalizeTerm(t, name); name != "" && !seen[name] {
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+func (h *Handler) attachTerms(c *gin.Context, t repository.Taxonomy) {
+	userID := c.GetUint("user_id")
+	post, ok := h.findOwnPost(c, "修改"+termLabel(t))
+	if !ok {
+		return
+	}
+	postID := post.ID
+	var input termsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		// [日志] 记录参数绑定失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"ip":      c.ClientIP(),
+			"user_id": userID,
+			"post_id": postID,
+			"error":   err.Error(),
+		}).Warn("添加" + termLabel(t) + "失败：参数格式错误")
+		res.FailBind(c, err)
+		return
+	}
+	names := normalizeTerms(t, input.Names)
+	if len(names) == 0 {
+		res.FailCode(c, res.CodeInvalidParams, termLabel(t)+"名称不能为空")
+		return
+	}
+	if err := h.taxonomy(t).Attach(c.Request.Context(), postID, names); err != nil {
+		// [日志] 记录添加失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"user_id": userID,
+			"post_id": postID,
+			"names":   names,
+			"error":   err.Error(),
+		}).Error("添加" + termLabel(t) + "失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "添加"+termLabel(t)+"失败")
+		return
+	}
+	h.respondPost(c, postID, "添加"+termLabel(t)+"成功")
+}
+
+func (h *Handler) detachTerm(c *gin.Context, t repository.Taxonomy) {
+	userID := c.GetUint("user_id")
+	post, ok := h.findOwnPost(c, "修改"+termLabel(t))
+	if !ok {
+		return
+	}
+	postID := post.ID
+	name := normalizeTerm(t, c.Param("name"))
+	if err := h.taxonomy(t).Detach(c.Request.Context(), postID, name); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			res.FailCode(c, res.CodeNotFound, termLabel(t)+"不存在")
+			return
+		}
+		// [日志] 记录移除失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"user_id": userID,
+			"post_id": postID,
+			"name":    name,
+			"error":   err.Error(),
+		}).Error("移除" + termLabel(t) + "失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "移除"+termLabel(t)+"失败")
+		return
+	}
+	h.respondPost(c, postID, "移除"+termLabel(t)+"成功")
+}
+
+// respondPost 重新读取文章并返回，让客户端拿到修改后的标签和分类
+func (h *Handler) respondPost(c *gin.Context, postID uint, message string) {
+	post, err := h.Posts.FindByID(c.Request.Context(), postID)
+	if err != nil {
+		res.FailCode(c, res.CodeServerError, "获取文章失败")
+		return
+	}
+	hideInvisibleComments(c, post)
+	res.Ok(c, gin.H{"post": post}, message)
+}
+
+func (h *Handler) listTerms(c *gin.Context, t repository.Taxonomy) {
+	counts, err := h.taxonomy(t).Counts(c.Request.Context())
+	if err != nil {
+		// [日志] 记录统计失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"ip":    c.ClientIP(),
+			"error": err.Error(),
+		}).Error("获取" + termLabel(t) + "列表失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "获取"+termLabel(t)+"列表失败")
+		return
+	}
+	key := "tags"
+	if t == repository.TaxonomyCategory {
+		key = "categories"
+	}
+	res.OkData(c, gin.H{key: counts})
+}
+
+func (h *Handler) getPostsByTerm(c *gin.Context, t repository.Taxonomy) {
+	opts, err := bindPostListOptions(c, true)
+	if err != nil {
+		res.FailBind(c, err)
+		return
+	}
+	// 路径中的名称优先于 tag / category 参数
+	name := normalizeTerm(t, c.Param("name"))
+	if t == repository.TaxonomyCategory {
+		opts.Category = name
+	} else {
+		opts.Tag = name
+	}
+	opts.Visibility = postVisibility(c)
+	page, err := h.Posts.List(c.Request.Context(), opts)
+	if err != nil {
+		// [日志] 记录获取文章列表失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"ip":    c.ClientIP(),
+			"name":  name,
+			"error": err.Error(),
+		}).Error("获取文章列表失败：数据库错误")
+		res.FailCode(c, res.CodeServerError, "获取文章列表失败")
+		return
+	}
+	for i := range page.Items {
+		hideInvisibleComments(c, &page.Items[i])
+	}
+	res.OkData(c, gin.H{"posts": page.Items, "pagination": paginationMeta(opts, page)})
+}
@@ -0,0 +1,286 @@
+// Package diff 计算两段文本之间的行级差异，用于比较文章的两个修订版本。
+// 实现的是线性空间的 Myers 差分算法，得到的是最短编辑脚本（插入和删除的行数最少）
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Kind 是一行的变化类型
+type Kind string
+
+const (
+	Equal  Kind = "equal"
+	Insert Kind = "insert"
+	Delete Kind = "delete"
+)
+
+// Line 是差异结果中的一行，行号从 1 开始，插入的行没有旧行号，删除的行没有新行号
+type Line struct {
+	Kind    Kind   `json:"op"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+	Text    string `json:"text"`
+}
+
+// Stats 统计新增和删除的行数
+type Stats struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+}
+
+// Split 按行拆分文本，兼容 \r\n 换行，末尾的换行符不会产生空行
+func Split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSuffix(l, "\r")
+	}
+	return lines
+}
+
+// MaxEdits 是一次比较允许的最大编辑距离（新增和删除的总行数），超过时返回 ErrTooLarge。
+// Myers 算法的耗时和 (N+M)·D 成正比，两段完全不同的长文本需要限制 D 才不会占满 CPU
+const MaxEdits = 5000
+
+// ErrTooLarge 表示两段文本的差异超过了 MaxEdits
+var ErrTooLarge = errors.New("diff: too many changes")
+
+// Text 比较两段文本，返回逐行的差异
+func Text(a, b string) ([]Line, error) {
+	return Lines(Split(a), Split(b))
+}
+
+// Lines 比较两组行，返回逐行的差异。差异超过 MaxEdits 行时返回 ErrTooLarge
+func Lines(a, b []string) ([]Line, error) {
+	// 使用线性空间的分治版本：每次只找出最短编辑路径上的一个中间点，再分别比较两边，
+	// 除了结果本身只需要两个 O(N+M) 的数组
+	size := len(a) + len(b) + 2
+	d := &differ{a: a, b: b, forward: make([]int, 2*size), backward: make([]int, 2*size)}
+	if err := d.compare(0, len(a), 0, len(b)); err != nil {
+		return nil, err
+	}
+	return d.out, nil
+}
+
+// differ 保存一次比较的状态，forward 和 backward 在递归中复用
+type differ struct {
+	a, b              []string
+	forward, backward []int
+	out               []Line
+}
+
+// compare 按顺序输出 a[a0:a1] 和 b[b0:b1] 之间的差异
+func (d *differ) compare(a0, a1, b0, b1 int) error {
+	// 先去掉相同的前缀和后缀，实际修改通常只涉及一小部分行
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.out = append(d.out, Line{Kind: Equal, OldLine: a0 + 1, NewLine: b0 + 1, Text: d.a[a0]})
+		a0, b0 = a0+1, b0+1
+	}
+	suffix := 0
+	for a0 < a1-suffix && b0 < b1-suffix && d.a[a1-1-suffix] == d.b[b1-1-suffix] {
+		suffix++
+	}
+	a1, b1 = a1-suffix, b1-suffix
+
+	switch {
+	case a0 == a1:
+		for y := b0; y < b1; y++ {
+			d.out = append(d.out, Line{Kind: Insert, NewLine: y + 1, Text: d.b[y]})
+		}
+	case b0 == b1:
+		for x := a0; x < a1; x++ {
+			d.out = append(d.out, Line{Kind: Delete, OldLine: x + 1, Text: d.a[x]})
+		}
+	default:
+		x, y, err := d.middle(a0, a1, b0, b1)
+		if err != nil {
+			return err
+		}
+		if err := d.compare(a0, x, b0, y); err != nil {
+			return err
+		}
+		if err := d.compare(x, a1, y, b1); err != nil {
+			return err
+		}
+	}
+
+	for i := suffix; i > 0; i-- {
+		d.out = append(d.out, Line{Kind: Equal, OldLine: a1 + suffix - i + 1, NewLine: b1 + suffix - i + 1, Text: d.a[a1+suffix-i]})
+	}
+	return nil
+}
+
+// middle 从两端同时搜索，返回 a[a0:a1] 和 b[b0:b1] 之间某条最短编辑路径上的一个中间点。
+// forward[k] 记录从起点出发、第 k 条对角线（k = x - y）上走得最远的 x；
+// backward 在倒过来的两组行上做同样的搜索，两边在同一条对角线上相遇时就找到了中间点。
+// 调用前已经去掉了相同的前缀和后缀，所以两边都不为空，并且至少需要两步编辑，中间点两边的问题都比原来小
+func (d *differ) middle(a0, a1, b0, b1 int) (int, int, error) {
+	n, m := a1-a0, b1-b0
+	delta := n - m
+	// 两端走的步数之和就是编辑距离，两端各走 max 步一定会相遇
+	max := (n + m + 1) / 2
+	offset := max + 1
+	forward, backward := d.forward[:2*offset+1], d.backward[:2*offset+1]
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+	// delta 为奇数时编辑距离是奇数，向前走完一步后检查是否相遇；为偶数时向后走完一步后检查
+	odd := delta%2 != 0
+	// 走出网格的对角线不再继续搜索
+	fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+	for step := 0; step < max; step++ {
+		// 已经走了 step 步还没有相遇，编辑距离至少是 2*step-1
+		if 2*step-1 > MaxEdits {
+			return 0, 0, ErrTooLarge
+		}
+		for k := -step + fStart; k <= step-fEnd; k += 2 {
+			var x int
+			if k == -step || (k != step && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[a0+x] == d.b[b0+y] {
+				x, y = x+1, y+1
+			}
+			forward[offset+k] = x
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case odd:
+				// 倒过来的坐标中，同一条对角线的编号是 delta - k
+				if bk := offset + delta - k; bk >= 0 && bk < len(backward) && backward[bk] != -1 && x >= n-backward[bk] {
+					return a0 + x, b0 + y, nil
+				}
+			}
+		}
+		for k := -step + bStart; k <= step-bEnd; k += 2 {
+			var x int
+			if k == -step || (k != step && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[a1-1-x] == d.b[b1-1-y] {
+				x, y = x+1, y+1
+			}
+			backward[offset+k] = x
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !odd:
+				if fk := offset + delta - k; fk >= 0 && fk < len(forward) && forward[fk] != -1 {
+					// 返回向前搜索在这条对角线上到达的点，它一定在某条最短路径上
+					fx := forward[fk]
+					if fx >= n-x {
+						return a0 + fx, b0 + fx - (delta - k), nil
+					}
+				}
+			}
+		}
+	}
+	// 两组行没有任何相同的行（编辑距离为 n + m）时两端不会相遇，
+	// 这时在 (a1, b0) 拆开：前一半删除全部旧行，后一半插入全部新行
+	if n+m > MaxEdits {
+		return 0, 0, ErrTooLarge
+	}
+	return a1, b0, nil
+}
+
+// Count 统计差异中新增和删除的行数
+func Count(lines []Line) Stats {
+	var s Stats
+	for _, l := range lines {
+		switch l.Kind {
+		case Insert:
+			s.Added++
+		case Delete:
+			s.Removed++
+		}
+	}
+	return s
+}
+
+// Unified 把差异格式化为 unified diff（和 diff -u、git diff 相同的格式），
+// context 是每处修改前后保留的相同行数，没有差异时返回空字符串
+func Unified(lines []Line, oldName, newName string, context int) string {
+	// 找出需要输出的区间：每处修改前后各扩展 context 行，相互重叠的区间合并成一个 hunk
+	type hunk struct{ start, end int }
+	var hunks []hunk
+	for i, l := range lines {
+		if l.Kind == Equal {
+			continue
+		}
+		start, end := max(i-context, 0), min(i+context+1, len(lines))
+		if n := len(hunks); n > 0 && start <= hunks[n-1].end {
+			hunks[n-1].end = end
+		} else {
+			hunks = append(hunks, hunk{start, end})
+		}
+	}
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	// oldPos、newPos 是 hunk 开始之前已经出现的旧行和新行数
+	oldPos, newPos, pos := 0, 0, 0
+	for _, h := range hunks {
+		for ; pos < h.start; pos++ {
+			oldPos, newPos = advance(lines[pos], oldPos, newPos)
+		}
+		oldCount, newCount := 0, 0
+		for _, l := range lines[h.start:h.end] {
+			oldCount, newCount = advance(l, oldCount, newCount)
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(oldPos, oldCount), hunkRange(newPos, newCount))
+		for _, l := range lines[h.start:h.end] {
+			switch l.Kind {
+			case Equal:
+				sb.WriteString(" ")
+			case Insert:
+				sb.WriteString("+")
+			case Delete:
+				sb.WriteString("-")
+			}
+			sb.WriteString(l.Text)
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// advance 按行的类型累加旧行数和新行数
+func advance(l Line, oldN, newN int) (int, int) {
+	if l.Kind != Insert {
+		oldN++
+	}
+	if l.Kind != Delete {
+		newN++
+	}
+	return oldN, newN
+}
+
+// hunkRange 生成 hunk 头中的 "起始行,行数"，行数为 0 时起始行是前一行
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
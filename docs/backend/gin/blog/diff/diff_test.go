@@ -0,0 +1,208 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// apply 用差异结果从 a 重建出 b，同时检查行号是否连续
+func apply(t *testing.T, a []string, lines []Line) []string {
+	t.Helper()
+	var out []string
+	oldN, newN := 0, 0
+	for _, l := range lines {
+		if l.Kind != Insert {
+			oldN++
+			if l.OldLine != oldN || a[oldN-1] != l.Text {
+				t.Fatalf("旧行号错误: %+v，期望 %d", l, oldN)
+			}
+		}
+		if l.Kind != Delete {
+			newN++
+			if l.NewLine != newN {
+				t.Fatalf("新行号错误: %+v，期望 %d", l, newN)
+			}
+			out = append(out, l.Text)
+		}
+	}
+	if oldN != len(a) {
+		t.Fatalf("差异没有覆盖全部旧行: %d/%d", oldN, len(a))
+	}
+	return out
+}
+
+// mustText 比较两段文本，差异不应超过 MaxEdits
+func mustText(t *testing.T, a, b string) []Line {
+	t.Helper()
+	lines, err := Text(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
+
+// mustLines 比较两组行，差异不应超过 MaxEdits
+func mustLines(t *testing.T, a, b []string) []Line {
+	t.Helper()
+	lines, err := Lines(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
+
+func TestText(t *testing.T) {
+	lines := mustText(t, "a\nb\nc\nd\n", "a\nc\nd\ne\n")
+	var got []string
+	for _, l := range lines {
+		got = append(got, string(l.Kind[0])+l.Text)
+	}
+	if strings.Join(got, " ") != "ea db ec ed ie" {
+		t.Fatalf("差异不符合预期: %v", got)
+	}
+	if s := Count(lines); s.Added != 1 || s.Removed != 1 {
+		t.Fatalf("统计不符合预期: %+v", s)
+	}
+	if lines := mustText(t, "", ""); len(lines) != 0 {
+		t.Fatalf("两段空文本不应有差异: %v", lines)
+	}
+	if s := Count(mustText(t, "", "a\r\nb")); s.Added != 2 || s.Removed != 0 {
+		t.Fatalf("新增全部内容的统计不符合预期: %+v", s)
+	}
+}
+
+func TestLinesRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	gen := func() []string {
+		lines := make([]string, r.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(4)))
+		}
+		return lines
+	}
+	for i := 0; i < 500; i++ {
+		a, b := gen(), gen()
+		lines := mustLines(t, a, b)
+		if got := apply(t, a, lines); strings.Join(got, "") != strings.Join(b, "") {
+			t.Fatalf("无法从差异重建新文本: %v -> %v，得到 %v", a, b, got)
+		}
+		// 最短编辑脚本：相同的行数应等于最长公共子序列的长度
+		if s := Count(lines); len(a)-s.Removed != lcs(a, b) {
+			t.Fatalf("编辑脚本不是最短的: %v -> %v", a, b)
+		}
+	}
+}
+
+// measure 返回 f 的耗时和期间分配的内存
+func measure(f func()) (time.Duration, uint64) {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	f()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	return elapsed, after.TotalAlloc - before.TotalAlloc
+}
+
+func TestLinesLarge(t *testing.T) {
+	lines := func(n int, format string) []string {
+		out := make([]string, n)
+		for i := range out {
+			out[i] = fmt.Sprintf(format, i)
+		}
+		return out
+	}
+	// 两段完全不同的约 20KB 的内容，空间和结果的大小成正比，不会随编辑距离平方增长
+	a, b := lines(1400, "old line %05d"), lines(1400, "new line %05d")
+	var result []Line
+	elapsed, alloc := measure(func() { result = mustLines(t, a, b) })
+	if s := Count(result); s.Added != len(b) || s.Removed != len(a) {
+		t.Fatalf("统计不符合预期: %+v", s)
+	}
+	if elapsed > 2*time.Second || alloc > 16<<20 {
+		t.Fatalf("比较完全不同的内容耗时 %v，分配 %d 字节", elapsed, alloc)
+	}
+
+	// 每三行修改一行，需要多次拆分
+	c := append([]string(nil), a...)
+	for i := 0; i < len(c); i += 3 {
+		c[i] = "changed"
+	}
+	result = mustLines(t, a, c)
+	if got := apply(t, a, result); strings.Join(got, "\n") != strings.Join(c, "\n") {
+		t.Fatal("无法从差异重建新文本")
+	}
+	if s := Count(result); s.Added != (len(c)+2)/3 || s.Removed != (len(c)+2)/3 {
+		t.Fatalf("统计不符合预期: %+v", s)
+	}
+
+	// 差异超过 MaxEdits 时很快返回错误
+	a, b = lines(30000, "old %d"), lines(30000, "new %d")
+	var err error
+	elapsed, alloc = measure(func() { _, err = Lines(a, b) })
+	if !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("期望 ErrTooLarge，实际 %v", err)
+	}
+	if elapsed > 2*time.Second || alloc > 16<<20 {
+		t.Fatalf("拒绝过大的差异耗时 %v，分配 %d 字节", elapsed, alloc)
+	}
+}
+
+// lcs 用动态规划计算最长公共子序列的长度，作为对照
+func lcs(a, b []string) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else {
+				dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+			}
+		}
+	}
+	return dp[0][0]
+}
+
+func TestUnified(t *testing.T) {
+	var a, b []string
+	for i := 1; i <= 20; i++ {
+		a = append(a, string(rune('a'+i)))
+	}
+	b = append(b, a...)
+	b[1] = "changed"
+	b = append(b[:15], b[16:]...)
+
+	got := Unified(mustLines(t, a, b), "rev1", "rev2", 2)
+	want := `--- rev1
++++ rev2
+@@ -1,4 +1,4 @@
+ b
+-c
++changed
+ d
+ e
+@@ -14,5 +14,4 @@
+ o
+ p
+-q
+ r
+ s
+`
+	if got != want {
+		t.Fatalf("unified 输出不符合预期:\n%s\n期望:\n%s", got, want)
+	}
+	if got := Unified(mustLines(t, a, a), "rev1", "rev2", 3); got != "" {
+		t.Fatalf("没有差异时应返回空字符串: %q", got)
+	}
+	if got := Unified(mustText(t, "", "x"), "a", "b", 3); got != "--- a\n+++ b\n@@ -0,0 +1 @@\n+x\n" {
+		t.Fatalf("新增全部内容的 unified 输出不符合预期: %q", got)
+	}
+}
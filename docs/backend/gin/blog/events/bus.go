@@ -0,0 +1,47 @@
+// Package events 是进程内的领域事件总线：控制器在业务操作完成后发布事件，
+// 通知等附加功能订阅事件，不需要修改发布事件的代码
+package events
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// Event 是领域事件，具体的事件类型见 events.go
+type Event interface {
+	// EventName 返回事件名，用于日志
+	EventName() string
+}
+
+// Handler 处理一个事件。处理函数自己记录错误，不会影响发布事件的请求
+type Handler func(ctx context.Context, e Event)
+
+// Bus 按事件类型分发事件，零值不可用，使用 NewBus 创建
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[reflect.Type][]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: map[reflect.Type][]Handler{}}
+}
+
+// Subscribe 订阅和 sample 类型相同的事件，例如 bus.Subscribe(events.CommentCreated{}, handler)
+func (b *Bus) Subscribe(sample Event, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := reflect.TypeOf(sample)
+	b.handlers[t] = append(b.handlers[t], h)
+}
+
+// Publish 按订阅顺序同步调用事件的处理函数。
+// 同步调用让事件的副作用（例如写入通知）在请求返回前完成，处理函数需要尽快返回
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	b.mu.RLock()
+	handlers := b.handlers[reflect.TypeOf(e)]
+	b.mu.RUnlock()
+	for _, h := range handlers {
+		h(ctx, e)
+	}
+}
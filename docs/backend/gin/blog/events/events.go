@@ -0,0 +1,25 @@
+package events
+
+import "blog/models"
+
+// CommentCreated 在评论（包括回复）保存后发布，Post 是评论所在的文章
+type CommentCreated struct {
+	Comment models.Comment
+	Post    models.Post
+}
+
+// CommentApproved 在待审核的评论通过审核后发布
+type CommentApproved struct {
+	Comment models.Comment
+	Post    models.Post
+}
+
+// UserFollowed 在新增关注后发布，重复关注不会发布
+type UserFollowed struct {
+	FollowerID uint
+	FolloweeID uint
+}
+
+func (CommentCreated) EventName() string  { return "comment.created" }
+func (CommentApproved) EventName() string { return "comment.approved" }
+func (UserFollowed) EventName() string    { return "user.followed" }
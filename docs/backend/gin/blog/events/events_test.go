@@ -0,0 +1,51 @@
+package events
+
+import (
+	"blog/models"
+	"context"
+	"testing"
+)
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+	var got []string
+	bus.Subscribe(UserFollowed{}, func(ctx context.Context, e Event) { got = append(got, "a:"+e.EventName()) })
+	bus.Subscribe(UserFollowed{}, func(ctx context.Context, e Event) { got = append(got, "b:"+e.EventName()) })
+	bus.Subscribe(CommentCreated{}, func(ctx context.Context, e Event) { got = append(got, "c:"+e.EventName()) })
+
+	bus.Publish(context.Background(), UserFollowed{FollowerID: 1, FolloweeID: 2})
+	if len(got) != 2 || got[0] != "a:user.followed" || got[1] != "b:user.followed" {
+		t.Fatalf("应按订阅顺序只调用同类型事件的处理函数: %q", got)
+	}
+	// 没有订阅者的事件直接忽略
+	bus.Publish(context.Background(), CommentApproved{})
+}
+
+func TestHub(t *testing.T) {
+	hub := NewHub()
+	ch, cancel := hub.Subscribe(1)
+	other, cancelOther := hub.Subscribe(2)
+	defer cancelOther()
+
+	hub.Publish(models.Notification{ID: 10, UserID: 1})
+	if n := <-ch; n.ID != 10 {
+		t.Fatalf("收到的通知不符合预期: %+v", n)
+	}
+	if len(other) != 0 {
+		t.Fatal("通知不应推送给其他用户")
+	}
+
+	// 缓冲区满时丢弃新的通知，不阻塞发布者
+	for i := 0; i < hubBuffer+5; i++ {
+		hub.Publish(models.Notification{ID: uint(i), UserID: 1})
+	}
+	if len(ch) != hubBuffer {
+		t.Fatalf("缓冲区中应有 %d 条通知，实际 %d", hubBuffer, len(ch))
+	}
+
+	cancel()
+	hub.Publish(models.Notification{ID: 99, UserID: 1})
+	if _, ok := hub.subs[1]; ok {
+		t.Fatal("取消订阅后应移除订阅者")
+	}
+}
@@ -0,0 +1,51 @@
+package events
+
+import (
+	"blog/models"
+	"sync"
+)
+
+// hubBuffer 是每个订阅者的缓冲区大小，缓冲区满时丢弃新的通知，避免慢客户端拖住发布者
+const hubBuffer = 16
+
+// Hub 把通知实时推送给在线的用户，每个连接（例如一个 SSE 请求）是一个订阅者。
+// 推送只是提醒，丢失的通知仍然可以通过通知列表查到
+type Hub struct {
+	mu   sync.Mutex
+	subs map[uint]map[chan models.Notification]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: map[uint]map[chan models.Notification]struct{}{}}
+}
+
+// Subscribe 订阅发给 userID 的通知，调用返回的函数取消订阅
+func (h *Hub) Subscribe(userID uint) (<-chan models.Notification, func()) {
+	ch := make(chan models.Notification, hubBuffer)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[userID] == nil {
+		h.subs[userID] = map[chan models.Notification]struct{}{}
+	}
+	h.subs[userID][ch] = struct{}{}
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subs[userID], ch)
+		if len(h.subs[userID]) == 0 {
+			delete(h.subs, userID)
+		}
+	}
+}
+
+// Publish 把通知推送给接收者的所有订阅者，不会阻塞
+func (h *Hub) Publish(n models.Notification) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[n.UserID] {
+		select {
+		case ch <- n:
+		default:
+		}
+	}
+}
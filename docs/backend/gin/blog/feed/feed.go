@@ -0,0 +1,69 @@
+// Package feed 把文章列表编码成 RSS 2.0、Atom 1.0 和 JSON Feed 1.1 三种订阅格式。
+// 包里只有和格式无关的 Feed / Item，调用方负责把自己的模型转换过来，并提供绝对地址的链接
+package feed
+
+import (
+	"fmt"
+	"time"
+)
+
+// Format 是订阅格式
+type Format string
+
+const (
+	RSS  Format = "rss"
+	Atom Format = "atom"
+	JSON Format = "json"
+)
+
+// ContentType 返回格式对应的 Content-Type 响应头
+func (f Format) ContentType() string {
+	switch f {
+	case RSS:
+		return "application/rss+xml; charset=utf-8"
+	case Atom:
+		return "application/atom+xml; charset=utf-8"
+	default:
+		return "application/feed+json; charset=utf-8"
+	}
+}
+
+// Feed 是一个订阅源，所有链接都必须是绝对地址，订阅客户端不知道相对于哪里解析
+type Feed struct {
+	Title       string
+	Description string
+	Link        string    // 订阅源对应的网页，例如首页或作者页
+	FeedURL     string    // 订阅源自身的地址
+	Updated     time.Time // 订阅源最后一次变化的时间，一般取最近更新的一项
+	Items       []Item
+}
+
+// Item 是订阅源中的一篇文章
+type Item struct {
+	Link        string // 文章页面的地址，同时作为文章的唯一标识
+	Title       string
+	ContentHTML string // 已经过滤的 HTML
+	Author      Author
+	Tags        []string
+	Published   time.Time
+	Updated     time.Time
+}
+
+// Author 是文章作者，只包含公开信息
+type Author struct {
+	Name string
+	URL  string
+}
+
+// Encode 把订阅源编码成指定格式
+func Encode(f *Feed, format Format) ([]byte, error) {
+	switch format {
+	case RSS:
+		return encodeRSS(f)
+	case Atom:
+		return encodeAtom(f)
+	case JSON:
+		return encodeJSON(f)
+	}
+	return nil, fmt.Errorf("不支持的订阅格式 %q", format)
+}
@@ -0,0 +1,134 @@
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFeed() *Feed {
+	published := time.Date(2024, 5, 1, 8, 0, 0, 0, time.FixedZone("CST", 8*3600))
+	return &Feed{
+		Title:       "博客",
+		Description: "简介",
+		Link:        "https://blog.example.com/",
+		FeedURL:     "https://blog.example.com/feed.xml",
+		Updated:     published.Add(time.Hour),
+		Items: []Item{{
+			Link:        "https://blog.example.com/p/1",
+			Title:       "标题 & <符号>",
+			ContentHTML: "<p>内容</p>",
+			Author:      Author{Name: "alice", URL: "https://blog.example.com/u/1"},
+			Tags:        []string{"go", "gin"},
+			Published:   published,
+			Updated:     published.Add(time.Hour),
+		}},
+	}
+}
+
+func TestRSS(t *testing.T) {
+	data, err := Encode(testFeed(), RSS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Version string `xml:"version,attr"`
+		Channel struct {
+			Title         string `xml:"title"`
+			LastBuildDate string `xml:"lastBuildDate"`
+			Items         []struct {
+				Title       string   `xml:"title"`
+				GUID        string   `xml:"guid"`
+				PubDate     string   `xml:"pubDate"`
+				Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+				Categories  []string `xml:"category"`
+				Description string   `xml:"description"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("RSS 不是合法的 XML: %v\n%s", err, data)
+	}
+	item := doc.Channel.Items[0]
+	if doc.Version != "2.0" || item.Title != "标题 & <符号>" || item.GUID != "https://blog.example.com/p/1" ||
+		item.PubDate != "Wed, 01 May 2024 00:00:00 +0000" || item.Creator != "alice" ||
+		strings.Join(item.Categories, ",") != "go,gin" || item.Description != "<p>内容</p>" ||
+		doc.Channel.LastBuildDate != "Wed, 01 May 2024 01:00:00 +0000" {
+		t.Fatalf("RSS 内容不符合预期: %+v", doc)
+	}
+	if !strings.Contains(string(data), `<atom:link href="https://blog.example.com/feed.xml" rel="self"`) {
+		t.Fatalf("RSS 缺少自身地址:\n%s", data)
+	}
+}
+
+func TestAtom(t *testing.T) {
+	data, err := Encode(testFeed(), Atom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		Updated string   `xml:"updated"`
+		Entries []struct {
+			ID        string `xml:"id"`
+			Published string `xml:"published"`
+			Author    struct {
+				Name string `xml:"name"`
+				URI  string `xml:"uri"`
+			} `xml:"author"`
+			Content struct {
+				Type string `xml:"type,attr"`
+				Body string `xml:",chardata"`
+			} `xml:"content"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Atom 不是合法的 XML: %v\n%s", err, data)
+	}
+	entry := doc.Entries[0]
+	if doc.Updated != "2024-05-01T01:00:00Z" || entry.ID != "https://blog.example.com/p/1" ||
+		entry.Published != "2024-05-01T00:00:00Z" || entry.Author.Name != "alice" ||
+		entry.Content.Type != "html" || entry.Content.Body != "<p>内容</p>" {
+		t.Fatalf("Atom 内容不符合预期: %+v", doc)
+	}
+
+	// 空的订阅源也必须有 updated，并且每次输出相同
+	empty := &Feed{Title: "空", Link: "https://blog.example.com/", FeedURL: "https://blog.example.com/atom.xml"}
+	a, _ := Encode(empty, Atom)
+	b, _ := Encode(empty, Atom)
+	if !strings.Contains(string(a), "<updated>1970-01-01T00:00:00Z</updated>") || string(a) != string(b) {
+		t.Fatalf("空的 Atom 订阅源不符合预期:\n%s", a)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data, err := Encode(testFeed(), JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("JSON Feed 不是合法的 JSON: %v", err)
+	}
+	item := doc["items"].([]any)[0].(map[string]any)
+	author := item["authors"].([]any)[0].(map[string]any)
+	if doc["version"] != "https://jsonfeed.org/version/1.1" || doc["feed_url"] != "https://blog.example.com/feed.xml" ||
+		item["id"] != "https://blog.example.com/p/1" || item["date_published"] != "2024-05-01T00:00:00Z" ||
+		author["name"] != "alice" || author["url"] != "https://blog.example.com/u/1" {
+		t.Fatalf("JSON Feed 内容不符合预期: %s", data)
+	}
+
+	// 没有文章时 items 是空数组而不是 null
+	data, _ = Encode(&Feed{Title: "空"}, JSON)
+	if !strings.Contains(string(data), `"items": []`) {
+		t.Fatalf("空的 JSON Feed 应该有空的 items: %s", data)
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	if _, err := Encode(testFeed(), Format("csv")); err == nil {
+		t.Fatal("不支持的格式应该返回错误")
+	}
+}
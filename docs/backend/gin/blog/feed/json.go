@@ -0,0 +1,58 @@
+package feed
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// jsonFeedVersion 是 JSON Feed 1.1 的版本标识
+const jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+
+type jsonFeed struct {
+	Version     string     `json:"version"`
+	Title       string     `json:"title"`
+	HomePageURL string     `json:"home_page_url"`
+	FeedURL     string     `json:"feed_url"`
+	Description string     `json:"description,omitempty"`
+	Items       []jsonItem `json:"items"`
+}
+
+type jsonItem struct {
+	ID            string       `json:"id"`
+	URL           string       `json:"url"`
+	Title         string       `json:"title"`
+	ContentHTML   string       `json:"content_html"`
+	DatePublished time.Time    `json:"date_published"`
+	DateModified  time.Time    `json:"date_modified"`
+	Authors       []jsonAuthor `json:"authors"`
+	Tags          []string     `json:"tags,omitempty"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+func encodeJSON(f *Feed) ([]byte, error) {
+	doc := jsonFeed{
+		Version:     jsonFeedVersion,
+		Title:       f.Title,
+		HomePageURL: f.Link,
+		FeedURL:     f.FeedURL,
+		Description: f.Description,
+		Items:       []jsonItem{},
+	}
+	for _, item := range f.Items {
+		doc.Items = append(doc.Items, jsonItem{
+			ID:            item.Link,
+			URL:           item.Link,
+			Title:         item.Title,
+			ContentHTML:   item.ContentHTML,
+			DatePublished: item.Published.UTC(),
+			DateModified:  item.Updated.UTC(),
+			Authors:       []jsonAuthor{{Name: item.Author.Name, URL: item.Author.URL}},
+			Tags:          item.Tags,
+		})
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
@@ -2,20 +2,88 @@ package main
 
 import (
 	routes "blog/Routes"
+	"blog/app"
 	"blog/config"
+	"blog/middle"
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
+// shutdownTimeout 是收到退出信号后等待进行中的请求完成的最长时间
+const shutdownTimeout = 10 * time.Second
+
 func main() {
-	config.InitLog()
+	// 命令行参数优先级最高：配置文件 < 环境变量 < 命令行
+	configPath := flag.String("config", "", "配置文件路径（.yaml/.yml/.toml），为空时只使用默认值和环境变量")
+	addr := flag.String("addr", "", "监听地址，覆盖配置中的 server.addr，例如 :8080")
+	flag.Parse()
+
+	// 加载并校验配置
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("❌ 加载配置失败: %v", err)
+	}
+	if *addr != "" {
+		cfg.Server.Addr = *addr
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("❌ 配置校验失败:\n%v", err)
+	}
+
+	config.InitLog(cfg.Log)
+	middle.InitJWT(cfg.JWT)
 	// 创建数据库
-	config.CreatDB()
+	config.CreatDB(cfg.Database)
 	// 初始化数据库
-	config.InitDB()
+	db := config.InitDB(cfg.Database)
 	//迁移数据库结构
-	config.MigrateDB()
+	config.MigrateDB(db)
+	// 组装依赖
+	deps := app.NewContainer(cfg, db)
+	// 提升配置中指定的管理员
+	if err := deps.PromoteAdmins(context.Background()); err != nil {
+		log.Fatalf("❌ 初始化管理员失败: %v", err)
+	}
+	// 从数据库重建搜索索引
+	if err := deps.Reindex(context.Background()); err != nil {
+		log.Fatalf("❌ 重建搜索索引失败: %v", err)
+	}
+	// 收到 Ctrl+C 或 SIGTERM 时取消 ctx，停止调度器和服务器
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// 后台发布到期的定时文章
+	schedulerDone := make(chan struct{})
+	go func() {
+		defer close(schedulerDone)
+		deps.RunScheduler(ctx, time.Duration(cfg.Scheduler.Interval))
+	}()
 	// 设置路由
-	r := routes.SetupRouter()
+	r := routes.SetupRouter(deps)
 	// 运行服务器
-	config.Log.Info("服务器启动在 :8080 端口")
-	r.Run(":8080")
+	srv := &http.Server{Addr: cfg.Server.Addr, Handler: r.Handler()}
+	go func() {
+		config.Log.Infof("服务器启动在 %s", cfg.Server.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("❌ 服务器启动失败: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	config.Log.Info("正在关闭服务器")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	// 实时推送等长连接不会自己结束，超时后直接关闭
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+	}
+	<-schedulerDone
+	config.Log.Info("服务器已关闭")
 }
@@ -1,37 +1,96 @@
 package middle
 
 import (
-	"net/http"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"strings"
 	"time"
 
 	"blog/config"
+	"blog/models"
+	"blog/repository"
+	"gin-demo/res"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/sirupsen/logrus"
 )
 
+// Token 类型，写在 typ 声明中，防止 refresh token 被当作 access token 使用（反之亦然）
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
 type JWTclaims struct {
-	ID uint
+	ID        uint        // 用户 ID
+	Role      models.Role `json:"role"` // 签发时的用户角色，角色变更时会吊销该用户的全部会话
+	SessionID string      `json:"sid"`  // 所属会话，用于吊销
+	Type      string      `json:"typ"`  // access 或 refresh
 	jwt.RegisteredClaims
 }
 
-// 定义用于签名和验证 JWT 的密钥
-var jwtKey = []byte("insert_your_own_secret_key")
+// TokenPair 是登录和刷新时返回给客户端的一对 token
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // access token 的有效秒数
+}
+
+// 用于签名和验证 JWT 的配置，由 InitJWT 在启动时注入
+var jwtConfig config.JWTConfig
+
+// InitJWT 注入 JWT 的密钥、签发者和有效期
+func InitJWT(cfg config.JWTConfig) {
+	jwtConfig = cfg
+}
+
+// RefreshExpiry 返回从 now 开始计算的 refresh token 过期时间，也就是会话的过期时间
+func RefreshExpiry(now time.Time) time.Time {
+	return now.Add(time.Duration(jwtConfig.RefreshExpire))
+}
+
+// NewJTI 生成随机的 token / 会话标识
+func NewJTI() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// GenerateTokenPair 为会话签发 access token 和 refresh token，refreshJTI 必须和会话中记录的一致
+func GenerateTokenPair(user *models.User, sessionID, refreshJTI string) (*TokenPair, error) {
+	now := time.Now()
+	access, err := signToken(user, sessionID, TokenTypeAccess, NewJTI(), now, now.Add(time.Duration(jwtConfig.Expire)))
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := signToken(user, sessionID, TokenTypeRefresh, refreshJTI, now, RefreshExpiry(now))
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    int64(time.Duration(jwtConfig.Expire).Seconds()),
+	}, nil
+}
 
-// GenerateToken 根据用户 ID 生成 JWT Token
-func GenerateToken(userID uint, c *gin.Context) (string, error) {
+func signToken(user *models.User, sessionID, typ, jti string, now, expiresAt time.Time) (string, error) {
 	claims := JWTclaims{
-		ID: userID,
+		ID:        user.ID,
+		Role:      user.Role,
+		SessionID: sessionID,
+		Type:      typ,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24)),
-			Issuer:    "your_app_name",
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			Issuer:    jwtConfig.Issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtKey)
+	tokenString, err := token.SignedString([]byte(jwtConfig.Secret))
 	if err != nil {
 		config.Log.Error("系统错误：JWT 签名失败 ", err)
 		return "", err
@@ -39,8 +98,34 @@ func GenerateToken(userID uint, c *gin.Context) (string, error) {
 	return tokenString, nil
 }
 
-// JWTAuthMiddleware 是一个 Gin 中间件函数，用于验证请求中的 JWT Token
-func JWTAuthMiddleware() gin.HandlerFunc {
+// ParseToken 校验签名、有效期和 token 类型，并检查必要的声明是否齐全
+func ParseToken(tokenStr, typ string) (*JWTclaims, error) {
+	claims := &JWTclaims{}
+	// 只接受 HS256，防止算法被替换
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(jwtConfig.Secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	//检查验证结果
+	// !token.Valid 确保 Token 最终被标记为“有效”
+	if !token.Valid {
+		return nil, errors.New("Token 无效或已过期")
+	}
+	if claims.Type != typ {
+		return nil, errors.New("Token 类型错误")
+	}
+	// 防御性编程：如果 Token 里没有用户 ID 或会话 ID
+	if claims.ID == 0 || claims.SessionID == "" {
+		return nil, errors.New("Token 缺少 ID 或 sid 字段")
+	}
+	return claims, nil
+}
+
+// JWTAuthMiddleware 是一个 Gin 中间件函数，用于验证请求中的 JWT Token，
+// 并通过会话表检查 token 所属的会话是否已被吊销
+func JWTAuthMiddleware(sessions repository.SessionRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		// 获取 Authorization Header，标准格式是"Authorization: Bearer <token>"
@@ -52,53 +137,72 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 				"ip": c.ClientIP(),
 			}).Warn("请求失败：未提供 token")
 			// 如果没有提供 Authorization Header，则返回 401 未授权错误
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "未提供 token"})
-			// 中止请求，后续的中间件和目标路由处理函数（Handler）都不会被执行
-			c.Abort()
+			res.FailCode(c, res.CodeUnauthorized, "未提供 token")
 			return
 		}
-		//提取 Token 字符串
-		tokenStr := strings.TrimPrefix(AuthHeader, "Bearer ")
-
-		// jwt.MapClaims 是 map[string]interface{} 的别名，用于存储键值对数据
-		claims := jwt.MapClaims{}
-		// 解析和验证 Token
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
-		//检查验证结果
-		// !token.Valid 确保 Token 最终被标记为“有效”
-		if err != nil || !token.Valid {
-			// 必须处理 err 为 nil 的情况，防止 panic
-			var errMsg string
-			if err != nil {
-				// 当 err 不为 nil 时，使用其错误信息
-				errMsg = err.Error()
-			} else {
-				// 当 err 为 nil 时，说明 Token 无效但没有具体错误信息
-				errMsg = "Token 无效或已过期"
-			}
-			// [日志] 记录 Token 验证失败的信息
-			config.Log.WithFields(logrus.Fields{
-				"ip":    c.ClientIP(),
-				"error": errMsg, // 使用安全的变量
-			}).Warn("Token 验证失败")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的 token"})
-			// 中止请求，后续的中间件和目标路由处理函数（Handler）都不会被执行
-			c.Abort()
+		if !authenticate(c, sessions, AuthHeader) {
 			return
 		}
-		// 从 claims 中提取用户 ID 并设置到 Gin 的上下文中，供后续处理函数使用,并转换为 uint 类型
-		if id, ok := claims["ID"].(float64); ok {
-			c.Set("user_id", uint(id))
-		} else {
-			// 防御性编程：如果 Token 里没有 ID 字段
-			config.Log.Error("Token 解析异常：缺少 ID 字段")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token 格式错误"})
-			c.Abort()
+		c.Next()
+	}
+}
+
+// OptionalAuth 用于匿名用户也可以访问的路由（例如阅读文章）：
+// 没有 Authorization 请求头时按匿名用户处理，上下文中没有 user_id（GetUint 返回 0）；
+// 带了 token 时和 JWTAuthMiddleware 一样验证，token 无效或会话已失效仍然返回 401，
+// 而不是悄悄降级为匿名用户，客户端才知道需要刷新 token 或重新登录
+func OptionalAuth(sessions repository.SessionRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		AuthHeader := c.GetHeader("Authorization")
+		if AuthHeader != "" && !authenticate(c, sessions, AuthHeader) {
 			return
 		}
-
 		c.Next()
 	}
 }
+
+// authenticate 验证 Authorization 请求头中的 access token 及其所属会话，
+// 通过时把用户 ID、角色和会话 ID 写入上下文并返回 true，不通过时已经写好错误响应
+func authenticate(c *gin.Context, sessions repository.SessionRepository, AuthHeader string) bool {
+	//提取 Token 字符串
+	tokenStr := strings.TrimPrefix(AuthHeader, "Bearer ")
+
+	// 解析和验证 Token
+	claims, err := ParseToken(tokenStr, TokenTypeAccess)
+	if err != nil {
+		// [日志] 记录 Token 验证失败的信息
+		config.Log.WithFields(logrus.Fields{
+			"ip":    c.ClientIP(),
+			"error": err.Error(),
+		}).Warn("Token 验证失败")
+		res.FailCode(c, res.CodeUnauthorized, "无效的 token")
+		return false
+	}
+
+	// 检查会话是否已被吊销（注销、刷新令牌被盗用等）
+	session, err := sessions.FindByJTI(c.Request.Context(), claims.SessionID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		config.Log.WithFields(logrus.Fields{
+			"session_id": claims.SessionID,
+			"error":      err.Error(),
+		}).Error("Token 验证失败：查询会话失败")
+		res.FailCode(c, res.CodeServerError, "服务器错误")
+		return false
+	}
+	if err != nil || session.UserID != claims.ID || !session.Active(time.Now()) {
+		// [日志] 记录会话失效的信息
+		config.Log.WithFields(logrus.Fields{
+			"ip":         c.ClientIP(),
+			"user_id":    claims.ID,
+			"session_id": claims.SessionID,
+		}).Warn("Token 验证失败：会话已失效")
+		res.FailCode(c, res.CodeUnauthorized, "登录已失效，请重新登录")
+		return false
+	}
+
+	// 把用户 ID、角色和会话 ID 设置到 Gin 的上下文中，供后续处理函数使用
+	c.Set("user_id", claims.ID)
+	c.Set("role", claims.Role)
+	c.Set("session_id", claims.SessionID)
+	return true
+}
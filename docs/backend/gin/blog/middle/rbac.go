@@ -0,0 +1,54 @@
+package middle
+
+import (
+	"blog/config"
+	"blog/models"
+	"gin-demo/res"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// CurrentRole 返回 JWTAuthMiddleware 写入上下文的当前用户角色
+func CurrentRole(c *gin.Context) models.Role {
+	role, _ := c.Get("role")
+	r, _ := role.(models.Role)
+	return r
+}
+
+// RequireRole 只允许指定角色访问，必须放在 JWTAuthMiddleware 之后
+func RequireRole(roles ...models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := CurrentRole(c)
+		for _, r := range roles {
+			if r == role {
+				c.Next()
+				return
+			}
+		}
+		forbidden(c, role)
+	}
+}
+
+// RequirePermission 只允许拥有指定权限的角色访问，必须放在 JWTAuthMiddleware 之后
+func RequirePermission(p models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := CurrentRole(c)
+		if !role.Can(p) {
+			forbidden(c, role)
+			return
+		}
+		c.Next()
+	}
+}
+
+func forbidden(c *gin.Context, role models.Role) {
+	// [日志] 记录越权访问的信息
+	config.Log.WithFields(logrus.Fields{
+		"ip":      c.ClientIP(),
+		"user_id": c.GetUint("user_id"),
+		"role":    role,
+		"path":    c.FullPath(),
+	}).Warn("请求失败：权限不足")
+	res.FailCode(c, res.CodeForbidden, "权限不足")
+}
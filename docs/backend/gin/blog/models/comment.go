@@ -4,11 +4,28 @@ import (
 	"gorm.io/gorm"
 )
 
+// 评论的审核状态
+const (
+	CommentApproved = "approved" // 已公开
+	CommentPending  = "pending"  // 等待文章作者审核，只有评论者、文章作者和版主可见
+	CommentDeleted  = "deleted"  // 已删除但还有回复，保留为占位符，所有人可见
+)
+
+// DeletedCommentContent 是已删除评论的占位内容，原内容在删除时被清除
+const DeletedCommentContent = "[deleted]"
+
 type Comment struct {
 	gorm.Model
 	Content string `gorm:"type:text;size:500;not null"  json:"Content"`
+	Status  string `gorm:"type:varchar(10);not null;default:approved;index" json:"status"`
 	UserID  uint   `gorm:"not null" json:"user_id"`
 	User    User   `gorm:"foreignKey:UserID;references:ID"`
 	PostID  uint   `gorm:"not null" json:"post_id"`
 	Post    Post   `gorm:"foreignKey:PostID;references:ID"`
+	// ParentID 是回复的评论，顶层评论为空；Depth 是嵌套层数，顶层评论为 0
+	ParentID *uint `gorm:"index" json:"parent_id"`
+	Depth    int   `gorm:"not null;default:0" json:"depth"`
+	// 由 Reaction 的钩子维护的计数，只用于输出：创建评论的请求体中没有这两个字段
+	LikeCount     int `gorm:"not null;default:0" json:"like_count"`
+	ReactionCount int `gorm:"not null;default:0" json:"reaction_count"`
 }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Follow 表示 FollowerID 关注了 FolloweeID。唯一索引以 follower_id 开头，
+// 同时用于读取某个用户关注的人（生成 feed）；followee_id 上的索引用于读取粉丝列表
+type Follow struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	FollowerID uint      `gorm:"not null;uniqueIndex:idx_follow_pair" json:"follower_id"`
+	Follower   User      `gorm:"foreignKey:FollowerID" json:"-"`
+	FolloweeID uint      `gorm:"not null;uniqueIndex:idx_follow_pair;index" json:"followee_id"`
+	Followee   User      `gorm:"foreignKey:FolloweeID" json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+}
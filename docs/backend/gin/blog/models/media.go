@@ -0,0 +1,32 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Media 是上传到文章的图片或附件，文件内容保存在 storage.Storage 中，这里只保存元数据。
+// 文件通过 /media/:key 访问，Key 是随机生成的，知道地址就可以读取，和文章是否公开无关
+type Media struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	PostID      uint      `gorm:"not null;index" json:"post_id"`
+	UserID      uint      `gorm:"not null;index" json:"user_id"` // 上传的用户
+	Key         string    `gorm:"type:varchar(100);not null;uniqueIndex" json:"key"`
+	Filename    string    `gorm:"type:varchar(255);not null" json:"filename"`     // 上传时的文件名，只用于显示和下载
+	ContentType string    `gorm:"type:varchar(100);not null" json:"content_type"` // 根据文件内容检测出的类型，不信任客户端声明的类型
+	Size        int64     `gorm:"not null" json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// 访问地址，由控制器填充，不保存到数据库
+	URL string `gorm:"-" json:"url"`
+}
+
+// TableName 固定表名为 media（media 本身就是复数）
+func (Media) TableName() string {
+	return "media"
+}
+
+// IsImage 判断文件是否是图片，图片可以在页面中直接显示，其他文件以附件的形式下载
+func (m *Media) IsImage() bool {
+	return strings.HasPrefix(m.ContentType, "image/")
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// 通知的类型
+const (
+	NotificationComment = "comment" // 文章收到了评论（包括待审核的评论）
+	NotificationReply   = "reply"   // 评论收到了回复
+	NotificationFollow  = "follow"  // 被其他用户关注
+)
+
+// Notification 是发给用户的通知，由 app 包订阅领域事件后生成。
+// 通知只是提醒，不和文章、评论做外键关联：它们被删除后通知仍然保留
+type Notification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index:idx_notification_user_read" json:"user_id"` // 接收通知的用户
+	ActorID   uint       `gorm:"not null" json:"actor_id"`                                 // 触发通知的用户
+	Actor     User       `gorm:"foreignKey:ActorID" json:"-"`
+	Type      string     `gorm:"type:varchar(20);not null" json:"type"`
+	PostID    *uint      `json:"post_id"`
+	CommentID *uint      `json:"comment_id"`
+	ReadAt    *time.Time `gorm:"index:idx_notification_user_read" json:"read_at"` // 为空表示未读
+	CreatedAt time.Time  `json:"created_at"`
+}
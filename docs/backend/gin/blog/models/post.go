@@ -1,14 +1,51 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
+// 文章的评论模式
+const (
+	CommentModeAuto   = "auto"   // 评论直接发布
+	CommentModeReview = "review" // 评论需要文章作者审核后才公开
+)
+
+// 文章内容的格式，决定 ?render=html 时如何渲染
+const (
+	FormatPlain    = "plain"    // 纯文本，按原样显示，空行分段
+	FormatMarkdown = "markdown" // GitHub 风格的 Markdown
+)
+
+// 文章的发布状态
+const (
+	PostDraft     = "draft"     // 草稿，只有作者可见
+	PostScheduled = "scheduled" // 定时发布，到达 PublishAt 后由调度器发布，发布前只有作者可见
+	PostPublished = "published" // 已发布
+	PostArchived  = "archived"  // 已归档，不出现在列表和搜索中，但仍可以通过 ID 访问
+)
+
 type Post struct {
 	gorm.Model
-	Title    string    `gorm:"type:varchar(100);size:100;not null" json:"Title"`
-	Content  string    `gorm:"type:text;size:100000;not null" json:"Content"`
-	UserID   uint      `gorm:"not null" json:"user_id"`
-	User     User      `gorm:"foreignKey:UserID;references:ID"`
-	Comments []Comment `gorm:"foreignKey:PostID"`
+	Title       string     `gorm:"type:varchar(100);size:100;not null" json:"Title"`
+	Content     string     `gorm:"type:text;size:100000;not null" json:"Content"`
+	Format      string     `gorm:"type:varchar(10);not null;default:plain" json:"format"`
+	CommentMode string     `gorm:"type:varchar(10);not null;default:auto" json:"comment_mode"`
+	Status      string     `gorm:"type:varchar(10);not null;default:published;index" json:"status"`
+	PublishAt   *time.Time `json:"publish_at"`                        // 定时发布的时间，只对 scheduled 状态有效
+	PublishedAt *time.Time `json:"published_at"`                      // 第一次发布的时间
+	Version     uint       `gorm:"not null;default:1" json:"version"` // 每次修改加一，用于乐观并发控制（ETag / If-Match）
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	User        User       `gorm:"foreignKey:UserID;references:ID"`
+	Comments    []Comment  `gorm:"foreignKey:PostID"`
+	Tags        []Tag      `gorm:"many2many:post_tags;" json:"tags"`
+	Categories  []Category `gorm:"many2many:post_categories;" json:"categories"`
+
+	// 由 Reaction 的钩子维护的计数，只用于输出：创建和修改文章的请求体中没有这两个字段，保存文章时也不会覆盖
+	LikeCount     int `gorm:"not null;default:0;index" json:"like_count"`
+	ReactionCount int `gorm:"not null;default:0" json:"reaction_count"`
+
+	// 渲染后的 HTML，只在请求 ?render=html 时填充，不保存到数据库
+	ContentHTML string `gorm:"-" json:"content_html,omitempty"`
 }
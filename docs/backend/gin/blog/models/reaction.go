@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 反应的目标类型
+const (
+	ReactionTargetPost    = "post"
+	ReactionTargetComment = "comment"
+)
+
+// ReactionLike 是点赞，总是可以使用；其他表情反应由配置 reactions.emojis 决定
+const ReactionLike = "like"
+
+// Reaction 是用户对文章或评论的反应（点赞或表情）。
+// 每个用户对每个目标只能有一个反应，由唯一索引保证；取消反应时直接删除记录，
+// 所以没有软删除字段，否则唯一索引会挡住再次点赞
+type Reaction struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_reaction_user_target" json:"user_id"`
+	TargetType string    `gorm:"type:varchar(10);not null;uniqueIndex:idx_reaction_user_target;index:idx_reaction_target" json:"target_type"`
+	TargetID   uint      `gorm:"not null;uniqueIndex:idx_reaction_user_target;index:idx_reaction_target" json:"target_id"`
+	Kind       string    `gorm:"type:varchar(20);not null" json:"kind"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AfterCreate 新增反应后，更新目标上的计数
+func (r *Reaction) AfterCreate(tx *gorm.DB) error {
+	return r.count(tx, 1)
+}
+
+// AfterDelete 删除反应后，更新目标上的计数。删除时必须传入完整的记录，而不是只有主键
+func (r *Reaction) AfterDelete(tx *gorm.DB) error {
+	return r.count(tx, -1)
+}
+
+// count 调整目标的反应数和点赞数。使用 UpdateColumns，不会修改目标的 updated_at 和版本号
+func (r *Reaction) count(tx *gorm.DB, delta int) error {
+	var target any = &Post{}
+	if r.TargetType == ReactionTargetComment {
+		target = &Comment{}
+	}
+	columns := map[string]any{"reaction_count": gorm.Expr("reaction_count + ?", delta)}
+	if r.Kind == ReactionLike {
+		columns["like_count"] = gorm.Expr("like_count + ?", delta)
+	}
+	return tx.Model(target).Where("id = ?", r.TargetID).UpdateColumns(columns).Error
+}
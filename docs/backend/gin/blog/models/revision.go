@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// PostRevision 是文章每次保存后的快照，修订号 Number 在同一篇文章内从 1 开始递增。
+// 修订只会追加不会修改，恢复旧版本也是追加一条新的修订
+type PostRevision struct {
+	ID        uint      `gorm:"primarykey" json:"-"`
+	PostID    uint      `gorm:"not null;uniqueIndex:idx_post_revision" json:"post_id"`
+	Number    int       `gorm:"not null;uniqueIndex:idx_post_revision" json:"number"`
+	Title     string    `gorm:"type:varchar(100);size:100;not null" json:"title"`
+	Content   string    `gorm:"type:text;size:100000;not null" json:"content"`
+	Format    string    `gorm:"type:varchar(10);not null;default:plain" json:"format"`
+	UserID    uint      `gorm:"not null" json:"user_id"` // 保存这个版本的用户
+	CreatedAt time.Time `json:"created_at"`
+}
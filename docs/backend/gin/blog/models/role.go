@@ -0,0 +1,47 @@
+package models
+
+// Role 是用户的角色，决定用户拥有哪些权限
+type Role string
+
+const (
+	RoleAdmin     Role = "admin"     // 管理员：拥有全部权限，可以管理用户
+	RoleModerator Role = "moderator" // 版主：可以删除和审核任何评论
+	RoleAuthor    Role = "author"    // 作者：可以发表文章，新注册用户的默认角色
+	RoleReader    Role = "reader"    // 读者：只能阅读和评论
+)
+
+// Permission 是可以授予角色的一项操作权限
+type Permission string
+
+const (
+	PermCreatePost       Permission = "post:create"
+	PermDeleteAnyPost    Permission = "post:delete:any"
+	PermDeleteAnyComment Permission = "comment:delete:any"
+	// PermModerateAnyComment 可以审核任何文章下的评论；文章作者总是可以审核自己文章下的评论
+	PermModerateAnyComment Permission = "comment:moderate:any"
+	PermManageUsers        Permission = "user:manage"
+)
+
+// rolePermissions 是角色到权限的映射，修改授权规则只需要改这里
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin:     {PermCreatePost, PermDeleteAnyPost, PermDeleteAnyComment, PermModerateAnyComment, PermManageUsers},
+	RoleModerator: {PermCreatePost, PermDeleteAnyComment, PermModerateAnyComment},
+	RoleAuthor:    {PermCreatePost},
+	RoleReader:    {},
+}
+
+// Valid 判断是否是已定义的角色
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Can 判断角色是否拥有指定权限
+func (r Role) Can(p Permission) bool {
+	for _, perm := range rolePermissions[r] {
+		if perm == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// Session 记录一次登录会话，access token 和 refresh token 都通过 sid 声明关联到它。
+// 注销或发现 refresh token 被重复使用时会设置 RevokedAt，之后该会话签发的所有 token 都会失效
+type Session struct {
+	ID         uint       `gorm:"primarykey" json:"-"`
+	JTI        string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"jti"` // 会话标识
+	UserID     uint       `gorm:"not null;index" json:"user_id"`                    // 所属用户
+	RefreshJTI string     `gorm:"type:varchar(64);not null" json:"-"`               // 当前有效的 refresh token 的 jti，每次刷新都会轮换
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`                       // refresh token 的过期时间
+	RevokedAt  *time.Time `json:"revoked_at"`                                       // 吊销时间，为空表示有效
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Active 判断会话在 now 时刻是否仍然有效
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
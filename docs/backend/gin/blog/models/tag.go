@@ -0,0 +1,23 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Term 是标签和分类共有的字段，名称全局唯一
+type Term struct {
+	gorm.Model
+	Name string `gorm:"type:varchar(30);size:30;not null;uniqueIndex" json:"name"`
+}
+
+// Tag 和 Post 是多对多关系，通过 post_tags 连接表关联
+type Tag struct {
+	Term
+	Posts []Post `gorm:"many2many:post_tags;" json:"-"`
+}
+
+// Category 和 Post 是多对多关系，通过 post_categories 连接表关联
+type Category struct {
+	Term
+	Posts []Post `gorm:"many2many:post_categories;" json:"-"`
+}
@@ -8,6 +8,7 @@ type User struct {
 	gorm.Model
 	Name     string `gorm:"type:varchar(20);not null"`
 	Email    string `gorm:"type:varchar(100);not null;unique"`
-	Password string `gorm:"type:varchar(255);not null"`
+	Password string `gorm:"type:varchar(255);not null" json:"-"`                  // 密码哈希，不能出现在任何响应中
+	Role     Role   `gorm:"type:varchar(20);not null;default:author" json:"role"` // 用户角色，见 role.go
 	Posts    []Post `gorm:"foreignKey:UserID"`
 }
@@ -0,0 +1,67 @@
+package render
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Key 标识一次渲染的结果：文章每次修改都会递增版本号，旧版本的结果不会再被命中，
+// 最终因为最近最少使用而被淘汰，不需要在修改文章时主动清理
+type Key struct {
+	ID      uint
+	Version uint
+}
+
+// Cache 是渲染结果的 LRU 缓存，并发安全。容量为 0 时不缓存
+type Cache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // 最近使用的在前，元素是 *entry
+	entries  map[Key]*list.Element
+}
+
+type entry struct {
+	key  Key
+	html string
+}
+
+func NewCache(capacity int) *Cache {
+	return &Cache{capacity: capacity, order: list.New(), entries: map[Key]*list.Element{}}
+}
+
+// Get 返回 key 的渲染结果，没有缓存时调用 render 生成并缓存
+func (c *Cache) Get(key Key, render func() string) string {
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		c.mu.Unlock()
+		return el.Value.(*entry).html
+	}
+	c.mu.Unlock()
+
+	// 渲染比较慢，不持有锁；并发的相同请求可能重复渲染，结果相同
+	html := render()
+	if c.capacity <= 0 {
+		return html
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		return html
+	}
+	c.entries[key] = c.order.PushFront(&entry{key: key, html: html})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+	}
+	return html
+}
+
+// Len 返回缓存的条数
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
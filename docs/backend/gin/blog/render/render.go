@@ -0,0 +1,55 @@
+// Package render 把文章内容渲染成可以直接插入页面的 HTML。
+// Markdown 由 goldmark 转换，结果再经过白名单过滤（bluemonday），去掉脚本、事件处理属性和危险链接
+package render
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// markdown 支持表格、删除线、自动链接和任务列表。
+// 没有开启 html.WithUnsafe，Markdown 中的原始 HTML 会被省略，之后还有 policy 兜底
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// policy 在面向用户内容的 UGCPolicy 基础上，允许代码块的语言 class（用于语法高亮）和任务列表的复选框
+var policy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^(|checked|disabled)$`)).OnElements("input")
+	// 外部链接在新窗口打开，并且不传递 referrer 和 window.opener
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}()
+
+// Markdown 把 Markdown 转换成过滤后的 HTML
+func Markdown(src string) string {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(src), &buf); err != nil {
+		// 写入 bytes.Buffer 不会失败，转换出错时退回纯文本
+		return Plain(src)
+	}
+	return policy.Sanitize(buf.String())
+}
+
+// Plain 转义纯文本：空行分隔的段落放在 <p> 中，段落内的换行转换为 <br>
+func Plain(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	var b strings.Builder
+	for _, para := range strings.Split(src, "\n\n") {
+		para = strings.Trim(para, "\n")
+		if strings.TrimSpace(para) == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(para), "\n", "<br>\n"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,91 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	got := Markdown("# 标题\n\n**粗体** ~~删除~~ https://example.com\n\n| a | b |\n| - | - |\n| 1 | 2 |\n\n- [x] 完成\n\n```go\nfmt.Println(\"<b>\")\n```\n")
+	for _, want := range []string{
+		"<h1>标题</h1>",
+		"<strong>粗体</strong>",
+		"<del>删除</del>",
+		`<a href="https://example.com" rel="nofollow noopener" target="_blank">https://example.com</a>`,
+		"<td>1</td>",
+		`<input checked="" disabled="" type="checkbox">`,
+		`<code class="language-go">fmt.Println(&#34;&lt;b&gt;&#34;)`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("渲染结果中缺少 %s\n%s", want, got)
+		}
+	}
+}
+
+func TestMarkdownSanitize(t *testing.T) {
+	for _, src := range []string{
+		"<script>alert(1)</script>",
+		`<img src="x" onerror="alert(1)">`,
+		"[点我](javascript:alert(1))",
+		`<a href="https://example.com" onclick="alert(1)">链接</a>`,
+		"![图片](x\" onerror=\"alert(1))",
+		`<iframe src="https://evil.example"></iframe>`,
+		"<style>body{display:none}</style>",
+		`<svg onload="alert(1)"></svg>`,
+		"```js\n</code><script>alert(1)</script>\n```",
+	} {
+		got := strings.ToLower(Markdown(src))
+		// 被转义成文本的内容（例如 onerror=&#34;）是无害的，只检查真正的标签和属性
+		for _, bad := range []string{"<script", `onerror="`, `onclick="`, `onload="`, "javascript:", "<iframe", "<style", "<svg"} {
+			if strings.Contains(got, bad) {
+				t.Errorf("%q 的渲染结果中不应包含 %s: %s", src, bad, got)
+			}
+		}
+	}
+}
+
+func TestPlain(t *testing.T) {
+	got := Plain("第一段<script>\r\n第二行\n\n\n第二段 **不是粗体**\n")
+	want := "<p>第一段&lt;script&gt;<br>\n第二行</p>\n<p>第二段 **不是粗体**</p>\n"
+	if got != want {
+		t.Fatalf("Plain = %q，期望 %q", got, want)
+	}
+	if Plain(" \n\n ") != "" {
+		t.Fatal("空白内容应渲染为空")
+	}
+}
+
+func TestCache(t *testing.T) {
+	cache := NewCache(2)
+	calls := 0
+	get := func(id, version uint) string {
+		return cache.Get(Key{id, version}, func() string {
+			calls++
+			return "html"
+		})
+	}
+	get(1, 1)
+	get(1, 1)
+	if calls != 1 {
+		t.Fatalf("相同的 key 应该只渲染一次，实际 %d 次", calls)
+	}
+	// 版本号变化后重新渲染，超出容量时淘汰最近最少使用的结果
+	get(1, 2)
+	get(1, 1)
+	get(2, 1)
+	if calls != 3 || cache.Len() != 2 {
+		t.Fatalf("渲染次数 %d，缓存条数 %d", calls, cache.Len())
+	}
+	get(1, 1)
+	if get(1, 2); calls != 4 {
+		t.Fatalf("被淘汰的结果应该重新渲染，实际渲染 %d 次", calls)
+	}
+
+	// 容量为 0 时不缓存
+	cache = NewCache(0)
+	get(1, 1)
+	get(1, 1)
+	if calls != 6 || cache.Len() != 0 {
+		t.Fatalf("容量为 0 时不应缓存: %d %d", calls, cache.Len())
+	}
+}
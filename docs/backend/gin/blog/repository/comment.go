@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"blog/models"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CommentRepository interface {
+	// Create 只保存评论本身的字段，不会写入关联的评论者和文章
+	Create(ctx context.Context, comment *models.Comment) error
+	// FindByID 返回评论，并预加载所属文章（用于判断文章作者的审核权限）
+	FindByID(ctx context.Context, id uint) (*models.Comment, error)
+	// ListByPost 分页返回文章下 opts.Visibility 可见的评论，并预加载评论者和所属文章
+	ListByPost(ctx context.Context, postID uint, opts ListOptions) (*Page[models.Comment], error)
+	// ListTree 用一次查询返回文章下 v 可见的全部评论（JOIN 评论者），按创建时间正序，用于组装评论树
+	ListTree(ctx context.Context, postID uint, v Visibility) ([]models.Comment, error)
+	// SetStatus 修改评论的审核状态，评论不存在时返回 ErrNotFound
+	SetStatus(ctx context.Context, id uint, status string) error
+	// Delete 删除评论。有回复的评论只清除内容，保留为 CommentDeleted 状态的占位符（comment 会被同步修改），
+	// 这样回复仍然挂在原来的位置；删除最后一条回复时，已经是占位符的上级评论也会一并删除
+	Delete(ctx context.Context, comment *models.Comment) error
+}
+
+type gormCommentRepository struct {
+	db *gorm.DB
+}
+
+func NewGormCommentRepository(db *gorm.DB) CommentRepository {
+	return &gormCommentRepository{db: db}
+}
+
+func (r *gormCommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(comment).Error
+}
+
+func (r *gormCommentRepository) FindByID(ctx context.Context, id uint) (*models.Comment, error) {
+	var comment models.Comment
+	if err := r.db.WithContext(ctx).Preload("Post").First(&comment, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &comment, nil
+}
+
+func (r *gormCommentRepository) ListByPost(ctx context.Context, postID uint, opts ListOptions) (*Page[models.Comment], error) {
+	tx := r.db.WithContext(ctx).Model(&models.Comment{}).Where("post_id = ?", postID).Scopes(VisibleComments(opts.Visibility))
+	return listPage(tx, opts, commentCursor, func(tx *gorm.DB) *gorm.DB {
+		return tx.Preload("User").Preload("Post")
+	})
+}
+
+func (r *gormCommentRepository) ListTree(ctx context.Context, postID uint, v Visibility) ([]models.Comment, error) {
+	var comments []models.Comment
+	err := r.db.WithContext(ctx).Joins("User").Where("comments.post_id = ?", postID).
+		Scopes(VisibleComments(v)).Order("comments.created_at, comments.id").Find(&comments).Error
+	return comments, err
+}
+
+func commentCursor(c models.Comment) Cursor {
+	return Cursor{CreatedAt: c.CreatedAt, ID: c.ID}
+}
+
+func (r *gormCommentRepository) SetStatus(ctx context.Context, id uint, status string) error {
+	result := r.db.WithContext(ctx).Model(&models.Comment{}).Where("id = ?", id).Update("status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// commentVisible 是 VisibleComments 的内存版本
+func commentVisible(c models.Comment, v Visibility) bool {
+	return v.All || c.Status == models.CommentApproved || c.Status == models.CommentDeleted ||
+		(v.ViewerID != 0 && c.UserID == v.ViewerID)
+}
+
+func (r *gormCommentRepository) Delete(ctx context.Context, comment *models.Comment) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var replies int64
+		if err := tx.Model(&models.Comment{}).Where("parent_id = ?", comment.ID).Count(&replies).Error; err != nil {
+			return err
+		}
+		if replies > 0 {
+			comment.Status, comment.Content = models.CommentDeleted, models.DeletedCommentContent
+			return tx.Model(comment).Updates(map[string]any{"status": comment.Status, "content": comment.Content}).Error
+		}
+		if err := tx.Delete(comment).Error; err != nil {
+			return err
+		}
+		// 沿着回复链向上清理不再有回复的占位符
+		for parentID := comment.ParentID; parentID != nil; {
+			var parent models.Comment
+			if err := tx.First(&parent, *parentID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if parent.Status != models.CommentDeleted {
+				return nil
+			}
+			if err := tx.Model(&models.Comment{}).Where("parent_id = ?", parent.ID).Count(&replies).Error; err != nil || replies > 0 {
+				return err
+			}
+			if err := tx.Delete(&parent).Error; err != nil {
+				return err
+			}
+			parentID = parent.ParentID
+		}
+		return nil
+	})
+}
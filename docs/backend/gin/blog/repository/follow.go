@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"blog/models"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FollowRepository interface {
+	// Follow 让 followerID 关注 followeeID，返回是否新增了关注；已经关注时不做修改，返回 false
+	Follow(ctx context.Context, followerID, followeeID uint) (bool, error)
+	// Unfollow 取消关注，没有关注时返回 ErrNotFound
+	Unfollow(ctx context.Context, followerID, followeeID uint) error
+	// Followers 分页返回关注 userID 的记录（预加载 Follower），按关注时间排序
+	Followers(ctx context.Context, userID uint, opts ListOptions) (*Page[models.Follow], error)
+	// Following 分页返回 userID 关注的记录（预加载 Followee），按关注时间排序
+	Following(ctx context.Context, userID uint, opts ListOptions) (*Page[models.Follow], error)
+}
+
+type gormFollowRepository struct {
+	db *gorm.DB
+}
+
+func NewGormFollowRepository(db *gorm.DB) FollowRepository {
+	return &gormFollowRepository{db: db}
+}
+
+func (r *gormFollowRepository) Follow(ctx context.Context, followerID, followeeID uint) (bool, error) {
+	follow := models.Follow{FollowerID: followerID, FolloweeID: followeeID}
+	// 重复关注（包括并发的重复请求）由唯一索引忽略
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&follow)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormFollowRepository) Unfollow(ctx context.Context, followerID, followeeID uint) error {
+	result := r.db.WithContext(ctx).Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Delete(&models.Follow{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormFollowRepository) Followers(ctx context.Context, userID uint, opts ListOptions) (*Page[models.Follow], error) {
+	tx := r.db.WithContext(ctx).Model(&models.Follow{}).Where("followee_id = ?", userID).Scopes(activeUser("follower_id"))
+	return listPage(tx, opts, followCursor, func(tx *gorm.DB) *gorm.DB { return tx.Preload("Follower") })
+}
+
+func (r *gormFollowRepository) Following(ctx context.Context, userID uint, opts ListOptions) (*Page[models.Follow], error) {
+	tx := r.db.WithContext(ctx).Model(&models.Follow{}).Where("follower_id = ?", userID).Scopes(activeUser("followee_id"))
+	return listPage(tx, opts, followCursor, func(tx *gorm.DB) *gorm.DB { return tx.Preload("Followee") })
+}
+
+func followCursor(f models.Follow) Cursor {
+	return Cursor{CreatedAt: f.CreatedAt, ID: f.ID}
+}
+
+// activeUser 排除 column 指向的用户已被删除的关注记录
+func activeUser(column string) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("EXISTS (SELECT 1 FROM users WHERE users.id = follows." + column + " AND users.deleted_at IS NULL)")
+	}
+}
+
+// followeesOf 是 userID 关注的人的子查询，Feed 用它在数据库中完成过滤，不需要先把关注列表读到内存
+func followeesOf(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&models.Follow{}).Select("followee_id").Where("follower_id = ?", userID).Scopes(activeUser("followee_id"))
+}
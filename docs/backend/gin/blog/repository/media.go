@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"blog/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// MediaRepository 保存上传文件的元数据，文件内容由 storage.Storage 保存
+type MediaRepository interface {
+	// Create 保存元数据，Key 重复时返回 ErrDuplicate
+	Create(ctx context.Context, media *models.Media) error
+	FindByID(ctx context.Context, id uint) (*models.Media, error)
+	FindByKey(ctx context.Context, key string) (*models.Media, error)
+	// ListByPost 按上传顺序返回文章的全部文件
+	ListByPost(ctx context.Context, postID uint) ([]models.Media, error)
+	// Delete 删除元数据，记录不存在时返回 ErrNotFound
+	Delete(ctx context.Context, id uint) error
+}
+
+type gormMediaRepository struct {
+	db *gorm.DB
+}
+
+func NewGormMediaRepository(db *gorm.DB) MediaRepository {
+	return &gormMediaRepository{db: db}
+}
+
+func (r *gormMediaRepository) Create(ctx context.Context, media *models.Media) error {
+	return translate(r.db.WithContext(ctx).Create(media).Error)
+}
+
+func (r *gormMediaRepository) FindByID(ctx context.Context, id uint) (*models.Media, error) {
+	var media models.Media
+	if err := r.db.WithContext(ctx).First(&media, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &media, nil
+}
+
+func (r *gormMediaRepository) FindByKey(ctx context.Context, key string) (*models.Media, error) {
+	var media models.Media
+	// key 在部分数据库中是保留字，使用结构体条件由 GORM 负责加引号
+	if err := r.db.WithContext(ctx).Where(&models.Media{Key: key}).First(&media).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &media, nil
+}
+
+func (r *gormMediaRepository) ListByPost(ctx context.Context, postID uint) ([]models.Media, error) {
+	media := []models.Media{}
+	err := r.db.WithContext(ctx).Where("post_id = ?", postID).Order("id").Find(&media).Error
+	return media, err
+}
+
+func (r *gormMediaRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.Media{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
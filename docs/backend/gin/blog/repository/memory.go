@@ -0,0 +1,943 @@
+package repository
+
+import (
+	"blog/models"
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Memory 是仓储接口的内存实现，数据只保存在进程内，主要用于处理函数的单元测试
+type Memory struct {
+	mu        sync.RWMutex
+	lastID    uint
+	users     map[uint]models.User
+	posts     map[uint]models.Post
+	comments  map[uint]models.Comment
+	sessions  map[string]models.Session
+	revisions map[uint]models.PostRevision
+	// terms 按名称保存标签和分类，postTerms 模拟连接表：文章 ID -> 名称集合
+	terms     map[Taxonomy]map[string]models.Term
+	postTerms map[Taxonomy]map[uint]map[string]bool
+	// reactions 按用户和目标保存反应，模拟唯一索引
+	reactions map[reactionKey]models.Reaction
+	// follows 按关注者和被关注者保存关注关系，模拟唯一索引
+	follows map[followKey]models.Follow
+
+	notifications map[uint]models.Notification
+	media         map[uint]models.Media
+}
+
+type followKey struct {
+	FollowerID uint
+	FolloweeID uint
+}
+
+type reactionKey struct {
+	UserID     uint
+	TargetType string
+	TargetID   uint
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		users:     map[uint]models.User{},
+		posts:     map[uint]models.Post{},
+		comments:  map[uint]models.Comment{},
+		sessions:  map[string]models.Session{},
+		revisions: map[uint]models.PostRevision{},
+		terms: map[Taxonomy]map[string]models.Term{
+			TaxonomyTag:      {},
+			TaxonomyCategory: {},
+		},
+		postTerms: map[Taxonomy]map[uint]map[string]bool{
+			TaxonomyTag:      {},
+			TaxonomyCategory: {},
+		},
+		reactions: map[reactionKey]models.Reaction{},
+		follows:   map[followKey]models.Follow{},
+
+		notifications: map[uint]models.Notification{},
+		media:         map[uint]models.Media{},
+	}
+}
+
+func (m *Memory) Users() UserRepository         { return memoryUserRepository{m} }
+func (m *Memory) Posts() PostRepository         { return memoryPostRepository{m} }
+func (m *Memory) Comments() CommentRepository   { return memoryCommentRepository{m} }
+func (m *Memory) Sessions() SessionRepository   { return memorySessionRepository{m} }
+func (m *Memory) Revisions() RevisionRepository { return memoryRevisionRepository{m} }
+func (m *Memory) Tags() TaxonomyRepository      { return memoryTaxonomyRepository{m, TaxonomyTag} }
+func (m *Memory) Categories() TaxonomyRepository {
+	return memoryTaxonomyRepository{m, TaxonomyCategory}
+}
+func (m *Memory) Reactions() ReactionRepository { return memoryReactionRepository{m} }
+func (m *Memory) Follows() FollowRepository     { return memoryFollowRepository{m} }
+func (m *Memory) Notifications() NotificationRepository {
+	return memoryNotificationRepository{m}
+}
+func (m *Memory) Media() MediaRepository { return memoryMediaRepository{m} }
+
+// nextID 生成自增主键，调用方需要持有写锁
+func (m *Memory) nextID() uint {
+	m.lastID++
+	return m.lastID
+}
+
+// sortedKeys 按主键升序返回，模拟数据库默认的返回顺序
+func sortedKeys[T any](rows map[uint]T) []uint {
+	ids := make([]uint, 0, len(rows))
+	for id := range rows {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// paginate 在内存中实现和 listPage 相同的过滤、排序、页码/游标分页，
+// match 用来实现只对某种记录有效的过滤条件，为 nil 时不过滤
+func paginate[T any](rows []T, opts ListOptions, key func(T) Cursor, author func(T) uint, match func(T) bool) *Page[T] {
+	opts = opts.Normalize()
+	// before 判断 a 是否排在 b 前面
+	before := func(a, b Cursor) bool {
+		if a.CreatedAt.Equal(b.CreatedAt) {
+			return (a.ID < b.ID) != opts.Desc && a.ID != b.ID
+		}
+		return a.CreatedAt.Before(b.CreatedAt) != opts.Desc
+	}
+
+	matched := filterRows(rows, opts, key, author, match)
+	sort.Slice(matched, func(i, j int) bool { return before(key(matched[i]), key(matched[j])) })
+
+	page := &Page[T]{Total: int64(len(matched))}
+	start := (opts.Page - 1) * opts.Size
+	if opts.Cursor != nil {
+		start = sort.Search(len(matched), func(i int) bool { return before(*opts.Cursor, key(matched[i])) })
+	}
+	if start > len(matched) {
+		start = len(matched)
+	}
+	items := matched[start:]
+	if len(items) > opts.Size {
+		items = items[:opts.Size]
+		page.NextCursor = key(items[len(items)-1]).Encode()
+	}
+	page.Items = items
+	return page
+}
+
+// filterRows 返回满足 opts 中作者、时间范围和 match 条件的记录（不排序、不分页）
+func filterRows[T any](rows []T, opts ListOptions, key func(T) Cursor, author func(T) uint, match func(T) bool) []T {
+	matched := []T{}
+	for _, row := range rows {
+		k := key(row)
+		if opts.AuthorID != 0 && author(row) != opts.AuthorID {
+			continue
+		}
+		if opts.From != nil && k.CreatedAt.Before(*opts.From) {
+			continue
+		}
+		if opts.To != nil && !k.CreatedAt.Before(*opts.To) {
+			continue
+		}
+		if match != nil && !match(row) {
+			continue
+		}
+		matched = append(matched, row)
+	}
+	return matched
+}
+
+// 以下 with* 方法模拟 GORM 的 Preload，调用方需要持有读锁
+
+func (m *Memory) withPostAssociations(post models.Post) models.Post {
+	post.User = m.users[post.UserID]
+	post.Comments = []models.Comment{}
+	for _, id := range sortedKeys(m.comments) {
+		if c := m.comments[id]; c.PostID == post.ID {
+			post.Comments = append(post.Comments, c)
+		}
+	}
+	post.Tags = []models.Tag{}
+	for _, term := range m.termsOf(TaxonomyTag, post.ID) {
+		post.Tags = append(post.Tags, models.Tag{Term: term})
+	}
+	post.Categories = []models.Category{}
+	for _, term := range m.termsOf(TaxonomyCategory, post.ID) {
+		post.Categories = append(post.Categories, models.Category{Term: term})
+	}
+	return post
+}
+
+// termsOf 按主键顺序返回文章的标签或分类，调用方需要持有读锁
+func (m *Memory) termsOf(t Taxonomy, postID uint) []models.Term {
+	terms := []models.Term{}
+	for name := range m.postTerms[t][postID] {
+		terms = append(terms, m.terms[t][name])
+	}
+	sort.Slice(terms, func(i, j int) bool { return terms[i].ID < terms[j].ID })
+	return terms
+}
+
+func (m *Memory) withCommentAssociations(comment models.Comment) models.Comment {
+	comment.User = m.users[comment.UserID]
+	comment.Post = m.posts[comment.PostID]
+	return comment
+}
+
+type memoryUserRepository struct{ m *Memory }
+
+func (r memoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	for _, u := range r.m.users {
+		if u.Email == user.Email {
+			return ErrDuplicate
+		}
+	}
+	user.ID = r.m.nextID()
+	user.CreatedAt, user.UpdatedAt = time.Now(), time.Now()
+	// 模拟数据库列的默认值
+	if user.Role == "" {
+		user.Role = models.RoleAuthor
+	}
+	r.m.users[user.ID] = *user
+	return nil
+}
+
+func (r memoryUserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	user, ok := r.m.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r memoryUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	for _, user := range r.m.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r memoryUserRepository) List(ctx context.Context, opts ListOptions) (*Page[models.User], error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	users := make([]models.User, 0, len(r.m.users))
+	for _, u := range r.m.users {
+		users = append(users, u)
+	}
+	return paginate(users, opts, userCursor, func(models.User) uint { return 0 }, nil), nil
+}
+
+func (r memoryUserRepository) SetRole(ctx context.Context, id uint, role models.Role) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	user, ok := r.m.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	user.Role, user.UpdatedAt = role, time.Now()
+	r.m.users[id] = user
+	return nil
+}
+
+func (r memoryUserRepository) Delete(ctx context.Context, id uint) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if _, ok := r.m.users[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.m.users, id)
+	return nil
+}
+
+type memoryPostRepository struct{ m *Memory }
+
+func (r memoryPostRepository) Create(ctx context.Context, post *models.Post) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	post.ID = r.m.nextID()
+	post.CreatedAt, post.UpdatedAt = time.Now(), time.Now()
+	post.Version = 1
+	if post.CommentMode == "" {
+		post.CommentMode = models.CommentModeAuto
+	}
+	if post.Format == "" {
+		post.Format = models.FormatPlain
+	}
+	if post.Status == "" {
+		post.Status = models.PostPublished
+	}
+	r.m.posts[post.ID] = *post
+	r.m.addRevision(post, 1)
+	return nil
+}
+
+func (r memoryPostRepository) FindByID(ctx context.Context, id uint) (*models.Post, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	post, ok := r.m.posts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	post = r.m.withPostAssociations(post)
+	return &post, nil
+}
+
+func (r memoryPostRepository) List(ctx context.Context, opts ListOptions) (*Page[models.Post], error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	posts := make([]models.Post, 0, len(r.m.posts))
+	for _, p := range r.m.posts {
+		posts = append(posts, p)
+	}
+	key := postCursor
+	if opts.ByPublished {
+		key = publishedCursor
+	}
+	page := paginate(posts, opts, key, postAuthor, func(p models.Post) bool {
+		return postVisible(p, opts.Visibility) && (opts.Status == "" || p.Status == opts.Status) && r.m.postHasTerms(p, opts)
+	})
+	for i := range page.Items {
+		page.Items[i] = r.m.withPostAssociations(page.Items[i])
+	}
+	return page, nil
+}
+
+func postAuthor(p models.Post) uint { return p.UserID }
+
+// postHasTerms 是 HasTerm 的内存版本，调用方需要持有读锁
+func (m *Memory) postHasTerms(p models.Post, opts ListOptions) bool {
+	return (opts.Tag == "" || m.postTerms[TaxonomyTag][p.ID][opts.Tag]) &&
+		(opts.Category == "" || m.postTerms[TaxonomyCategory][p.ID][opts.Category])
+}
+
+func (r memoryPostRepository) MostLiked(ctx context.Context, opts ListOptions) (*Page[models.Post], error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	opts = opts.Normalize()
+	posts := make([]models.Post, 0, len(r.m.posts))
+	for _, p := range r.m.posts {
+		posts = append(posts, p)
+	}
+	matched := filterRows(posts, opts, postCursor, postAuthor, func(p models.Post) bool {
+		return p.Status == models.PostPublished && r.m.postHasTerms(p, opts)
+	})
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if a.LikeCount != b.LikeCount {
+			return a.LikeCount > b.LikeCount
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+	page := &Page[models.Post]{Total: int64(len(matched))}
+	start := min((opts.Page-1)*opts.Size, len(matched))
+	page.Items = matched[start:min(start+opts.Size, len(matched))]
+	for i := range page.Items {
+		page.Items[i] = r.m.withPostAssociations(page.Items[i])
+	}
+	return page, nil
+}
+
+func (r memoryPostRepository) Feed(ctx context.Context, userID uint, opts ListOptions) (*Page[models.Post], error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	posts := []models.Post{}
+	for _, p := range r.m.posts {
+		if _, ok := r.m.follows[followKey{userID, p.UserID}]; ok && p.Status == models.PostPublished {
+			if _, ok := r.m.users[p.UserID]; ok {
+				posts = append(posts, p)
+			}
+		}
+	}
+	// 只支持游标分页，忽略页码等其他选项
+	page := paginate(posts, ListOptions{Size: opts.Size, Desc: true, Cursor: opts.Cursor}, publishedCursor, postAuthor, nil)
+	page.Total = 0
+	for i := range page.Items {
+		page.Items[i] = r.m.withPostAssociations(page.Items[i])
+	}
+	return page, nil
+}
+
+func (r memoryPostRepository) Update(ctx context.Context, post *models.Post) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	current, ok := r.m.posts[post.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if current.Version != post.Version {
+		return ErrStale
+	}
+	latest := r.m.latestRevision(post.ID)
+	post.UpdatedAt = time.Now()
+	post.Version++
+	// 计数由反应维护，保存文章时保留当前的值
+	post.LikeCount, post.ReactionCount = current.LikeCount, current.ReactionCount
+	stored := *post
+	stored.User, stored.Comments, stored.Tags, stored.Categories = models.User{}, nil, nil, nil
+	r.m.posts[post.ID] = stored
+	if latest == nil {
+		r.m.addRevision(post, 1)
+	} else if changed(post, latest) {
+		r.m.addRevision(post, latest.Number+1)
+	}
+	return nil
+}
+
+func (r memoryPostRepository) Delete(ctx context.Context, post *models.Post) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	delete(r.m.posts, post.ID)
+	return nil
+}
+
+func (r memoryPostRepository) PublishDue(ctx context.Context, now time.Time) ([]models.Post, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	var published []models.Post
+	for _, id := range sortedKeys(r.m.posts) {
+		post := r.m.posts[id]
+		if post.Status != models.PostScheduled || post.PublishAt == nil || post.PublishAt.After(now) {
+			continue
+		}
+		post.Status, post.PublishedAt, post.PublishAt, post.UpdatedAt = models.PostPublished, post.PublishAt, nil, time.Now()
+		post.Version++
+		r.m.posts[id] = post
+		published = append(published, r.m.withPostAssociations(post))
+	}
+	return published, nil
+}
+
+type memoryCommentRepository struct{ m *Memory }
+
+func (r memoryCommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	comment.ID = r.m.nextID()
+	comment.CreatedAt, comment.UpdatedAt = time.Now(), time.Now()
+	if comment.Status == "" {
+		comment.Status = models.CommentApproved
+	}
+	r.m.comments[comment.ID] = *comment
+	return nil
+}
+
+func (r memoryCommentRepository) FindByID(ctx context.Context, id uint) (*models.Comment, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	comment, ok := r.m.comments[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	comment.Post = r.m.posts[comment.PostID]
+	return &comment, nil
+}
+
+func (r memoryCommentRepository) ListByPost(ctx context.Context, postID uint, opts ListOptions) (*Page[models.Comment], error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	comments := []models.Comment{}
+	for _, c := range r.m.comments {
+		if c.PostID == postID && commentVisible(c, opts.Visibility) {
+			comments = append(comments, c)
+		}
+	}
+	page := paginate(comments, opts, commentCursor, func(c models.Comment) uint { return c.UserID }, nil)
+	for i := range page.Items {
+		page.Items[i] = r.m.withCommentAssociations(page.Items[i])
+	}
+	return page, nil
+}
+
+func (r memoryCommentRepository) ListTree(ctx context.Context, postID uint, v Visibility) ([]models.Comment, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	comments := []models.Comment{}
+	// ID 递增，按 ID 排序就是按创建时间正序
+	for _, id := range sortedKeys(r.m.comments) {
+		if c := r.m.comments[id]; c.PostID == postID && commentVisible(c, v) {
+			c.User = r.m.users[c.UserID]
+			comments = append(comments, c)
+		}
+	}
+	return comments, nil
+}
+
+func (r memoryCommentRepository) SetStatus(ctx context.Context, id uint, status string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	comment, ok := r.m.comments[id]
+	if !ok {
+		return ErrNotFound
+	}
+	comment.Status, comment.UpdatedAt = status, time.Now()
+	r.m.comments[id] = comment
+	return nil
+}
+
+func (r memoryCommentRepository) Delete(ctx context.Context, comment *models.Comment) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if r.m.hasReplies(comment.ID) {
+		comment.Status, comment.Content = models.CommentDeleted, models.DeletedCommentContent
+		stored := r.m.comments[comment.ID]
+		stored.Status, stored.Content, stored.UpdatedAt = comment.Status, comment.Content, time.Now()
+		r.m.comments[comment.ID] = stored
+		return nil
+	}
+	delete(r.m.comments, comment.ID)
+	for parentID := comment.ParentID; parentID != nil; {
+		parent, ok := r.m.comments[*parentID]
+		if !ok || parent.Status != models.CommentDeleted || r.m.hasReplies(parent.ID) {
+			return nil
+		}
+		delete(r.m.comments, parent.ID)
+		parentID = parent.ParentID
+	}
+	return nil
+}
+
+// hasReplies 判断评论是否还有回复，调用方需要持有锁
+func (m *Memory) hasReplies(id uint) bool {
+	for _, c := range m.comments {
+		if c.ParentID != nil && *c.ParentID == id {
+			return true
+		}
+	}
+	return false
+}
+
+type memorySessionRepository struct{ m *Memory }
+
+func (r memorySessionRepository) Create(ctx context.Context, session *models.Session) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if _, ok := r.m.sessions[session.JTI]; ok {
+		return ErrDuplicate
+	}
+	session.ID = r.m.nextID()
+	session.CreatedAt, session.UpdatedAt = time.Now(), time.Now()
+	r.m.sessions[session.JTI] = *session
+	return nil
+}
+
+func (r memorySessionRepository) FindByJTI(ctx context.Context, jti string) (*models.Session, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	session, ok := r.m.sessions[jti]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+func (r memorySessionRepository) Rotate(ctx context.Context, jti, oldRefresh, newRefresh string, expiresAt time.Time) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	session, ok := r.m.sessions[jti]
+	if !ok || session.RefreshJTI != oldRefresh || session.RevokedAt != nil {
+		return ErrNotFound
+	}
+	session.RefreshJTI, session.ExpiresAt, session.UpdatedAt = newRefresh, expiresAt, time.Now()
+	r.m.sessions[jti] = session
+	return nil
+}
+
+func (r memorySessionRepository) Revoke(ctx context.Context, jti string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if session, ok := r.m.sessions[jti]; ok && session.RevokedAt == nil {
+		now := time.Now()
+		session.RevokedAt = &now
+		r.m.sessions[jti] = session
+	}
+	return nil
+}
+
+func (r memorySessionRepository) RevokeAllByUser(ctx context.Context, userID uint) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	now := time.Now()
+	for jti, session := range r.m.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+			r.m.sessions[jti] = session
+		}
+	}
+	return nil
+}
+
+type memoryTaxonomyRepository struct {
+	m *Memory
+	t Taxonomy
+}
+
+func (r memoryTaxonomyRepository) Attach(ctx context.Context, postID uint, names []string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	terms, attached := r.m.terms[r.t], r.m.postTerms[r.t]
+	for _, name := range names {
+		if _, ok := terms[name]; !ok {
+			term := models.Term{Name: name}
+			term.ID = r.m.nextID()
+			term.CreatedAt, term.UpdatedAt = time.Now(), time.Now()
+			terms[name] = term
+		}
+		if attached[postID] == nil {
+			attached[postID] = map[string]bool{}
+		}
+		attached[postID][name] = true
+	}
+	return nil
+}
+
+func (r memoryTaxonomyRepository) Detach(ctx context.Context, postID uint, name string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if _, ok := r.m.terms[r.t][name]; !ok {
+		return ErrNotFound
+	}
+	delete(r.m.postTerms[r.t][postID], name)
+	return nil
+}
+
+func (r memoryTaxonomyRepository) Counts(ctx context.Context) ([]TermCount, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	byName := map[string]int64{}
+	for postID, names := range r.m.postTerms[r.t] {
+		// 已删除和未发布的文章不计入
+		if post, ok := r.m.posts[postID]; !ok || post.Status != models.PostPublished {
+			continue
+		}
+		for name := range names {
+			byName[name]++
+		}
+	}
+	counts := []TermCount{}
+	for name, n := range byName {
+		counts = append(counts, TermCount{Name: name, Count: n})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+	return counts, nil
+}
+
+// latestRevision 返回文章最新的修订，没有修订时返回 nil，调用方需要持有读锁
+func (m *Memory) latestRevision(postID uint) *models.PostRevision {
+	var latest *models.PostRevision
+	for _, id := range sortedKeys(m.revisions) {
+		if rev := m.revisions[id]; rev.PostID == postID && (latest == nil || rev.Number > latest.Number) {
+			latest = &rev
+		}
+	}
+	return latest
+}
+
+// addRevision 追加文章的修订，调用方需要持有写锁
+func (m *Memory) addRevision(post *models.Post, number int) {
+	rev := newRevision(post, number)
+	rev.ID = m.nextID()
+	rev.CreatedAt = time.Now()
+	m.revisions[rev.ID] = *rev
+}
+
+type memoryRevisionRepository struct{ m *Memory }
+
+func (r memoryRevisionRepository) ListByPost(ctx context.Context, postID uint, opts ListOptions) (*Page[models.PostRevision], error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	revs := []models.PostRevision{}
+	for _, rev := range r.m.revisions {
+		if rev.PostID == postID {
+			revs = append(revs, rev)
+		}
+	}
+	return paginate(revs, opts, revisionCursor, func(rev models.PostRevision) uint { return rev.UserID }, nil), nil
+}
+
+func (r memoryRevisionRepository) Find(ctx context.Context, postID uint, number int) (*models.PostRevision, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	if number == 0 {
+		if latest := r.m.latestRevision(postID); latest != nil {
+			return latest, nil
+		}
+		return nil, ErrNotFound
+	}
+	for _, rev := range r.m.revisions {
+		if rev.PostID == postID && rev.Number == number {
+			return &rev, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+type memoryReactionRepository struct{ m *Memory }
+
+// countReaction 是 models.Reaction 钩子的内存版本，调用方需要持有写锁
+func (m *Memory) countReaction(r models.Reaction, delta int) {
+	likes := 0
+	if r.Kind == models.ReactionLike {
+		likes = delta
+	}
+	switch r.TargetType {
+	case models.ReactionTargetPost:
+		if p, ok := m.posts[r.TargetID]; ok {
+			p.ReactionCount, p.LikeCount = p.ReactionCount+delta, p.LikeCount+likes
+			m.posts[r.TargetID] = p
+		}
+	case models.ReactionTargetComment:
+		if c, ok := m.comments[r.TargetID]; ok {
+			c.ReactionCount, c.LikeCount = c.ReactionCount+delta, c.LikeCount+likes
+			m.comments[r.TargetID] = c
+		}
+	}
+}
+
+func (r memoryReactionRepository) Set(ctx context.Context, reaction *models.Reaction) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	key := reactionKey{reaction.UserID, reaction.TargetType, reaction.TargetID}
+	if existing, ok := r.m.reactions[key]; ok {
+		if existing.Kind == reaction.Kind {
+			*reaction = existing
+			return nil
+		}
+		r.m.countReaction(existing, -1)
+	}
+	reaction.ID = r.m.nextID()
+	reaction.CreatedAt = time.Now()
+	r.m.reactions[key] = *reaction
+	r.m.countReaction(*reaction, 1)
+	return nil
+}
+
+func (r memoryReactionRepository) Remove(ctx context.Context, userID uint, targetType string, targetID uint) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	key := reactionKey{userID, targetType, targetID}
+	existing, ok := r.m.reactions[key]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(r.m.reactions, key)
+	r.m.countReaction(existing, -1)
+	return nil
+}
+
+func (r memoryReactionRepository) Summary(ctx context.Context, targetType string, targetID, userID uint) (*ReactionSummary, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	summary := &ReactionSummary{Counts: map[string]int64{}}
+	for key, reaction := range r.m.reactions {
+		if key.TargetType != targetType || key.TargetID != targetID {
+			continue
+		}
+		summary.Counts[reaction.Kind]++
+		if key.UserID == userID {
+			summary.Mine = reaction.Kind
+		}
+	}
+	return summary, nil
+}
+
+type memoryFollowRepository struct{ m *Memory }
+
+func (r memoryFollowRepository) Follow(ctx context.Context, followerID, followeeID uint) (bool, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	key := followKey{followerID, followeeID}
+	if _, ok := r.m.follows[key]; ok {
+		return false, nil
+	}
+	r.m.follows[key] = models.Follow{ID: r.m.nextID(), FollowerID: followerID, FolloweeID: followeeID, CreatedAt: time.Now()}
+	return true, nil
+}
+
+func (r memoryFollowRepository) Unfollow(ctx context.Context, followerID, followeeID uint) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	key := followKey{followerID, followeeID}
+	if _, ok := r.m.follows[key]; !ok {
+		return ErrNotFound
+	}
+	delete(r.m.follows, key)
+	return nil
+}
+
+func (r memoryFollowRepository) Followers(ctx context.Context, userID uint, opts ListOptions) (*Page[models.Follow], error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	page := r.m.listFollows(opts, func(f models.Follow) bool { return f.FolloweeID == userID }, func(f models.Follow) uint { return f.FollowerID })
+	for i := range page.Items {
+		page.Items[i].Follower = r.m.users[page.Items[i].FollowerID]
+	}
+	return page, nil
+}
+
+func (r memoryFollowRepository) Following(ctx context.Context, userID uint, opts ListOptions) (*Page[models.Follow], error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	page := r.m.listFollows(opts, func(f models.Follow) bool { return f.FollowerID == userID }, func(f models.Follow) uint { return f.FolloweeID })
+	for i := range page.Items {
+		page.Items[i].Followee = r.m.users[page.Items[i].FolloweeID]
+	}
+	return page, nil
+}
+
+// listFollows 分页返回满足 match 的关注记录，other 是列表中展示的另一方，已删除的用户不返回（activeUser 的内存版本）。
+// 调用方需要持有读锁
+func (m *Memory) listFollows(opts ListOptions, match func(models.Follow) bool, other func(models.Follow) uint) *Page[models.Follow] {
+	follows := []models.Follow{}
+	for _, f := range m.follows {
+		if _, ok := m.users[other(f)]; ok && match(f) {
+			follows = append(follows, f)
+		}
+	}
+	return paginate(follows, opts, followCursor, func(models.Follow) uint { return 0 }, nil)
+}
+
+type memoryNotificationRepository struct{ m *Memory }
+
+func (r memoryNotificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	notification.ID = r.m.nextID()
+	notification.CreatedAt = time.Now()
+	r.m.notifications[notification.ID] = *notification
+	return nil
+}
+
+func (r memoryNotificationRepository) List(ctx context.Context, userID uint, unread bool, opts ListOptions) (*Page[models.Notification], error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	notifications := make([]models.Notification, 0, len(r.m.notifications))
+	for _, n := range r.m.notifications {
+		notifications = append(notifications, n)
+	}
+	page := paginate(notifications, opts, notificationCursor, func(models.Notification) uint { return 0 }, func(n models.Notification) bool {
+		return n.UserID == userID && (!unread || n.ReadAt == nil)
+	})
+	for i := range page.Items {
+		page.Items[i].Actor = r.m.users[page.Items[i].ActorID]
+	}
+	return page, nil
+}
+
+func (r memoryNotificationRepository) UnreadCount(ctx context.Context, userID uint) (int64, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	var count int64
+	for _, n := range r.m.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r memoryNotificationRepository) MarkRead(ctx context.Context, userID, id uint) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	n, ok := r.m.notifications[id]
+	if !ok || n.UserID != userID {
+		return ErrNotFound
+	}
+	if n.ReadAt == nil {
+		now := time.Now()
+		n.ReadAt = &now
+		r.m.notifications[id] = n
+	}
+	return nil
+}
+
+func (r memoryNotificationRepository) MarkAllRead(ctx context.Context, userID uint) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	var count int64
+	now := time.Now()
+	for id, n := range r.m.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			n.ReadAt = &now
+			r.m.notifications[id] = n
+			count++
+		}
+	}
+	return count, nil
+}
+
+type memoryMediaRepository struct{ m *Memory }
+
+func (r memoryMediaRepository) Create(ctx context.Context, media *models.Media) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	for _, existing := range r.m.media {
+		if existing.Key == media.Key {
+			return ErrDuplicate
+		}
+	}
+	media.ID = r.m.nextID()
+	media.CreatedAt = time.Now()
+	r.m.media[media.ID] = *media
+	return nil
+}
+
+func (r memoryMediaRepository) FindByID(ctx context.Context, id uint) (*models.Media, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	media, ok := r.m.media[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &media, nil
+}
+
+func (r memoryMediaRepository) FindByKey(ctx context.Context, key string) (*models.Media, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	for _, media := range r.m.media {
+		if media.Key == key {
+			return &media, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r memoryMediaRepository) ListByPost(ctx context.Context, postID uint) ([]models.Media, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	media := []models.Media{}
+	for _, id := range sortedKeys(r.m.media) {
+		if r.m.media[id].PostID == postID {
+			media = append(media, r.m.media[id])
+		}
+	}
+	return media, nil
+}
+
+func (r memoryMediaRepository) Delete(ctx context.Context, id uint) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if _, ok := r.m.media[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.m.media, id)
+	return nil
+}
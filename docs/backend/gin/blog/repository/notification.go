@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"blog/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *models.Notification) error
+	// List 分页返回用户的通知（预加载 Actor），unread 为 true 时只返回未读通知
+	List(ctx context.Context, userID uint, unread bool, opts ListOptions) (*Page[models.Notification], error)
+	// UnreadCount 统计用户的未读通知数
+	UnreadCount(ctx context.Context, userID uint) (int64, error)
+	// MarkRead 把用户的一条通知标记为已读，通知不存在或不属于该用户时返回 ErrNotFound，已读的通知不做修改
+	MarkRead(ctx context.Context, userID, id uint) error
+	// MarkAllRead 把用户的全部未读通知标记为已读，返回标记的数量
+	MarkAllRead(ctx context.Context, userID uint) (int64, error)
+}
+
+type gormNotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewGormNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &gormNotificationRepository{db: db}
+}
+
+// unreadOf 查询用户的通知，unread 为 true 时只查询未读的
+func unreadOf(userID uint, unread bool) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Where("user_id = ?", userID)
+		if unread {
+			tx = tx.Where("read_at IS NULL")
+		}
+		return tx
+	}
+}
+
+func (r *gormNotificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	return r.db.WithContext(ctx).Create(notification).Error
+}
+
+func (r *gormNotificationRepository) List(ctx context.Context, userID uint, unread bool, opts ListOptions) (*Page[models.Notification], error) {
+	tx := r.db.WithContext(ctx).Model(&models.Notification{}).Scopes(unreadOf(userID, unread))
+	return listPage(tx, opts, notificationCursor, func(tx *gorm.DB) *gorm.DB { return tx.Preload("Actor") })
+}
+
+func (r *gormNotificationRepository) UnreadCount(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Notification{}).Scopes(unreadOf(userID, true)).Count(&count).Error
+	return count, err
+}
+
+func (r *gormNotificationRepository) MarkRead(ctx context.Context, userID, id uint) error {
+	var notification models.Notification
+	if err := r.db.WithContext(ctx).Scopes(unreadOf(userID, false)).First(&notification, id).Error; err != nil {
+		return translate(err)
+	}
+	if notification.ReadAt != nil {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&notification).Update("read_at", time.Now()).Error
+}
+
+func (r *gormNotificationRepository) MarkAllRead(ctx context.Context, userID uint) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.Notification{}).Scopes(unreadOf(userID, true)).Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+func notificationCursor(n models.Notification) Cursor {
+	return Cursor{CreatedAt: n.CreatedAt, ID: n.ID}
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// ErrInvalidCursor 表示客户端传入的游标无法解析
+var ErrInvalidCursor = errors.New("无效的游标")
+
+// ListOptions 描述列表查询的分页、排序和过滤条件
+// Cursor 非空时使用游标（keyset）分页，此时忽略 Page
+type ListOptions struct {
+	Page     int
+	Size     int
+	Cursor   *Cursor
+	Desc     bool       // 按 created_at,id 倒序（最新的在前）
+	AuthorID uint       // 只返回该用户的记录，0 表示不过滤
+	From     *time.Time // created_at >= From
+	To       *time.Time // created_at < To
+	Tag      string     // 只返回带有该标签的文章，只对文章列表有效
+	Category string     // 只返回属于该分类的文章，只对文章列表有效
+	Status   string     // 只返回该状态的文章，只对文章列表有效
+	// ByPublished 表示用发布时间代替上面的 created_at 排序、分页和过滤，只对已发布文章的列表有效。
+	// 定时发布的文章创建得早、发布得晚，公开的文章列表应该按发布时间排序
+	ByPublished bool
+	// Visibility 控制非公开记录（例如待审核的评论、草稿）的可见性，零值表示只返回公开记录
+	Visibility Visibility
+}
+
+// Visibility 描述当前查看者除了公开记录外还能看到哪些记录
+type Visibility struct {
+	All      bool // 可以看到全部记录，例如文章作者查看自己文章下的评论
+	ViewerID uint // 可以看到自己的记录，0 表示匿名
+}
+
+// Normalize 补齐默认值，保证 Page 和 Size 在合法范围内
+func (o ListOptions) Normalize() ListOptions {
+	if o.Page < 1 {
+		o.Page = 1
+	}
+	if o.Size < 1 {
+		o.Size = DefaultPageSize
+	}
+	if o.Size > MaxPageSize {
+		o.Size = MaxPageSize
+	}
+	return o
+}
+
+// Cursor 是 keyset 分页的位置：上一页最后一条记录的 created_at 和 id。
+// 按发布时间排序的列表中 CreatedAt 是发布时间
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// Encode 把游标编码成对客户端不透明的字符串
+func (c Cursor) Encode() string {
+	raw := fmt.Sprintf("%d:%d", c.CreatedAt.UnixNano(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor 解析 Encode 生成的游标
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	n, err1 := strconv.ParseInt(nanos, 10, 64)
+	i, err2 := strconv.ParseUint(id, 10, 64)
+	if err1 != nil || err2 != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{CreatedAt: time.Unix(0, n), ID: uint(i)}, nil
+}
+
+// Page 是一页查询结果
+type Page[T any] struct {
+	Items      []T
+	Total      int64  // 满足过滤条件的总数（不受分页影响）
+	NextCursor string // 还有下一页时为下一页的游标，否则为空
+}
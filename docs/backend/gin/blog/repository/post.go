@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"blog/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PostRepository interface {
+	// Create 保存文章，并记录第一个修订；只保存文章本身的字段，不会写入关联的作者、评论、标签和分类
+	Create(ctx context.Context, post *models.Post) error
+	// FindByID 返回文章，并预加载作者、评论、标签和分类
+	FindByID(ctx context.Context, id uint) (*models.Post, error)
+	// List 按 ListOptions 分页、排序和过滤 opts.Visibility 可见的文章，并预加载作者、评论、标签和分类
+	List(ctx context.Context, opts ListOptions) (*Page[models.Post], error)
+	// Update 只保存文章本身的字段，不会级联更新关联的作者、评论、标签和分类；
+	// 标题或内容有变化时在同一个事务中追加一个修订。
+	// post.Version 是读取文章时的版本号，只有数据库中的版本号没有变化时才会保存，
+	// 保存后版本号加一；文章在此期间被其他请求修改时返回 ErrStale
+	Update(ctx context.Context, post *models.Post) error
+	Delete(ctx context.Context, post *models.Post) error
+	// MostLiked 按点赞数从多到少分页返回已发布的文章（点赞数相同时最新的在前），
+	// 支持 opts 中的作者、时间范围、标签和分类过滤，只支持页码分页
+	MostLiked(ctx context.Context, opts ListOptions) (*Page[models.Post], error)
+	// Feed 按发布时间倒序返回 userID 关注的人发布的文章，只支持游标分页，不统计总数（Page.Total 为 0）。
+	// 读取时在数据库中按关注关系过滤（fan-out on read），关注的人再多也只需要一次查询
+	Feed(ctx context.Context, userID uint, opts ListOptions) (*Page[models.Post], error)
+	// PublishDue 发布所有 publish_at 不晚于 now 的定时文章，返回被发布的文章（预加载评论）
+	PublishDue(ctx context.Context, now time.Time) ([]models.Post, error)
+}
+
+type gormPostRepository struct {
+	db *gorm.DB
+}
+
+func NewGormPostRepository(db *gorm.DB) PostRepository {
+	return &gormPostRepository{db: db}
+}
+
+func (r *gormPostRepository) Create(ctx context.Context, post *models.Post) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		post.Version = 1
+		if err := tx.Omit(clause.Associations).Create(post).Error; err != nil {
+			return err
+		}
+		// 新文章的第一个修订就是刚写入的版本
+		_, err := baselineRevision(tx, post.ID)
+		return err
+	})
+}
+
+func (r *gormPostRepository) FindByID(ctx context.Context, id uint) (*models.Post, error) {
+	var post models.Post
+	if err := r.db.WithContext(ctx).Preload("User").Preload("Comments").Preload("Tags").Preload("Categories").First(&post, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &post, nil
+}
+
+func (r *gormPostRepository) List(ctx context.Context, opts ListOptions) (*Page[models.Post], error) {
+	tx := r.db.WithContext(ctx).Model(&models.Post{}).Scopes(VisiblePosts(opts.Visibility), ByStatus(opts.Status),
+		HasTerm(TaxonomyTag, opts.Tag), HasTerm(TaxonomyCategory, opts.Category))
+	key := postCursor
+	if opts.ByPublished {
+		key = publishedCursor
+	}
+	return listPage(tx, opts, key, func(tx *gorm.DB) *gorm.DB {
+		return tx.Preload("User").Preload("Comments").Preload("Tags").Preload("Categories")
+	})
+}
+
+func (r *gormPostRepository) MostLiked(ctx context.Context, opts ListOptions) (*Page[models.Post], error) {
+	opts = opts.Normalize()
+	// 新建会话，让统计和查询两条链互不影响
+	tx := r.db.WithContext(ctx).Model(&models.Post{}).Where("status = ?", models.PostPublished).
+		Scopes(filters(opts), HasTerm(TaxonomyTag, opts.Tag), HasTerm(TaxonomyCategory, opts.Category)).Session(&gorm.Session{})
+	page := &Page[models.Post]{}
+	if err := tx.Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+	err := tx.Preload("User").Preload("Comments").Preload("Tags").Preload("Categories").
+		Order("like_count DESC").Scopes(OrderByCreated(true), Paginate(opts.Page, opts.Size)).Find(&page.Items).Error
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+func (r *gormPostRepository) Feed(ctx context.Context, userID uint, opts ListOptions) (*Page[models.Post], error) {
+	opts = opts.Normalize()
+	db := r.db.WithContext(ctx)
+	// 子查询走 follows 的 (follower_id, followee_id) 唯一索引，文章走 user_id 索引，整个时间线只需要一次查询
+	var items []models.Post
+	err := db.Preload("User").Preload("Comments").Preload("Tags").Preload("Categories").
+		Where("user_id IN (?) AND status = ?", followeesOf(db, userID), models.PostPublished).
+		Scopes(OrderByPublished(true), AfterPublishedCursor(opts.Cursor, true)).Limit(opts.Size + 1).Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	page := &Page[models.Post]{Items: items}
+	if len(items) > opts.Size {
+		page.Items = items[:opts.Size]
+		page.NextCursor = publishedCursor(page.Items[opts.Size-1]).Encode()
+	}
+	return page, nil
+}
+
+// postVisible 是 VisiblePosts 的内存版本
+func postVisible(p models.Post, v Visibility) bool {
+	return v.All || p.Status == models.PostPublished || (v.ViewerID != 0 && p.UserID == v.ViewerID)
+}
+
+func postCursor(p models.Post) Cursor {
+	return Cursor{CreatedAt: p.CreatedAt, ID: p.ID}
+}
+
+// publishedCursor 是按发布时间排序时的游标，和 publishedColumn 一致。
+// 已发布的文章一定有发布时间，没有时（只会出现在内存仓储直接写入的数据中）使用创建时间
+func publishedCursor(p models.Post) Cursor {
+	if p.PublishedAt == nil {
+		return postCursor(p)
+	}
+	return Cursor{CreatedAt: *p.PublishedAt, ID: p.ID}
+}
+
+func (r *gormPostRepository) Update(ctx context.Context, post *models.Post) error {
+	expected := post.Version
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		latest, err := baselineRevision(tx, post.ID)
+		if err != nil {
+			return err
+		}
+		// 带上版本号条件更新（compare-and-swap），没有更新任何行说明版本号已经变了
+		post.Version = expected + 1
+		result := tx.Model(post).Where("version = ?", expected).
+			Select("*").Omit(clause.Associations, "CreatedAt", "LikeCount", "ReactionCount").Updates(post)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrStale
+		}
+		if !changed(post, latest) {
+			return nil
+		}
+		return translate(tx.Create(newRevision(post, latest.Number+1)).Error)
+	})
+	if err != nil {
+		post.Version = expected
+	}
+	return err
+}
+
+func (r *gormPostRepository) Delete(ctx context.Context, post *models.Post) error {
+	return r.db.WithContext(ctx).Delete(post).Error
+}
+
+func (r *gormPostRepository) PublishDue(ctx context.Context, now time.Time) ([]models.Post, error) {
+	var published []models.Post
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var due []models.Post
+		if err := tx.Preload("Comments").
+			Where("status = ? AND publish_at <= ?", models.PostScheduled, now).Find(&due).Error; err != nil {
+			return err
+		}
+		for _, post := range due {
+			// 带上状态条件更新，多个实例同时运行调度器时只有一个能发布成功
+			result := tx.Model(&models.Post{}).
+				Where("id = ? AND status = ?", post.ID, models.PostScheduled).
+				Updates(map[string]any{
+					"status":       models.PostPublished,
+					"published_at": post.PublishAt,
+					"publish_at":   nil, // 发布后不再是定时文章，和 applyStatus 一样清空
+					"version":      gorm.Expr("version + 1"),
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+			post.Status, post.PublishedAt, post.PublishAt = models.PostPublished, post.PublishAt, nil
+			post.Version++
+			published = append(published, post)
+		}
+		return nil
+	})
+	return published, err
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"blog/models"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ReactionSummary 是一个目标上的反应统计
+type ReactionSummary struct {
+	Counts map[string]int64 `json:"counts"` // 每种反应的数量，没有人使用的反应不出现
+	Mine   string           `json:"mine"`   // 当前用户的反应，没有时为空
+}
+
+type ReactionRepository interface {
+	// Set 设置用户对目标的反应：没有反应时新增，已有其他反应时替换，和已有的反应相同时不做修改。
+	// 目标上的计数由 models.Reaction 的钩子在同一个事务中维护
+	Set(ctx context.Context, reaction *models.Reaction) error
+	// Remove 删除用户对目标的反应，没有反应时返回 ErrNotFound
+	Remove(ctx context.Context, userID uint, targetType string, targetID uint) error
+	// Summary 统计目标上每种反应的数量，以及 userID 的反应
+	Summary(ctx context.Context, targetType string, targetID, userID uint) (*ReactionSummary, error)
+}
+
+type gormReactionRepository struct {
+	db *gorm.DB
+}
+
+func NewGormReactionRepository(db *gorm.DB) ReactionRepository {
+	return &gormReactionRepository{db: db}
+}
+
+// ofUser 查询用户对目标的反应
+func ofUser(userID uint, targetType string, targetID uint) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("user_id = ? AND target_type = ? AND target_id = ?", userID, targetType, targetID)
+	}
+}
+
+func (r *gormReactionRepository) Set(ctx context.Context, reaction *models.Reaction) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing models.Reaction
+		err := tx.Scopes(ofUser(reaction.UserID, reaction.TargetType, reaction.TargetID)).First(&existing).Error
+		switch {
+		case err == nil && existing.Kind == reaction.Kind:
+			*reaction = existing
+			return nil
+		case err == nil:
+			// 替换反应：删除旧的再新增，让钩子分别调整两种反应的计数
+			if err := tx.Delete(&existing).Error; err != nil {
+				return err
+			}
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+		// 并发的重复请求会被唯一索引拦下，返回 ErrDuplicate
+		return translate(tx.Create(reaction).Error)
+	})
+}
+
+func (r *gormReactionRepository) Remove(ctx context.Context, userID uint, targetType string, targetID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing models.Reaction
+		if err := tx.Scopes(ofUser(userID, targetType, targetID)).First(&existing).Error; err != nil {
+			return translate(err)
+		}
+		return tx.Delete(&existing).Error
+	})
+}
+
+func (r *gormReactionRepository) Summary(ctx context.Context, targetType string, targetID, userID uint) (*ReactionSummary, error) {
+	db := r.db.WithContext(ctx)
+	var rows []struct {
+		Kind  string
+		Count int64
+	}
+	if err := db.Model(&models.Reaction{}).Select("kind, COUNT(*) AS count").
+		Where("target_type = ? AND target_id = ?", targetType, targetID).Group("kind").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	summary := &ReactionSummary{Counts: map[string]int64{}}
+	for _, row := range rows {
+		summary.Counts[row.Kind] = row.Count
+	}
+	var mine models.Reaction
+	err := db.Scopes(ofUser(userID, targetType, targetID)).First(&mine).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	summary.Mine = mine.Kind
+	return summary, nil
+}
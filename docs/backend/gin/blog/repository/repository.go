@@ -0,0 +1,29 @@
+// Package repository 把数据访问从 HTTP 处理函数中剥离出来：
+// 控制器只依赖这里定义的接口，具体使用 GORM 还是内存实现由启动代码决定。
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotFound 表示要查找的记录不存在，各个实现都应该返回这个错误而不是驱动自己的错误
+var ErrNotFound = errors.New("记录不存在")
+
+// ErrDuplicate 表示违反了唯一约束，例如邮箱已被注册
+var ErrDuplicate = errors.New("记录已存在")
+
+// ErrStale 表示保存时记录已经被其他请求修改（版本号不一致），调用方应该重新读取后再提交
+var ErrStale = errors.New("记录已被修改")
+
+// translate 把 GORM 的错误转换成仓储层的错误
+func translate(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicate
+	}
+	return err
+}
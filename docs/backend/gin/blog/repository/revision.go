@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"blog/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// RevisionRepository 只负责读取修订，修订由 PostRepository 在创建和更新文章时自动追加
+type RevisionRepository interface {
+	// ListByPost 分页返回文章的修订
+	ListByPost(ctx context.Context, postID uint, opts ListOptions) (*Page[models.PostRevision], error)
+	// Find 返回文章的第 number 个修订，number 为 0 时返回最新的修订
+	Find(ctx context.Context, postID uint, number int) (*models.PostRevision, error)
+}
+
+type gormRevisionRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRevisionRepository(db *gorm.DB) RevisionRepository {
+	return &gormRevisionRepository{db: db}
+}
+
+func (r *gormRevisionRepository) ListByPost(ctx context.Context, postID uint, opts ListOptions) (*Page[models.PostRevision], error) {
+	tx := r.db.WithContext(ctx).Model(&models.PostRevision{}).Where("post_id = ?", postID)
+	return listPage(tx, opts, revisionCursor)
+}
+
+func (r *gormRevisionRepository) Find(ctx context.Context, postID uint, number int) (*models.PostRevision, error) {
+	tx := r.db.WithContext(ctx).Where("post_id = ?", postID)
+	if number == 0 {
+		tx = tx.Order("number DESC")
+	} else {
+		tx = tx.Where("number = ?", number)
+	}
+	var rev models.PostRevision
+	if err := tx.First(&rev).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &rev, nil
+}
+
+func revisionCursor(r models.PostRevision) Cursor {
+	return Cursor{CreatedAt: r.CreatedAt, ID: r.ID}
+}
+
+// newRevision 为文章当前的标题和内容生成第 number 个修订
+func newRevision(post *models.Post, number int) *models.PostRevision {
+	return &models.PostRevision{
+		PostID:  post.ID,
+		Number:  number,
+		Title:   post.Title,
+		Content: post.Content,
+		Format:  post.Format,
+		UserID:  post.UserID,
+	}
+}
+
+// changed 判断文章的标题、内容或格式和修订是否不同，只修改状态等字段时不产生新修订
+func changed(post *models.Post, rev *models.PostRevision) bool {
+	return rev == nil || post.Title != rev.Title || post.Content != rev.Content || post.Format != rev.Format
+}
+
+// baselineRevision 返回文章最新的修订，需要在事务中调用。
+// 这个功能上线前创建的文章没有修订，先把数据库中当前的版本补记为第一个修订，保证修改前的内容不会丢失
+func baselineRevision(tx *gorm.DB, postID uint) (*models.PostRevision, error) {
+	var latest models.PostRevision
+	if err := tx.Where("post_id = ?", postID).Order("number DESC").Limit(1).Find(&latest).Error; err != nil {
+		return nil, err
+	}
+	if latest.ID != 0 {
+		return &latest, nil
+	}
+	var current models.Post
+	if err := tx.First(&current, postID).Error; err != nil {
+		return nil, translate(err)
+	}
+	rev := newRevision(&current, 1)
+	if err := tx.Create(rev).Error; err != nil {
+		return nil, translate(err)
+	}
+	return rev, nil
+}
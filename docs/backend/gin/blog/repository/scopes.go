@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"blog/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 以下是可以组合使用的命名范围（Scopes），用法：db.Scopes(ByAuthor(1), Paginate(2, 20))
+
+// Paginate 按页码分页，page 从 1 开始
+func Paginate(page, size int) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Limit(size).Offset((page - 1) * size)
+	}
+}
+
+// ByAuthor 只查询指定用户的记录，userID 为 0 时不过滤
+func ByAuthor(userID uint) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if userID == 0 {
+			return tx
+		}
+		return tx.Where("user_id = ?", userID)
+	}
+}
+
+// 列表排序、游标分页和按时间过滤使用的时间列
+const (
+	createdColumn = "created_at"
+	// publishedColumn 是文章的发布时间，已发布的文章一定有这一列（迁移时给旧文章补上了创建时间），
+	// 直接使用列而不是表达式，排序和游标分页才能走 (user_id, published_at, id) 等索引
+	publishedColumn = "published_at"
+)
+
+// CreatedBetween 按创建时间过滤，区间为 [from, to)，任一端为 nil 表示不限制
+func CreatedBetween(from, to *time.Time) func(tx *gorm.DB) *gorm.DB {
+	return timeBetween(createdColumn, from, to)
+}
+
+func timeBetween(column string, from, to *time.Time) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if from != nil {
+			tx = tx.Where(column+" >= ?", *from)
+		}
+		if to != nil {
+			tx = tx.Where(column+" < ?", *to)
+		}
+		return tx
+	}
+}
+
+// OrderByCreated 按 created_at,id 排序，id 用来保证创建时间相同时顺序稳定
+func OrderByCreated(desc bool) func(tx *gorm.DB) *gorm.DB {
+	return orderByTime(createdColumn, desc)
+}
+
+// OrderByPublished 按发布时间和 id 排序，只用于已发布的文章
+func OrderByPublished(desc bool) func(tx *gorm.DB) *gorm.DB {
+	return orderByTime(publishedColumn, desc)
+}
+
+func orderByTime(column string, desc bool) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if desc {
+			return tx.Order(column + " DESC").Order("id DESC")
+		}
+		return tx.Order(column + " ASC").Order("id ASC")
+	}
+}
+
+// AfterCursor 从游标之后继续查询（keyset 分页），必须和相同方向的 OrderByCreated 一起使用
+func AfterCursor(cursor *Cursor, desc bool) func(tx *gorm.DB) *gorm.DB {
+	return afterTime(createdColumn, cursor, desc)
+}
+
+// AfterPublishedCursor 和 AfterCursor 相同，但游标中是发布时间，必须和相同方向的 OrderByPublished 一起使用
+func AfterPublishedCursor(cursor *Cursor, desc bool) func(tx *gorm.DB) *gorm.DB {
+	return afterTime(publishedColumn, cursor, desc)
+}
+
+func afterTime(column string, cursor *Cursor, desc bool) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if cursor == nil {
+			return tx
+		}
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		return tx.Where("("+column+" "+op+" ?) OR ("+column+" = ? AND id "+op+" ?)",
+			cursor.CreatedAt, cursor.CreatedAt, cursor.ID)
+	}
+}
+
+// VisibleComments 只查询已公开的评论、已删除评论的占位符和查看者自己的评论，v.All 为 true 时不过滤。
+// 列名带上表名，查询可以 JOIN 评论者
+func VisibleComments(v Visibility) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if v.All {
+			return tx
+		}
+		return tx.Where("comments.status IN ? OR comments.user_id = ?",
+			[]string{models.CommentApproved, models.CommentDeleted}, v.ViewerID)
+	}
+}
+
+// VisiblePosts 只查询已发布的文章和查看者自己的文章，v.All 为 true 时不过滤
+func VisiblePosts(v Visibility) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if v.All {
+			return tx
+		}
+		return tx.Where("status = ? OR user_id = ?", models.PostPublished, v.ViewerID)
+	}
+}
+
+// ByStatus 只查询指定状态的记录，status 为空时不过滤
+func ByStatus(status string) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if status == "" {
+			return tx
+		}
+		return tx.Where("status = ?", status)
+	}
+}
+
+// filters 组合 ListOptions 中各种列表通用的过滤条件（不含分页），用于统计总数和查询。
+// 标签、分类和状态只对文章有意义，由文章的查询自己添加
+func filters(opts ListOptions) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Scopes(ByAuthor(opts.AuthorID), timeBetween(opts.timeColumn(), opts.From, opts.To))
+	}
+}
+
+// timeColumn 返回 opts 排序和过滤使用的时间列
+func (o ListOptions) timeColumn() string {
+	if o.ByPublished {
+		return publishedColumn
+	}
+	return createdColumn
+}
+
+// listPage 是 GORM 实现共用的分页查询：先按过滤条件统计总数，再按页码或游标取出一页。
+// 页码分页用总数判断是否还有下一页；游标分页多取一条来判断。
+// tx 需要已经通过 Model 指定表，preloads 只作用于取数据的查询
+func listPage[T any](tx *gorm.DB, opts ListOptions, key func(T) Cursor, preloads ...func(*gorm.DB) *gorm.DB) (*Page[T], error) {
+	opts = opts.Normalize()
+	// 新建会话，让统计和查询两条链互不影响
+	tx = tx.Session(&gorm.Session{})
+	page := &Page[T]{}
+	if err := tx.Scopes(filters(opts)).Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	query := tx.Scopes(preloads...).Scopes(filters(opts), orderByTime(opts.timeColumn(), opts.Desc))
+	var items []T
+	if opts.Cursor != nil {
+		if err := query.Scopes(afterTime(opts.timeColumn(), opts.Cursor, opts.Desc)).Limit(opts.Size + 1).Find(&items).Error; err != nil {
+			return nil, err
+		}
+		if len(items) > opts.Size {
+			items = items[:opts.Size]
+			page.NextCursor = key(items[len(items)-1]).Encode()
+		}
+	} else {
+		if err := query.Scopes(Paginate(opts.Page, opts.Size)).Find(&items).Error; err != nil {
+			return nil, err
+		}
+		if offset := (opts.Page - 1) * opts.Size; len(items) > 0 && int64(offset+len(items)) < page.Total {
+			page.NextCursor = key(items[len(items)-1]).Encode()
+		}
+	}
+	page.Items = items
+	return page, nil
+}
+
+// HasTerm 只查询带有指定标签或分类的文章，name 为空时不过滤
+func HasTerm(t Taxonomy, name string) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if name == "" {
+			return tx
+		}
+		spec := taxonomySpecs[t]
+		return tx.Where("id IN (SELECT j.post_id FROM "+spec.joinTable+" AS j JOIN "+spec.table+
+			" AS t ON t.id = j."+spec.joinColumn+" WHERE t.name = ? AND t.deleted_at IS NULL)", name)
+	}
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"blog/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	FindByJTI(ctx context.Context, jti string) (*models.Session, error)
+	// Rotate 原子地把会话的 refresh token 从 oldRefresh 换成 newRefresh 并延长有效期。
+	// 会话已吊销或 oldRefresh 不是当前的 refresh token 时返回 ErrNotFound
+	Rotate(ctx context.Context, jti, oldRefresh, newRefresh string, expiresAt time.Time) error
+	Revoke(ctx context.Context, jti string) error
+	RevokeAllByUser(ctx context.Context, userID uint) error
+}
+
+type gormSessionRepository struct {
+	db *gorm.DB
+}
+
+func NewGormSessionRepository(db *gorm.DB) SessionRepository {
+	return &gormSessionRepository{db: db}
+}
+
+func (r *gormSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *gormSessionRepository) FindByJTI(ctx context.Context, jti string) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.WithContext(ctx).Where("jti = ?", jti).First(&session).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &session, nil
+}
+
+func (r *gormSessionRepository) Rotate(ctx context.Context, jti, oldRefresh, newRefresh string, expiresAt time.Time) error {
+	// 用 refresh_jti 作为条件实现比较并交换，并发刷新时只有一个请求能成功
+	result := r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("jti = ? AND refresh_jti = ? AND revoked_at IS NULL", jti, oldRefresh).
+		Updates(map[string]any{"refresh_jti": newRefresh, "expires_at": expiresAt})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormSessionRepository) Revoke(ctx context.Context, jti string) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("jti = ? AND revoked_at IS NULL", jti).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *gormSessionRepository) RevokeAllByUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
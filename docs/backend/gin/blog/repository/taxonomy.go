@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"blog/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Taxonomy 区分标签和分类，两者的存储结构和操作完全相同
+type Taxonomy string
+
+const (
+	TaxonomyTag      Taxonomy = "tag"
+	TaxonomyCategory Taxonomy = "category"
+)
+
+// TermCount 是标签或分类以及使用它的文章数
+type TermCount struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+type TaxonomyRepository interface {
+	// Attach 给文章添加标签（分类），不存在的会自动创建，已经添加过的会被忽略
+	Attach(ctx context.Context, postID uint, names []string) error
+	// Detach 从文章上移除标签（分类），标签（分类）不存在时返回 ErrNotFound
+	Detach(ctx context.Context, postID uint, name string) error
+	// Counts 返回至少被一篇已发布文章使用的标签（分类）及文章数，按文章数从多到少排序
+	Counts(ctx context.Context) ([]TermCount, error)
+}
+
+// taxonomySpec 描述一种分类方式在数据库中的表结构，对应 models.Post 上的多对多关联
+type taxonomySpec struct {
+	association string // models.Post 上的关联字段名
+	table       string
+	joinTable   string
+	joinColumn  string // 连接表中指向 table 的外键
+	newTerm     func(name string) any
+}
+
+var taxonomySpecs = map[Taxonomy]taxonomySpec{
+	TaxonomyTag: {
+		association: "Tags",
+		table:       "tags",
+		joinTable:   "post_tags",
+		joinColumn:  "tag_id",
+		newTerm:     func(name string) any { return &models.Tag{Term: models.Term{Name: name}} },
+	},
+	TaxonomyCategory: {
+		association: "Categories",
+		table:       "categories",
+		joinTable:   "post_categories",
+		joinColumn:  "category_id",
+		newTerm:     func(name string) any { return &models.Category{Term: models.Term{Name: name}} },
+	},
+}
+
+type gormTaxonomyRepository struct {
+	db   *gorm.DB
+	spec taxonomySpec
+}
+
+func NewGormTaxonomyRepository(db *gorm.DB, t Taxonomy) TaxonomyRepository {
+	return &gormTaxonomyRepository{db: db, spec: taxonomySpecs[t]}
+}
+
+func (r *gormTaxonomyRepository) Attach(ctx context.Context, postID uint, names []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		terms := make([]any, 0, len(names))
+		for _, name := range names {
+			term := r.spec.newTerm(name)
+			if err := tx.Where("name = ?", name).FirstOrCreate(term).Error; err != nil {
+				return translate(err)
+			}
+			terms = append(terms, term)
+		}
+		// 和 gorm/many-to-many 示例一样通过关联模式维护连接表，已存在的关联不会重复插入
+		post := &models.Post{Model: gorm.Model{ID: postID}}
+		return tx.Model(post).Association(r.spec.association).Append(terms...)
+	})
+}
+
+func (r *gormTaxonomyRepository) Detach(ctx context.Context, postID uint, name string) error {
+	db := r.db.WithContext(ctx)
+	term := r.spec.newTerm(name)
+	if err := db.Where("name = ?", name).First(term).Error; err != nil {
+		return translate(err)
+	}
+	post := &models.Post{Model: gorm.Model{ID: postID}}
+	return db.Model(post).Association(r.spec.association).Delete(term)
+}
+
+func (r *gormTaxonomyRepository) Counts(ctx context.Context) ([]TermCount, error) {
+	counts := []TermCount{}
+	err := r.db.WithContext(ctx).Table(r.spec.table+" AS t").
+		Select("t.name AS name, COUNT(*) AS count").
+		Joins("JOIN "+r.spec.joinTable+" AS j ON j."+r.spec.joinColumn+" = t.id").
+		Joins("JOIN posts AS p ON p.id = j.post_id AND p.deleted_at IS NULL AND p.status = ?", models.PostPublished).
+		Where("t.deleted_at IS NULL").
+		Group("t.id, t.name").
+		Order("count DESC").Order("t.name ASC").
+		Scan(&counts).Error
+	return counts, err
+}
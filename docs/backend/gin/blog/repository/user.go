@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"blog/models"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UserRepository interface {
+	// Create 保存用户本身的字段，不会写入关联的文章
+	Create(ctx context.Context, user *models.User) error
+	FindByID(ctx context.Context, id uint) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	// List 按注册时间分页列出用户
+	List(ctx context.Context, opts ListOptions) (*Page[models.User], error)
+	// SetRole 修改用户角色，用户不存在时返回 ErrNotFound
+	SetRole(ctx context.Context, id uint, role models.Role) error
+	// Delete 删除用户（软删除），用户不存在时返回 ErrNotFound
+	Delete(ctx context.Context, id uint) error
+}
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+func NewGormUserRepository(db *gorm.DB) UserRepository {
+	return &gormUserRepository{db: db}
+}
+
+func (r *gormUserRepository) Create(ctx context.Context, user *models.User) error {
+	return translate(r.db.WithContext(ctx).Omit(clause.Associations).Create(user).Error)
+}
+
+func (r *gormUserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) List(ctx context.Context, opts ListOptions) (*Page[models.User], error) {
+	return listPage(r.db.WithContext(ctx).Model(&models.User{}), opts, userCursor)
+}
+
+func userCursor(u models.User) Cursor {
+	return Cursor{CreatedAt: u.CreatedAt, ID: u.ID}
+}
+
+func (r *gormUserRepository) SetRole(ctx context.Context, id uint, role models.Role) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormUserRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.User{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package search
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+)
+
+// BM25 参数，以及标题命中时的权重（标题里的词算作出现了 titleBoost 次）
+const (
+	bm25K1     = 1.2
+	bm25B      = 0.75
+	titleBoost = 3
+)
+
+type docKey struct {
+	kind Kind
+	id   uint
+}
+
+type indexedDoc struct {
+	Document
+	tf     map[string]int // 词频，标题中的词已经乘以 titleBoost
+	length int
+}
+
+// Index 是进程内的倒排索引，实现 Backend。
+// 数据只在内存中，服务启动时需要从数据库重建（见 app.Container.Reindex）
+type Index struct {
+	mu          sync.RWMutex
+	docs        map[docKey]*indexedDoc
+	postings    map[string]map[docKey]struct{} // 词 -> 包含该词的文档
+	totalLength int
+}
+
+func NewIndex() *Index {
+	return &Index{
+		docs:     map[docKey]*indexedDoc{},
+		postings: map[string]map[docKey]struct{}{},
+	}
+}
+
+func (ix *Index) Index(ctx context.Context, doc Document) error {
+	if doc.Kind == KindPost {
+		doc.PostID = doc.ID
+	}
+	d := &indexedDoc{Document: doc, tf: map[string]int{}}
+	for _, t := range tokenize(doc.Title) {
+		d.tf[t.term] += titleBoost
+		d.length += titleBoost
+	}
+	for _, t := range tokenize(doc.Content) {
+		d.tf[t.term]++
+		d.length++
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	key := docKey{doc.Kind, doc.ID}
+	ix.remove(key)
+	ix.docs[key] = d
+	ix.totalLength += d.length
+	for term := range d.tf {
+		if ix.postings[term] == nil {
+			ix.postings[term] = map[docKey]struct{}{}
+		}
+		ix.postings[term][key] = struct{}{}
+	}
+	return nil
+}
+
+func (ix *Index) Remove(ctx context.Context, kind Kind, id uint) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(docKey{kind, id})
+	return nil
+}
+
+func (ix *Index) RemovePost(ctx context.Context, postID uint) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for key, d := range ix.docs {
+		if d.PostID == postID {
+			ix.remove(key)
+		}
+	}
+	return nil
+}
+
+// remove 删除文档，调用方需要持有写锁
+func (ix *Index) remove(key docKey) {
+	d, ok := ix.docs[key]
+	if !ok {
+		return
+	}
+	for term := range d.tf {
+		delete(ix.postings[term], key)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	ix.totalLength -= d.length
+	delete(ix.docs, key)
+}
+
+// Search 返回包含查询中全部词的文档，按 BM25 相关度排序，相关度相同时新的在前
+func (ix *Index) Search(ctx context.Context, q Query) (*Result, error) {
+	queryTerms := terms(q.Text)
+	result := &Result{Hits: []Hit{}}
+	if len(queryTerms) == 0 {
+		return result, nil
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	// 从包含文档最少的词开始求交集
+	sort.Slice(queryTerms, func(i, j int) bool {
+		return len(ix.postings[queryTerms[i]]) < len(ix.postings[queryTerms[j]])
+	})
+	var matched []*indexedDoc
+	for key := range ix.postings[queryTerms[0]] {
+		d := ix.docs[key]
+		if q.Kind != "" && d.Kind != q.Kind {
+			continue
+		}
+		all := true
+		for _, term := range queryTerms[1:] {
+			if _, ok := ix.postings[term][key]; !ok {
+				all = false
+				break
+			}
+		}
+		if all {
+			matched = append(matched, d)
+		}
+	}
+
+	n := float64(len(ix.docs))
+	avgLength := float64(ix.totalLength) / n
+	scores := make(map[*indexedDoc]float64, len(matched))
+	for _, d := range matched {
+		var score float64
+		for _, term := range queryTerms {
+			df := float64(len(ix.postings[term]))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			tf := float64(d.tf[term])
+			score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*float64(d.length)/avgLength))
+		}
+		scores[d] = score
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+
+	result.Total = len(matched)
+	if q.Offset < len(matched) {
+		matched = matched[q.Offset:]
+	} else {
+		matched = nil
+	}
+	if q.Limit > 0 && len(matched) > q.Limit {
+		matched = matched[:q.Limit]
+	}
+	highlight := map[string]bool{}
+	for _, term := range queryTerms {
+		highlight[term] = true
+	}
+	for _, d := range matched {
+		result.Hits = append(result.Hits, Hit{
+			Kind:      d.Kind,
+			ID:        d.ID,
+			PostID:    d.PostID,
+			Title:     d.Title,
+			Snippet:   snippet(d.Content, highlight),
+			Score:     math.Round(scores[d]*1000) / 1000,
+			CreatedAt: d.CreatedAt,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,100 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTokenize(t *testing.T) {
+	got := terms("Go 语言的 GORM 数据库, go-sqlite3 和库")
+	want := []string{"go", "语言", "言的", "gorm", "数据", "据库", "sqlite3", "和库"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("terms = %q，期望 %q", got, want)
+	}
+	if got := terms("库"); !reflect.DeepEqual(got, []string{"库"}) {
+		t.Fatalf("单个汉字应作为一个词: %q", got)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	query := map[string]bool{"数据": true, "据库": true, "gorm": true}
+	got := snippet("使用 <b>GORM</b> 连接数据库", query)
+	want := "使用 &lt;b&gt;<mark>GORM</mark>&lt;/b&gt; 连接<mark>数据库</mark>"
+	if got != want {
+		t.Fatalf("snippet = %q，期望 %q", got, want)
+	}
+
+	long := ""
+	for i := 0; i < 50; i++ {
+		long += "填充文字"
+	}
+	got = snippet(long+"数据库"+long, query)
+	if got[:len("…")] != "…" || got[len(got)-len("…"):] != "…" {
+		t.Fatalf("长文本的摘要应截断并带省略号: %q", got)
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	ctx := context.Background()
+	ix := NewIndex()
+	now := time.Now()
+	docs := []Document{
+		{Kind: KindPost, ID: 1, Title: "GORM 入门", Content: "介绍 ORM 的基本用法", CreatedAt: now},
+		{Kind: KindPost, ID: 2, Title: "Gin 路由", Content: "在 Gin 中使用 GORM 访问数据库", CreatedAt: now},
+		{Kind: KindComment, ID: 3, PostID: 2, Content: "GORM 的事务怎么用？", CreatedAt: now},
+		{Kind: KindPost, ID: 4, Title: "Redis 缓存", Content: "缓存穿透与雪崩", CreatedAt: now},
+	}
+	for _, d := range docs {
+		if err := ix.Index(ctx, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ids := func(q Query) []uint {
+		t.Helper()
+		res, err := ix.Search(ctx, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []uint
+		for _, h := range res.Hits {
+			out = append(out, h.ID)
+		}
+		return out
+	}
+
+	// 标题命中的文章排在前面
+	if got := ids(Query{Text: "gorm"}); len(got) != 3 || got[0] != 1 {
+		t.Fatalf("gorm 的结果不符合预期: %v", got)
+	}
+	// 必须包含全部关键词
+	if got := ids(Query{Text: "GORM 数据库"}); !reflect.DeepEqual(got, []uint{2}) {
+		t.Fatalf("GORM 数据库 的结果不符合预期: %v", got)
+	}
+	if got := ids(Query{Text: "gorm", Kind: KindComment}); !reflect.DeepEqual(got, []uint{3}) {
+		t.Fatalf("只搜索评论的结果不符合预期: %v", got)
+	}
+	if got := ids(Query{Text: "gorm", Offset: 1, Limit: 1}); len(got) != 1 {
+		t.Fatalf("分页结果不符合预期: %v", got)
+	}
+	if got := ids(Query{Text: "不存在"}); len(got) != 0 {
+		t.Fatalf("不应有结果: %v", got)
+	}
+
+	// 更新后旧内容不再命中
+	ix.Index(ctx, Document{Kind: KindPost, ID: 4, Title: "Redis 缓存", Content: "GORM 二级缓存", CreatedAt: now})
+	if got := ids(Query{Text: "雪崩"}); len(got) != 0 {
+		t.Fatalf("更新后旧内容不应命中: %v", got)
+	}
+	// 删除文章时同时删除它的评论
+	ix.RemovePost(ctx, 2)
+	if got := ids(Query{Text: "gorm"}); !reflect.DeepEqual(got, []uint{1, 4}) && !reflect.DeepEqual(got, []uint{4, 1}) {
+		t.Fatalf("删除文章后的结果不符合预期: %v", got)
+	}
+	ix.Remove(ctx, KindPost, 1)
+	ix.Remove(ctx, KindPost, 4)
+	if len(ix.docs) != 0 || len(ix.postings) != 0 || ix.totalLength != 0 {
+		t.Fatalf("删除全部文档后索引应为空: %d docs, %d terms", len(ix.docs), len(ix.postings))
+	}
+}
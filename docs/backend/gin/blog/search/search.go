@@ -0,0 +1,64 @@
+// Package search 提供文章和评论的全文搜索。
+// Backend 是可替换的搜索后端：默认使用进程内的倒排索引（NewIndex），
+// 也可以换成 MySQL FULLTEXT、SQLite FTS5 或外部搜索服务，只要实现同样的接口
+package search
+
+import (
+	"context"
+	"time"
+)
+
+// Kind 是被索引的文档类型
+type Kind string
+
+const (
+	KindPost    Kind = "post"
+	KindComment Kind = "comment"
+)
+
+// Document 是一篇被索引的文档：文章或评论
+type Document struct {
+	Kind      Kind
+	ID        uint
+	PostID    uint   // 评论所属的文章，文章自身的 PostID 等于 ID
+	Title     string // 只有文章有标题
+	Content   string
+	CreatedAt time.Time
+}
+
+// Query 是一次搜索请求
+type Query struct {
+	Text   string
+	Kind   Kind // 为空表示同时搜索文章和评论
+	Offset int
+	Limit  int
+}
+
+// Hit 是一条搜索结果
+type Hit struct {
+	Kind      Kind      `json:"type"`
+	ID        uint      `json:"id"`
+	PostID    uint      `json:"post_id"`
+	Title     string    `json:"title,omitempty"`
+	Snippet   string    `json:"snippet"` // 命中内容的摘要，关键词用 <mark></mark> 包裹，其余内容已做 HTML 转义
+	Score     float64   `json:"score"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Result 是一页搜索结果，Total 是全部命中的数量
+type Result struct {
+	Hits  []Hit
+	Total int
+}
+
+// Backend 是搜索后端
+type Backend interface {
+	// Index 添加或更新一篇文档
+	Index(ctx context.Context, doc Document) error
+	// Remove 删除一篇文档，文档不存在时不返回错误
+	Remove(ctx context.Context, kind Kind, id uint) error
+	// RemovePost 删除文章以及它的全部评论
+	RemovePost(ctx context.Context, postID uint) error
+	// Search 按相关度从高到低返回结果
+	Search(ctx context.Context, q Query) (*Result, error)
+}
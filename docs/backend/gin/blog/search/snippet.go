@@ -0,0 +1,78 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// snippetRunes 是摘要的最大长度（字符数），snippetLead 是第一个命中词之前保留的字符数
+const (
+	snippetRunes = 120
+	snippetLead  = 30
+)
+
+// snippet 截取 text 中第一个命中词附近的一段，把命中的词用 <mark></mark> 包裹。
+// 返回值是 HTML 片段：原文已经转义，可以直接插入页面
+func snippet(text string, query map[string]bool) string {
+	// 找出命中的区间，相邻或重叠的区间（例如中文 bigram）合并成一个
+	var marks [][2]int
+	for _, t := range tokenize(text) {
+		if !query[t.term] {
+			continue
+		}
+		if n := len(marks); n > 0 && t.start <= marks[n-1][1] {
+			marks[n-1][1] = max(marks[n-1][1], t.end)
+			continue
+		}
+		marks = append(marks, [2]int{t.start, t.end})
+	}
+
+	start := 0
+	if len(marks) > 0 {
+		start = backRunes(text, marks[0][0], snippetLead)
+	}
+	end := forwardRunes(text, start, snippetRunes)
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, m := range marks {
+		if m[1] <= pos {
+			continue
+		}
+		if m[0] >= end {
+			break
+		}
+		b.WriteString(html.EscapeString(text[pos:max(m[0], pos)]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[max(m[0], pos):min(m[1], end)]))
+		b.WriteString("</mark>")
+		pos = min(m[1], end)
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// backRunes 从字节位置 i 向前退 n 个字符
+func backRunes(text string, i, n int) int {
+	for ; n > 0 && i > 0; n-- {
+		_, size := utf8.DecodeLastRuneInString(text[:i])
+		i -= size
+	}
+	return i
+}
+
+// forwardRunes 从字节位置 i 向后前进 n 个字符
+func forwardRunes(text string, i, n int) int {
+	for ; n > 0 && i < len(text); n-- {
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+	}
+	return i
+}
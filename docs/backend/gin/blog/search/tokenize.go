@@ -0,0 +1,75 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// token 是切分出的一个词以及它在原文中的字节区间 [start, end)
+type token struct {
+	term       string
+	start, end int
+}
+
+// tokenize 把文本切分成词：
+// 字母和数字连续的部分作为一个词（转小写）；中文等汉字没有空格分词，按相邻两个字切分（bigram），
+// 单独的一个汉字作为一个词。查询和索引使用同样的切分方式，所以 "数据库" 能匹配 "关系型数据库"
+func tokenize(text string) []token {
+	var tokens []token
+	var (
+		wordStart = -1 // 当前字母数字词的起始位置
+		prevHan   = -1 // 上一个汉字的起始位置
+		hanRun    = 0  // 当前连续汉字的个数
+	)
+	flushWord := func(end int) {
+		if wordStart >= 0 {
+			tokens = append(tokens, token{strings.ToLower(text[wordStart:end]), wordStart, end})
+			wordStart = -1
+		}
+	}
+	flushHan := func() {
+		// 只有一个汉字时没有 bigram，单独作为一个词
+		if hanRun == 1 {
+			_, size := utf8.DecodeRuneInString(text[prevHan:])
+			tokens = append(tokens, token{text[prevHan : prevHan+size], prevHan, prevHan + size})
+		}
+		prevHan, hanRun = -1, 0
+	}
+	for i, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord(i)
+			if prevHan >= 0 {
+				end := i + utf8.RuneLen(r)
+				tokens = append(tokens, token{text[prevHan:end], prevHan, end})
+			}
+			prevHan = i
+			hanRun++
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHan()
+			if wordStart < 0 {
+				wordStart = i
+			}
+		default:
+			flushWord(i)
+			flushHan()
+		}
+	}
+	flushWord(len(text))
+	flushHan()
+	return tokens
+}
+
+// terms 返回去重后的词，保持首次出现的顺序
+func terms(text string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, t := range tokenize(text) {
+		if !seen[t.term] {
+			seen[t.term] = true
+			out = append(out, t.term)
+		}
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+// Package site 是博客对外公开的 HTML 页面使用的模板和静态文件。
+// 模板和静态文件在编译时嵌入二进制文件，部署时不需要额外拷贝目录
+package site
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin/render"
+)
+
+//go:embed templates
+var templates embed.FS
+
+//go:embed static
+var static embed.FS
+
+// Pages 是所有页面的模板，模板在编译时嵌入，解析失败说明模板本身写错了，所以在启动时直接 panic
+var Pages = MustParse()
+
+// Renderer 保存每个页面各自的模板集合。
+// 每个页面都定义自己的 content 模板，所以不能和其他页面放在同一个集合里；
+// 布局（layout.html）和片段（partials/*.html）在每个集合中都解析一次
+type Renderer struct {
+	pages map[string]*template.Template
+}
+
+// Parse 解析 templates/pages 下的每个页面，页面名就是文件名，例如 "post.html"
+func Parse() (*Renderer, error) {
+	names, err := fs.Glob(templates, "templates/pages/*.html")
+	if err != nil {
+		return nil, err
+	}
+	r := &Renderer{pages: make(map[string]*template.Template, len(names))}
+	for _, name := range names {
+		t, err := template.New(path.Base(name)).Funcs(funcs).ParseFS(templates,
+			"templates/layout.html", "templates/partials/*.html", name)
+		if err != nil {
+			return nil, fmt.Errorf("解析页面模板 %s 失败: %w", name, err)
+		}
+		r.pages[path.Base(name)] = t
+	}
+	return r, nil
+}
+
+// MustParse 和 Parse 一样，但解析失败时 panic
+func MustParse() *Renderer {
+	r, err := Parse()
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Execute 用布局渲染页面 name，页面不存在时返回错误
+func (r *Renderer) Execute(w io.Writer, name string, data any) error {
+	t, ok := r.pages[name]
+	if !ok {
+		return fmt.Errorf("页面模板 %s 不存在", name)
+	}
+	return t.ExecuteTemplate(w, "layout", data)
+}
+
+// Instance 实现 gin 的 render.HTMLRender，设置为 engine.HTMLRender 后可以直接使用 c.HTML
+func (r *Renderer) Instance(name string, data any) render.Render {
+	return render.HTML{Template: r.pages[name], Name: "layout", Data: data}
+}
+
+// Static 返回嵌入的静态文件（样式表等），用于挂载到 /static
+func Static() http.FileSystem {
+	sub, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+	return http.FS(sub)
+}
+
+// funcs 是模板中可以使用的函数
+var funcs = template.FuncMap{
+	"date":    func(t time.Time) string { return t.Format("2006-01-02") },
+	"iso":     func(t time.Time) string { return t.Format(time.RFC3339) },
+	"excerpt": excerpt,
+}
+
+// excerpt 把内容压成一行并截取前 n 个字符，用于文章列表中的摘要
+func excerpt(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
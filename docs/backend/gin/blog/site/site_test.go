@@ -0,0 +1,47 @@
+package site
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPages(t *testing.T) {
+	for _, name := range []string{"home.html", "post.html", "author.html", "error.html"} {
+		if _, ok := Pages.pages[name]; !ok {
+			t.Errorf("缺少页面模板 %s", name)
+		}
+	}
+	var buf bytes.Buffer
+	err := Pages.Execute(&buf, "error.html", map[string]any{
+		"Site":    map[string]string{"Title": "站点"},
+		"Title":   "出错了",
+		"Message": "<script>alert(1)</script>",
+	})
+	if err != nil {
+		t.Fatalf("渲染页面失败: %v", err)
+	}
+	html := buf.String()
+	if !strings.Contains(html, "<title>出错了 - 站点</title>") || !strings.Contains(html, "&lt;script&gt;") {
+		t.Fatalf("页面内容不符合预期:\n%s", html)
+	}
+	if err := Pages.Execute(&buf, "missing.html", nil); err == nil {
+		t.Fatal("不存在的页面应该返回错误")
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"短内容", 10, "短内容"},
+		{"第一行\n\n  第二行", 10, "第一行 第二行"},
+		{"一二三四五六", 3, "一二三…"},
+	} {
+		if got := excerpt(tt.in, tt.n); got != tt.want {
+			t.Errorf("excerpt(%q, %d) = %q，期望 %q", tt.in, tt.n, got, tt.want)
+		}
+	}
+}
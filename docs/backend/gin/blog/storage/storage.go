@@ -0,0 +1,43 @@
+// Package storage 保存用户上传的文件（图片和附件）。
+// Storage 是可替换的存储后端：默认保存在本地目录（NewLocal），
+// 也可以换成兼容 S3 协议的对象存储（NewS3），例如 AWS S3、MinIO、Cloudflare R2
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrNotExist 表示要读取的文件不存在
+var ErrNotExist = errors.New("文件不存在")
+
+// Storage 按 key 保存文件内容，文件的元数据（文件名、类型、所属文章）由调用方另外保存
+type Storage interface {
+	// Put 保存 r 中的 size 字节，key 已存在时覆盖。r 的长度和 size 不一致时返回错误，不会留下不完整的文件
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open 读取文件，不存在时返回 ErrNotExist，调用方负责关闭
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除文件，文件不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+}
+
+// maxKeyLength 是 key 的最大长度
+const maxKeyLength = 200
+
+// ValidKey 检查 key 能否安全地用作文件名和对象路径：只能包含字母、数字、"."、"-" 和 "_"，
+// 不能以 "." 开头。这样 key 不会跳出存储目录，在 URL 中也不需要转义
+func ValidKey(key string) error {
+	if key == "" || len(key) > maxKeyLength || key[0] == '.' {
+		return fmt.Errorf("不合法的 key %q", key)
+	}
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+		default:
+			return fmt.Errorf("不合法的 key %q", key)
+		}
+	}
+	return nil
+}
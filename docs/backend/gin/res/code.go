@@ -0,0 +1,62 @@
+package res
+
+import "net/http"
+
+// Code 是业务错误码，0 表示成功。每个错误码对应一个 HTTP 状态码和默认提示
+type Code int
+
+const (
+	CodeOK                   Code = 0
+	CodeInvalidParams        Code = 1001
+	CodeUnauthorized         Code = 1002
+	CodeForbidden            Code = 1003
+	CodeNotFound             Code = 1004
+	CodeServerError          Code = 1005
+	CodeConflict             Code = 1006
+	CodePreconditionFailed   Code = 1007 // If-Match 与资源当前版本不一致
+	CodePreconditionRequired Code = 1008 // 修改资源时没有带 If-Match
+	CodePayloadTooLarge      Code = 1009 // 请求体（例如上传的文件）超过大小限制
+	CodeUnsupportedMediaType Code = 1010 // 不支持的文件类型
+	CodeUnprocessable        Code = 1011 // 请求格式正确但无法处理，例如要比较的两个版本差异过大
+)
+
+type codeInfo struct {
+	status int
+	msg    string
+}
+
+var codeMap = map[Code]codeInfo{
+	CodeOK:                   {http.StatusOK, "success"},
+	CodeInvalidParams:        {http.StatusBadRequest, "参数错误"},
+	CodeUnauthorized:         {http.StatusUnauthorized, "未授权"},
+	CodeForbidden:            {http.StatusForbidden, "禁止访问"},
+	CodeNotFound:             {http.StatusNotFound, "资源未找到"},
+	CodeServerError:          {http.StatusInternalServerError, "服务错误"},
+	CodeConflict:             {http.StatusConflict, "资源冲突"},
+	CodePreconditionFailed:   {http.StatusPreconditionFailed, "资源已被修改，请重新获取后再提交"},
+	CodePreconditionRequired: {http.StatusPreconditionRequired, "缺少 If-Match 请求头"},
+	CodePayloadTooLarge:      {http.StatusRequestEntityTooLarge, "文件过大"},
+	CodeUnsupportedMediaType: {http.StatusUnsupportedMediaType, "不支持的文件类型"},
+	CodeUnprocessable:        {http.StatusUnprocessableEntity, "无法处理的请求"},
+}
+
+// Register 注册自定义错误码，应在程序启动时调用（不是并发安全的）
+func Register(code Code, status int, msg string) {
+	codeMap[code] = codeInfo{status: status, msg: msg}
+}
+
+// Status 返回错误码对应的 HTTP 状态码，未注册的错误码按 500 处理
+func (c Code) Status() int {
+	if info, ok := codeMap[c]; ok {
+		return info.status
+	}
+	return http.StatusInternalServerError
+}
+
+// Message 返回错误码的默认提示
+func (c Code) Message() string {
+	if info, ok := codeMap[c]; ok {
+		return info.msg
+	}
+	return "未知错误"
+}
@@ -1,31 +1,46 @@
 package res
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/gin-gonic/gin"
+)
 
+// Response 是所有接口统一的响应格式
 type Response struct {
-	Code int    `json:"code"`
-	Msg  string `json:"msg"`
-	Data any    `json:"data"`
+	Code      Code         `json:"code"`
+	Msg       string       `json:"msg"`
+	Data      any          `json:"data"`
+	Details   []FieldError `json:"details,omitempty"`    // 参数校验失败时的字段错误
+	RequestID string       `json:"request_id,omitempty"` // 由 RequestID 中间件生成，方便排查问题
 }
 
-var codeMap = map[int]string{
-	1001: "参数错误",
-	1002: "未授权",
-	1003: "禁止访问",
-	1004: "资源未找到",
-	1005: "服务错误",
+// FieldError 描述一个字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
 }
 
-func response(c *gin.Context, code int, data any, msg string) {
-	c.JSON(200, Response{
-		Msg:  msg,
-		Code: code,
-		Data: data,
-	})
+func response(c *gin.Context, code Code, data any, msg string, details []FieldError) {
+	if msg == "" {
+		msg = code.Message()
+	}
+	resp := Response{
+		Code:      code,
+		Msg:       msg,
+		Data:      data,
+		Details:   details,
+		RequestID: c.GetString(RequestIDKey),
+	}
+	if code == CodeOK {
+		c.JSON(code.Status(), resp)
+		return
+	}
+	// 失败时中止后续的处理函数，中间件也可以直接使用
+	c.AbortWithStatusJSON(code.Status(), resp)
 }
 
 func Ok(c *gin.Context, data any, msg string) {
-	response(c, 0, data, msg)
+	response(c, CodeOK, data, msg, nil)
 }
 
 func OkData(c *gin.Context, data any) {
@@ -36,22 +51,26 @@ func OkMsg(c *gin.Context, msg string) {
 	Ok(c, gin.H{}, msg)
 }
 
-func Fail(c *gin.Context, code int, data any, msg string) {
-	response(c, code, data, msg)
+func Fail(c *gin.Context, code Code, data any, msg string) {
+	response(c, code, data, msg, nil)
 }
 
 func FailMsg(c *gin.Context, msg string) {
-	Fail(c, 1001, nil, msg) // 默认错误码1001
+	Fail(c, CodeInvalidParams, nil, msg) // 默认错误码1001
 }
 
-func FailCode(c *gin.Context, code int, msg string) {
-	msg, ok := codeMap[code]
-	if !ok {
-		msg = "未知错误"
-	}
+// FailCode 返回错误码，msg 为空时使用错误码的默认提示
+func FailCode(c *gin.Context, code Code, msg string) {
 	Fail(c, code, nil, msg) //传入空数据
 }
 
 func FailData(c *gin.Context, data any) {
-	Fail(c, 1001, data, "")
+	Fail(c, CodeInvalidParams, data, "")
+}
+
+// FailBind 处理 ShouldBind 系列方法返回的错误：校验错误会展开成字段级的 details，
+// 提示语言根据请求的 Accept-Language 选择（zh / en）
+func FailBind(c *gin.Context, err error) {
+	details, msg := Translate(err, Lang(c.GetHeader("Accept-Language")))
+	response(c, CodeInvalidParams, nil, msg, details)
 }
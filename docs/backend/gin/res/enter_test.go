@@ -0,0 +1,126 @@
+package res
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCodeStatus(t *testing.T) {
+	cases := map[Code]int{
+		CodeOK:                   http.StatusOK,
+		CodeInvalidParams:        http.StatusBadRequest,
+		CodeUnauthorized:         http.StatusUnauthorized,
+		CodeForbidden:            http.StatusForbidden,
+		CodeNotFound:             http.StatusNotFound,
+		CodeServerError:          http.StatusInternalServerError,
+		CodeConflict:             http.StatusConflict,
+		CodePreconditionFailed:   http.StatusPreconditionFailed,
+		CodePreconditionRequired: http.StatusPreconditionRequired,
+		CodePayloadTooLarge:      http.StatusRequestEntityTooLarge,
+		CodeUnsupportedMediaType: http.StatusUnsupportedMediaType,
+		CodeUnprocessable:        http.StatusUnprocessableEntity,
+		Code(9999):               http.StatusInternalServerError,
+	}
+	for code, status := range cases {
+		if got := code.Status(); got != status {
+			t.Errorf("Code(%d).Status() = %d，期望 %d", code, got, status)
+		}
+	}
+	Register(2001, http.StatusTeapot, "我是茶壶")
+	if Code(2001).Status() != http.StatusTeapot || Code(2001).Message() != "我是茶壶" {
+		t.Error("自定义错误码注册失败")
+	}
+}
+
+func TestFailBind(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID())
+	r.POST("/", func(c *gin.Context) {
+		var input struct {
+			Name string `json:"name" binding:"required"`
+			Age  int    `json:"age" binding:"gte=0,lte=150"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			FailBind(c, err)
+			return
+		}
+		OkData(c, input)
+	})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"age": 200}`))
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("期望 400，实际 %d", rec.Code)
+	}
+	var resp Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != CodeInvalidParams || len(resp.Details) != 2 || resp.RequestID == "" {
+		t.Fatalf("响应不符合预期: %s", rec.Body.String())
+	}
+	if resp.Details[0].Rule != "required" || resp.Details[1].Rule != "lte" {
+		t.Fatalf("字段错误不符合预期: %+v", resp.Details)
+	}
+}
+
+func TestLang(t *testing.T) {
+	cases := map[string]string{
+		"":                             LangZh,
+		"en":                           LangEn,
+		"en-US,en;q=0.9":               LangEn,
+		"zh-CN,zh;q=0.9,en;q=0.8":      LangZh,
+		"fr-FR,en;q=0.5,zh;q=0.8":      LangZh,
+		"de, en;q=0.7":                 LangEn,
+		"ja":                           DefaultLang,
+		"en;q=0, zh-TW":                LangZh,
+		"zh;q=0.1, en-GB;q=0.2, *;q=1": LangEn,
+	}
+	for header, want := range cases {
+		if got := Lang(header); got != want {
+			t.Errorf("Lang(%q) = %q，期望 %q", header, got, want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	type input struct {
+		Email    string `json:"email" binding:"required,email"`
+		Password string `json:"password" binding:"min=8"`
+		Age      int    `json:"age"`
+	}
+	bind := func(body string) error {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		var in input
+		return c.ShouldBindJSON(&in)
+	}
+
+	err := bind(`{"email": "x", "password": "short"}`)
+	zh, msg := Translate(err, LangZh)
+	if len(zh) != 2 || zh[0].Field != "email" || zh[1].Field != "password" || msg != zh[0].Message {
+		t.Fatalf("中文字段错误不符合预期: %+v", zh)
+	}
+	if !strings.Contains(zh[1].Message, "8个字符") {
+		t.Fatalf("中文提示不符合预期: %q", zh[1].Message)
+	}
+	en, _ := Translate(err, LangEn)
+	if en[1].Message != "password must be at least 8 characters in length" {
+		t.Fatalf("英文提示不符合预期: %q", en[1].Message)
+	}
+
+	details, msg := Translate(bind(`{"email": "a@b.c", "password": "password", "age": "ten"}`), LangEn)
+	if len(details) != 1 || details[0].Field != "age" || details[0].Rule != "type" || msg != "age must be of type int" {
+		t.Fatalf("类型错误不符合预期: %+v %q", details, msg)
+	}
+	if details, msg := Translate(bind(`not json`), LangZh); details != nil || msg != "请求体格式错误" {
+		t.Fatalf("格式错误不符合预期: %+v %q", details, msg)
+	}
+}
@@ -0,0 +1,48 @@
+package res
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// RequestIDHeader 是请求 ID 的请求头和响应头
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDKey 是请求 ID 在 gin.Context 中的键
+	RequestIDKey = "request_id"
+)
+
+// RequestID 中间件沿用客户端传入的 X-Request-ID（或生成一个新的），
+// 写回响应头，并放到每个响应体的 request_id 字段中
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set(RequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// validRequestID 只接受长度合理的可打印 ASCII，避免把任意内容写进日志和响应头
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
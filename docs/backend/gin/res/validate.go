@@ -0,0 +1,126 @@
+package res
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	zh_translations "github.com/go-playground/validator/v10/translations/zh"
+)
+
+// 支持的语言，DefaultLang 用于没有 Accept-Language 或不支持的语言
+const (
+	LangZh      = "zh"
+	LangEn      = "en"
+	DefaultLang = LangZh
+)
+
+// uni 保存各语言的翻译器，在 init 中注册到 gin 默认的校验器上
+var uni *ut.UniversalTranslator
+
+// 非校验类绑定错误（请求体格式错误、字段类型错误）的提示
+var bindMessages = map[string]map[string]string{
+	LangZh: {"malformed": "请求体格式错误", "type": "%s 的类型应为 %s"},
+	LangEn: {"malformed": "malformed request body", "type": "%s must be of type %s"},
+}
+
+// 导入 res 包时为 gin 的校验器注册中英文翻译，并让字段名使用 json/form 标签，
+// 这样返回给客户端的是 "password" 而不是 Go 结构体字段名 "Password"
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(fieldName)
+
+	zhLocale, enLocale := zh.New(), en.New()
+	uni = ut.New(zhLocale, zhLocale, enLocale)
+	zhTrans, _ := uni.GetTranslator(LangZh)
+	enTrans, _ := uni.GetTranslator(LangEn)
+	if err := zh_translations.RegisterDefaultTranslations(v, zhTrans); err != nil {
+		panic(err)
+	}
+	if err := en_translations.RegisterDefaultTranslations(v, enTrans); err != nil {
+		panic(err)
+	}
+}
+
+// fieldName 依次取 json、form 标签作为字段名，都没有时使用结构体字段名
+func fieldName(f reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name := strings.Split(f.Tag.Get(tag), ",")[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+// Lang 从 Accept-Language 中选出优先级最高的受支持语言，例如 "en-US,en;q=0.9,zh;q=0.8" 返回 "en"
+func Lang(acceptLanguage string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if (base == LangZh || base == LangEn) && q > 0 {
+			candidates = append(candidates, candidate{base, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return DefaultLang
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang
+}
+
+// Translate 把 ShouldBind 系列方法返回的错误翻译成指定语言的字段错误和一条整体提示。
+// 请求体格式错误等没有字段信息的错误只返回整体提示
+func Translate(err error, lang string) ([]FieldError, string) {
+	if _, ok := bindMessages[lang]; !ok {
+		lang = DefaultLang
+	}
+	var errs validator.ValidationErrors
+	if errors.As(err, &errs) {
+		trans, _ := uni.GetTranslator(lang)
+		details := make([]FieldError, 0, len(errs))
+		for _, fe := range errs {
+			details = append(details, FieldError{Field: fe.Field(), Rule: fe.Tag(), Message: fe.Translate(trans)})
+		}
+		return details, details[0].Message
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		msg := fmt.Sprintf(bindMessages[lang]["type"], typeErr.Field, typeErr.Type.Kind())
+		return []FieldError{{Field: typeErr.Field, Rule: "type", Message: msg}}, msg
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, bindMessages[lang]["malformed"]
+	}
+	// 其他错误（例如处理函数自己的参数检查）原样返回
+	return nil, err.Error()
+}